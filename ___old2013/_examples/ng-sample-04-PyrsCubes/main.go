@@ -181,7 +181,7 @@ func setupExample_04_PyrsCubes() {
 		tmpNode = apputil.AddNode(scene, 0, meshPyrID, -1, -1)
 		pyrIDs[i] = tmpNode.ID
 		if i > 1 {
-			tmpNode.Render.ModelID = modelPyrDogID
+			tmpNode.SetModelID(modelPyrDogID)
 		}
 		f = float64(len(pyrIDs) - i)
 		tmpNode.Transform.SetScale((f + 1) * 2)
@@ -236,11 +236,11 @@ func addCrates(scene *ng.Scene, num int) {
 		tmpNode.Transform.SetPos((f+3)*-2, (f+1)*2, (f+2)*3)
 		switch i {
 		case 0:
-			tmpNode.Render.MatID = apputil.LibIDs.Mat["mix"]
+			tmpNode.SetMatID(apputil.LibIDs.Mat["mix"])
 		case 1:
 			apputil.AddNode(scene, tmpNode.ID, meshPyrID, apputil.LibIDs.Mat["cat"], -1).Transform.Pos.Y = 2.125
 		case 2:
-			tmpNode.Render.ModelID = modelCubeCatID
+			tmpNode.SetModelID(modelCubeCatID)
 		}
 	}
 	scene.ApplyNodeTransforms(0)
@@ -124,7 +124,7 @@ func Main(setupExampleScene, onAppThread, onWinThread func()) {
 
 	opt.AppDir.BasePath = AppDirBasePath()
 	opt.AppDir.Temp.BaseName = filepath.Join("_tmp", filepath.Base(os.Args[0]))
-	opt.AppDir.Temp.ShaderSources, opt.AppDir.Temp.CachedTextures = "glsl", "tex"
+	opt.AppDir.Temp.ShaderSources, opt.AppDir.Temp.CachedTextures, opt.AppDir.Temp.CachedMeshes = "glsl", "tex", "mesh"
 	// but for now, we don't need separate per-app tmp dirs:
 	opt.AppDir.Temp.BaseName = "_tmp"
 
@@ -139,6 +139,7 @@ func Main(setupExampleScene, onAppThread, onWinThread func()) {
 		opt.Textures.Storage.DiskCache.Decompressor = func(r io.ReadCloser) io.ReadCloser {
 			return flate.NewReader(r)
 		}
+		opt.Meshes.DiskCache.Compressor, opt.Meshes.DiskCache.Decompressor = opt.Textures.Storage.DiskCache.Compressor, opt.Textures.Storage.DiskCache.Decompressor
 	}
 
 	//	STEP 1: init go:ngine
@@ -26,7 +26,8 @@ func AddMainScene() (me *ng.Scene) {
 func AddNode(scene *ng.Scene, parentNodeID, meshID, matID, modelID int) (node *ng.SceneNode) {
 	nodeID := scene.AddNewChildNode(parentNodeID, meshID)
 	node = scene.Node(nodeID)
-	node.Render.MatID, node.Render.ModelID = matID, modelID
+	node.SetMatID(matID)
+	node.SetModelID(modelID)
 	return
 }
 
@@ -52,7 +53,7 @@ func AddSkyMesh(scene *ng.Scene, meshID int) {
 	LibIDs.Mat["sky"] = matID
 
 	scene.SetNodeMeshID(0, meshID)
-	scene.Root().Render.MatID = matID
+	scene.Root().SetMatID(matID)
 }
 
 //	Sets up textures and associated effects/materials with the specified IDs and image URLs.
@@ -40,13 +40,13 @@ func (me *Gui2D) Setup() (err error) {
 
 	me.DogNodeID = scene.AddNewChildNode(0, quadMeshID)
 	dog := scene.Node(me.DogNodeID)
-	dog.Render.MatID = LibIDs.Mat["dog"]
+	dog.SetMatID(LibIDs.Mat["dog"])
 	dog.Transform.SetScale(0.85)
 	dog.Transform.Rot.Z = unum.DegToRad(90)
 
 	me.CatNodeID = scene.AddNewChildNode(0, quadMeshID)
 	cat := scene.Node(me.CatNodeID)
-	cat.Render.MatID = LibIDs.Mat["cat"]
+	cat.SetMatID(LibIDs.Mat["cat"])
 	cat.Transform.SetScale(0.85)
 	cat.Transform.Rot.Z = unum.DegToRad(90)
 
@@ -27,10 +27,22 @@ vec3 fx_Coords (const in vec3 vCol) {
 	return vec3(0, vCoords);
 }
 
+vec3 fx_Normals (const in vec3 vCol) {
+	return (normalize(var_vec3_Normal) * 0.5) + 0.5;
+}
+
 vec3 fx_Tex2D (const in vec3 vCol) {
 	return texture(uni_sampler2D_Tex2D_Img, var_vec2_Tex2D).rgb;
 }
 
+vec3 fx_Tex2DUv2 (const in vec3 vCol) {
+	return texture(uni_sampler2D_Tex2DUv2_Img, var_vec2_Tex2DUv2).rgb;
+}
+
 vec3 fx_TexCube (const in vec3 vCol) {
 	return texture(uni_samplerCube_TexCube_Img, var_vec3_TexCube).rgb;
 }
+
+vec3 fx_VertColor (const in vec3 vCol) {
+	return var_vec4_Color.rgb;
+}
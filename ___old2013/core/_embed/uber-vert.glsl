@@ -33,3 +33,137 @@ vec2 vx_Scene_var_vec2_Tex2D () {
 vec3 vx_Scene_var_vec3_TexCube () {
 	return att_vec3_Pos;
 }
+
+vec3 vx_Scene_var_vec3_Normal () {
+	return att_vec3_Normal;
+}
+
+vec4 vx_Scene_var_vec4_Tangent () {
+	return att_vec4_Tangent;
+}
+
+vec4 vx_Scene_var_vec4_Color () {
+	return att_vec4_Color;
+}
+
+vec2 vx_Scene_var_vec2_Tex2DUv2 () {
+	return att_vec2_Tex2DUv2;
+}
+
+vec4 vx_SceneInst_gl_Position () {
+	int i = 4 * (uni_int_InstOffset + gl_InstanceID);
+	mat4 mat = mat4(texelFetch(uni_samplerBuffer_InstMats, i), texelFetch(uni_samplerBuffer_InstMats, i + 1), texelFetch(uni_samplerBuffer_InstMats, i + 2), texelFetch(uni_samplerBuffer_InstMats, i + 3));
+	return mat * vec4(att_vec3_Pos, 1.0);
+}
+
+vec2 vx_SceneInst_var_vec2_Tex2D () {
+	return att_vec2_Tex2D;
+}
+
+vec3 vx_SceneInst_var_vec3_TexCube () {
+	return att_vec3_Pos;
+}
+
+vec3 vx_SceneInst_var_vec3_Normal () {
+	return att_vec3_Normal;
+}
+
+vec4 vx_SceneInst_var_vec4_Tangent () {
+	return att_vec4_Tangent;
+}
+
+vec4 vx_SceneInst_var_vec4_Color () {
+	return att_vec4_Color;
+}
+
+vec2 vx_SceneInst_var_vec2_Tex2DUv2 () {
+	return att_vec2_Tex2DUv2;
+}
+
+mat4 vx_Scene_SkinMatrix () {
+	mat4 mat = att_vec4_SkinWeights.x * uni_mat4_SkinJoints[int(att_vec4_SkinJoints.x)];
+	mat += att_vec4_SkinWeights.y * uni_mat4_SkinJoints[int(att_vec4_SkinJoints.y)];
+	mat += att_vec4_SkinWeights.z * uni_mat4_SkinJoints[int(att_vec4_SkinJoints.z)];
+	mat += att_vec4_SkinWeights.w * uni_mat4_SkinJoints[int(att_vec4_SkinJoints.w)];
+	return (dot(att_vec4_SkinWeights, vec4(1.0)) > 0.0) ? mat : mat4(1.0);
+}
+
+vec4 vx_SceneSkin_gl_Position () {
+	return uni_mat4_VertexMatrix * vx_Scene_SkinMatrix() * vec4(vx_Scene_MorphPos(), 1.0);
+}
+
+vec2 vx_SceneSkin_var_vec2_Tex2D () {
+	return att_vec2_Tex2D;
+}
+
+vec3 vx_SceneSkin_var_vec3_TexCube () {
+	return att_vec3_Pos;
+}
+
+vec3 vx_SceneSkin_var_vec3_Normal () {
+	return normalize(mat3(vx_Scene_SkinMatrix()) * vx_Scene_MorphNormal());
+}
+
+vec4 vx_SceneSkin_var_vec4_Tangent () {
+	return vec4(normalize(mat3(vx_Scene_SkinMatrix()) * att_vec4_Tangent.xyz), att_vec4_Tangent.w);
+}
+
+vec4 vx_SceneSkin_var_vec4_Color () {
+	return att_vec4_Color;
+}
+
+vec2 vx_SceneSkin_var_vec2_Tex2DUv2 () {
+	return att_vec2_Tex2DUv2;
+}
+
+vec3 vx_Scene_MorphPos () {
+	vec3 pos = att_vec3_Pos;
+	int v = gl_VertexID - uni_int_MorphBaseVertex;
+	float w;
+	for (int i = 0; i < uni_int_MorphCount; i++) {
+		if ((w = uni_mat4_MorphWeights[i / 4][i % 4]) != 0.0) {
+			pos += w * texelFetch(uni_samplerBuffer_MorphDeltas, 2 * (i * uni_int_MorphNumVerts + v)).xyz;
+		}
+	}
+	return pos;
+}
+
+vec3 vx_Scene_MorphNormal () {
+	vec3 normal = att_vec3_Normal;
+	int v = gl_VertexID - uni_int_MorphBaseVertex;
+	float w;
+	for (int i = 0; i < uni_int_MorphCount; i++) {
+		if ((w = uni_mat4_MorphWeights[i / 4][i % 4]) != 0.0) {
+			normal += w * texelFetch(uni_samplerBuffer_MorphDeltas, 2 * (i * uni_int_MorphNumVerts + v) + 1).xyz;
+		}
+	}
+	return normalize(normal);
+}
+
+vec4 vx_SceneMorph_gl_Position () {
+	return uni_mat4_VertexMatrix * vec4(vx_Scene_MorphPos(), 1.0);
+}
+
+vec2 vx_SceneMorph_var_vec2_Tex2D () {
+	return att_vec2_Tex2D;
+}
+
+vec3 vx_SceneMorph_var_vec3_TexCube () {
+	return att_vec3_Pos;
+}
+
+vec3 vx_SceneMorph_var_vec3_Normal () {
+	return vx_Scene_MorphNormal();
+}
+
+vec4 vx_SceneMorph_var_vec4_Tangent () {
+	return att_vec4_Tangent;
+}
+
+vec4 vx_SceneMorph_var_vec4_Color () {
+	return att_vec4_Color;
+}
+
+vec2 vx_SceneMorph_var_vec2_Tex2DUv2 () {
+	return att_vec2_Tex2DUv2;
+}
@@ -0,0 +1,439 @@
+package core
+
+import (
+	"math"
+	"sort"
+
+	"github.com/metaleap/go-util-num"
+)
+
+//	Identifies the property animated by an AnimTrack.
+type AnimTarget int
+
+const (
+	//	Animates SceneNodeTransform.Pos.
+	AnimTargetPos AnimTarget = iota
+
+	//	Animates SceneNodeTransform.Rot, taken from AnimKey.Rot: keys are interpolated as quaternions
+	//	(AnimInterpCubic and AnimInterpHermite like AnimInterpLinear) and only then converted to Euler angles.
+	AnimTargetRot
+
+	//	Animates SceneNodeTransform.Scale.
+	AnimTargetScale
+
+	//	Animates the mix weight of an FxProc (see FxProc.SetMixWeight()), taken from AnimKey.Value.X.
+	AnimTargetFxMixWeight
+
+	//	Animates FxProc.Color.Rgb of a "Color" FxProc, taken from AnimKey.Value.
+	AnimTargetFxColor
+
+	//	Animates the weight of a morph target (see SceneNode.SetMorphWeight()), taken from AnimKey.Value.X.
+	AnimTargetMorphWeight
+)
+
+//	Controls how an AnimTrack interpolates between two AnimKeys.
+type AnimInterp int
+
+const (
+	//	Linear interpolation between two keys.
+	AnimInterpLinear AnimInterp = iota
+
+	//	No interpolation: each key's value holds until the next key.
+	AnimInterpStep
+
+	//	Smooth (Catmull-Rom) interpolation through all keys.
+	AnimInterpCubic
+
+	//	Cubic Hermite interpolation between two keys, using their AnimKey.OutTangent and AnimKey.InTangent.
+	AnimInterpHermite
+)
+
+//	A single keyframe in an AnimTrack.
+type AnimKey struct {
+	//	The time of this key in seconds from the start of its AnimClip.
+	Time float64
+
+	//	The value at Time. Scalar targets use only X. Unused by AnimTargetRot.
+	Value unum.Vec3
+
+	//	For AnimInterpHermite only: the tangents (in value units per second) arriving at and leaving Value.
+	InTangent, OutTangent unum.Vec3
+
+	//	For AnimTargetRot only: the rotation at Time, as a unit quaternion (x, y, z, w). See SetRot().
+	Rot [4]float64
+}
+
+//	Sets me.Rot to the rotation described by the Euler angles rot, as per SceneNodeTransform.Rot.
+func (me *AnimKey) SetRot(rot *unum.Vec3) {
+	tr := SceneNodeTransform{Rot: *rot}
+	tr.Scale.Set(1, 1, 1)
+	var mat unum.Mat4
+	tr.localMatrix(&mat)
+	quatFromMatrix(&me.Rot, &mat)
+}
+
+//	A named marker in an AnimClip. Whenever playback passes its Time,
+//	the AnimPlayer.OnEvent callback (if any) is invoked.
+type AnimEvent struct {
+	Time float64
+	Name string
+}
+
+//	Animates a single property of a single node over time.
+type AnimTrack struct {
+	//	The SceneNode.Name of the node to animate, looked up in the sub-tree of the AnimPlayer.RootNodeID.
+	//	If empty, the AnimPlayer.RootNodeID itself is animated.
+	NodeName string
+
+	//	The property of the node (or its effect) to animate.
+	Target AnimTarget
+
+	//	For the AnimTargetFx* targets, the FxProcs.Get() procID and n of the FxProc to animate, in the
+	//	DefaultEffectID of the node's material. The AnimPlayer first gives the node its own copies of that
+	//	FxMaterial and FxEffect, so that other nodes sharing them are not affected.
+	FxProcID string
+	FxProcN  int
+
+	//	For the AnimTargetMorphWeight target, the name of the morph target in the node's mesh.
+	MorphTarget string
+
+	Interp AnimInterp
+
+	//	Must be sorted by Time. Before the first and after the last key, their values hold.
+	Keys []AnimKey
+}
+
+//	Sets val to the value of me at time t.
+func (me *AnimTrack) sample(t float64, val *unum.Vec3) {
+	n := len(me.Keys)
+	switch {
+	case n == 0:
+		return
+	case t <= me.Keys[0].Time:
+		*val = me.Keys[0].Value
+		return
+	case t >= me.Keys[n-1].Time:
+		*val = me.Keys[n-1].Value
+		return
+	}
+	i := sort.Search(n, func(i int) bool { return me.Keys[i].Time > t }) - 1
+	k0, k1 := &me.Keys[i], &me.Keys[i+1]
+	span := k1.Time - k0.Time
+	if me.Interp == AnimInterpStep || span <= 0 {
+		*val = k0.Value
+		return
+	}
+	f := (t - k0.Time) / span
+	if me.Interp == AnimInterpCubic || me.Interp == AnimInterpHermite {
+		var m0, m1 unum.Vec3
+		if me.Interp == AnimInterpHermite {
+			m0.Set(k0.OutTangent.X*span, k0.OutTangent.Y*span, k0.OutTangent.Z*span)
+			m1.Set(k1.InTangent.X*span, k1.InTangent.Y*span, k1.InTangent.Z*span)
+		} else {
+			me.tangent(i, span, &m0)
+			me.tangent(i+1, span, &m1)
+		}
+		f2, f3 := f*f, f*f*f
+		h00, h10, h01, h11 := 2*f3-3*f2+1, f3-2*f2+f, -2*f3+3*f2, f3-f2
+		val.Set(
+			h00*k0.Value.X+h10*m0.X+h01*k1.Value.X+h11*m1.X,
+			h00*k0.Value.Y+h10*m0.Y+h01*k1.Value.Y+h11*m1.Y,
+			h00*k0.Value.Z+h10*m0.Z+h01*k1.Value.Z+h11*m1.Z)
+	} else {
+		val.Set(k0.Value.X+f*(k1.Value.X-k0.Value.X), k0.Value.Y+f*(k1.Value.Y-k0.Value.Y), k0.Value.Z+f*(k1.Value.Z-k0.Value.Z))
+	}
+}
+
+//	Sets q to the rotation of me at time t, spherically interpolated between the two adjacent keys.
+func (me *AnimTrack) sampleRot(t float64, q *[4]float64) {
+	n := len(me.Keys)
+	switch {
+	case n == 0:
+		return
+	case t <= me.Keys[0].Time:
+		*q = me.Keys[0].Rot
+		return
+	case t >= me.Keys[n-1].Time:
+		*q = me.Keys[n-1].Rot
+		return
+	}
+	i := sort.Search(n, func(i int) bool { return me.Keys[i].Time > t }) - 1
+	k0, k1 := &me.Keys[i], &me.Keys[i+1]
+	if span := k1.Time - k0.Time; me.Interp == AnimInterpStep || span <= 0 {
+		*q = k0.Rot
+	} else {
+		quatSlerp(q, &k0.Rot, &k1.Rot, (t-k0.Time)/span)
+	}
+}
+
+//	Sets m to the Catmull-Rom tangent at key i, scaled for a segment of length span.
+func (me *AnimTrack) tangent(i int, span float64, m *unum.Vec3) {
+	prev, next := i-1, i+1
+	if prev < 0 {
+		prev = i
+	}
+	if next >= len(me.Keys) {
+		next = i
+	}
+	if dt := me.Keys[next].Time - me.Keys[prev].Time; dt > 0 {
+		p, n, s := &me.Keys[prev].Value, &me.Keys[next].Value, span/dt
+		m.Set((n.X-p.X)*s, (n.Y-p.Y)*s, (n.Z-p.Z)*s)
+	} else {
+		m.Set(0, 0, 0)
+	}
+}
+
+//	Sets q to the (normalized) spherical linear interpolation from a to b by f, along the shorter arc.
+func quatSlerp(q, a, b *[4]float64, f float64) {
+	dot, sign := a[0]*b[0]+a[1]*b[1]+a[2]*b[2]+a[3]*b[3], 1.0
+	if dot < 0 {
+		dot, sign = -dot, -1
+	}
+	wa, wb := 1-f, f*sign
+	if dot < 0.9995 {
+		//	otherwise, the quaternions are close enough to interpolate linearly
+		theta := math.Acos(dot)
+		s := math.Sin(theta)
+		wa, wb = math.Sin((1-f)*theta)/s, sign*math.Sin(f*theta)/s
+	}
+	var l float64
+	for i := 0; i < 4; i++ {
+		q[i] = wa*a[i] + wb*b[i]
+		l += q[i] * q[i]
+	}
+	if l = math.Sqrt(l); l > 0 {
+		for i := 0; i < 4; i++ {
+			q[i] /= l
+		}
+	}
+}
+
+//	Sets q to the unit quaternion (x, y, z, w) of the rotation in the upper-left 3x3 of mat, which must not be scaled.
+func quatFromMatrix(q *[4]float64, mat *unum.Mat4) {
+	m00, m01, m02 := mat[0], mat[4], mat[8]
+	m10, m11, m12 := mat[1], mat[5], mat[9]
+	m20, m21, m22 := mat[2], mat[6], mat[10]
+	if tr := m00 + m11 + m22; tr > 0 {
+		s := 0.5 / math.Sqrt(tr+1)
+		*q = [4]float64{(m21 - m12) * s, (m02 - m20) * s, (m10 - m01) * s, 0.25 / s}
+	} else if m00 > m11 && m00 > m22 {
+		s := 2 * math.Sqrt(1+m00-m11-m22)
+		*q = [4]float64{0.25 * s, (m01 + m10) / s, (m02 + m20) / s, (m21 - m12) / s}
+	} else if m11 > m22 {
+		s := 2 * math.Sqrt(1+m11-m00-m22)
+		*q = [4]float64{(m01 + m10) / s, 0.25 * s, (m12 + m21) / s, (m02 - m20) / s}
+	} else {
+		s := 2 * math.Sqrt(1+m22-m00-m11)
+		*q = [4]float64{(m02 + m20) / s, (m12 + m21) / s, 0.25 * s, (m10 - m01) / s}
+	}
+}
+
+//	Sets rot to the Euler angles (as per SceneNodeTransform.Rot) of the quaternion q (x, y, z, w), which need not be normalized.
+func quatToEuler(q *[4]float64, rot *unum.Vec3) {
+	var tr SceneNodeTransform
+	x, y, z, w := q[0], q[1], q[2], q[3]
+	if l := math.Sqrt(x*x + y*y + z*z + w*w); l > 0 {
+		x, y, z, w = x/l, y/l, z/l, w/l
+	}
+	mat := unum.Mat4{
+		1 - 2*(y*y+z*z), 2 * (x*y + z*w), 2 * (x*z - y*w), 0,
+		2 * (x*y - z*w), 1 - 2*(x*x+z*z), 2 * (y*z + x*w), 0,
+		2 * (x*z + y*w), 2 * (y*z - x*w), 1 - 2*(x*x+y*y), 0,
+		0, 0, 0, 1,
+	}
+	tr.setFromMatrix(&mat)
+	*rot = tr.Rot
+}
+
+//	A reusable keyframe animation, played back on scene nodes by AnimPlayers (see Scene.AddNewAnimPlayer()).
+type AnimClip struct {
+	ID   int
+	Name string
+
+	Tracks []AnimTrack
+
+	//	Need not be sorted.
+	Events []AnimEvent
+
+	libGen uint64
+}
+
+func (me *AnimClip) dispose() {
+}
+
+func (me *AnimClip) init() {
+	me.Name, me.Tracks, me.Events = "", nil, nil
+}
+
+//	Returns the time of the last key or event in me.
+func (me *AnimClip) Duration() (dur float64) {
+	for i := 0; i < len(me.Tracks); i++ {
+		if n := len(me.Tracks[i].Keys); n > 0 {
+			dur = math.Max(dur, me.Tracks[i].Keys[n-1].Time)
+		}
+	}
+	for i := 0; i < len(me.Events); i++ {
+		dur = math.Max(dur, me.Events[i].Time)
+	}
+	return
+}
+
+//#begin-gt -gen-lib.gt T:AnimClip L:Core.Libs.AnimClips
+
+//	A generational handle to a AnimClip in Core.Libs.AnimClips.
+//	Unlike a plain ID, it is never silently resolved to another AnimClip that
+//	later re-uses the same ID after the original was removed or moved by Compact().
+type AnimClipHandle struct {
+	id  int
+	gen uint64
+}
+
+//	Returns the ID that me referred to when last resolved. It may since have become invalid.
+func (me AnimClipHandle) ID() int {
+	return me.id
+}
+
+//	Returns true if me is the zero AnimClipHandle (or was detected to be stale), which never resolves to a AnimClip.
+func (me AnimClipHandle) IsNil() bool {
+	return me.gen == 0
+}
+
+//	Only used for Core.Libs.AnimClips
+type AnimClipLib []AnimClip
+
+func (me *AnimClipLib) AddNew() (id int) {
+	id = -1
+	for i := 0; i < len(*me); i++ {
+		if (*me)[i].ID == -1 {
+			id = i
+			break
+		}
+	}
+	if id == -1 {
+		if id = len(*me); id == cap(*me) {
+			nu := make(AnimClipLib, id, id+Options.Libs.GrowCapBy)
+			copy(nu, *me)
+			*me = nu
+		}
+		*me = append(*me, AnimClip{})
+	}
+	ref := &(*me)[id]
+	ref.ID, ref.libGen = id, libGenNext()
+	ref.init()
+	return
+}
+
+func (me *AnimClipLib) Compact() {
+	var (
+		before, after []AnimClip
+		ref           *AnimClip
+		oldID, i      int
+		compact       bool
+	)
+	for i = 0; i < len(*me); i++ {
+		if (*me)[i].ID == -1 {
+			compact, before, after = true, (*me)[:i], (*me)[i+1:]
+			*me = append(before, after...)
+			//	re-check i, which now holds the next entry
+			i--
+		}
+	}
+	if compact {
+		changed := make(map[int]int, len(*me))
+		for i = 0; i < len(*me); i++ {
+			if ref = &(*me)[i]; ref.ID != i {
+				oldID, ref.ID = ref.ID, i
+				changed[oldID] = i
+				libGenMove(ref.libGen, i)
+			}
+		}
+		if len(changed) > 0 {
+			me.onAnimClipIDsChanged(changed)
+		}
+	}
+}
+
+func (me *AnimClipLib) init() {
+	*me = make(AnimClipLib, 0, Options.Libs.InitialCap)
+}
+
+func (me *AnimClipLib) dispose() {
+	me.Remove(0, 0)
+	*me = (*me)[:0]
+}
+
+func (me AnimClipLib) get(id int) (ref *AnimClip) {
+	if me.IsOk(id) {
+		ref = &me[id]
+	}
+	return
+}
+
+//	Returns the AnimClip that h refers to, or nil if it has since been removed.
+//	If it was moved by Compact(), h is updated to its new ID. If it was removed, h becomes nil.
+func (me AnimClipLib) Deref(h *AnimClipHandle) (ref *AnimClip) {
+	if ref = me.deref(*h); ref != nil {
+		h.id = ref.ID
+	} else if h.gen != 0 {
+		*h = AnimClipHandle{id: -1}
+	}
+	return
+}
+
+//	Like Deref(), but leaves h as is: safe to call on handles owned by another thread.
+func (me AnimClipLib) deref(h AnimClipHandle) (ref *AnimClip) {
+	if h.gen != 0 {
+		id := h.id
+		if id < 0 || id >= len(me) || me[id].libGen != h.gen {
+			id = libGenMovedTo(h.gen)
+		}
+		if id > -1 && id < len(me) && me[id].ID == id && me[id].libGen == h.gen {
+			ref = &me[id]
+		}
+	}
+	return
+}
+
+//	Returns a AnimClipHandle to the AnimClip with the specified ID, or a nil AnimClipHandle if id is invalid.
+func (me AnimClipLib) Handle(id int) (h AnimClipHandle) {
+	if h.id = -1; me.IsOk(id) {
+		h.id, h.gen = id, me[id].libGen
+	}
+	return
+}
+
+func (me AnimClipLib) IsOk(id int) (ok bool) {
+	if id > -1 && id < len(me) {
+		ok = me[id].ID == id
+	}
+	return
+}
+
+func (me AnimClipLib) Ok(id int) bool {
+	return me[id].ID == id
+}
+
+func (me AnimClipLib) Remove(fromID, num int) {
+	if l := len(me); fromID > -1 && fromID < l {
+		if num < 1 || num > (l-fromID) {
+			num = l - fromID
+		}
+		changed := make(map[int]int, num)
+		for id := fromID; id < fromID+num; id++ {
+			me[id].dispose()
+			libGenForget(me[id].libGen)
+			changed[id], me[id].ID, me[id].libGen = -1, -1, 0
+		}
+		me.onAnimClipIDsChanged(changed)
+	}
+}
+
+func (me AnimClipLib) Walk(on func(ref *AnimClip)) {
+	for id := 0; id < len(me); id++ {
+		if me.Ok(id) {
+			on(&me[id])
+		}
+	}
+}
+
+//#end-gt
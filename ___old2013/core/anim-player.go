@@ -0,0 +1,302 @@
+package core
+
+import (
+	"math"
+
+	"github.com/metaleap/go-util-num"
+	gl "github.com/metaleap/go-opengl/core"
+)
+
+//	Controls what an AnimPlayer does once it reaches the end of its AnimClip.
+type AnimMode int
+
+const (
+	//	Restarts from the beginning.
+	AnimModeLoop AnimMode = iota
+
+	//	Plays backwards to the beginning, then forwards again, and so on.
+	AnimModePingPong
+
+	//	Stops at the end, setting AnimPlayer.Playing to false.
+	AnimModeOnce
+)
+
+//	Plays back an AnimClip on the nodes of a Scene. Created via Scene.AddNewAnimPlayer().
+//	All AnimPlayers are evaluated on the app thread right before each Loop.On.AppThread() call.
+type AnimPlayer struct {
+	//	The AnimClip in Core.Libs.AnimClips to play, see AnimClipLib.Handle().
+	Clip AnimClipHandle
+
+	//	Defaults to AnimModeLoop.
+	Mode AnimMode
+
+	//	Set to false to pause playback. Defaults to true.
+	Playing bool
+
+	//	The playback speed factor. Must not be negative. Defaults to 1.
+	Speed float64
+
+	//	The current playback position in seconds from the start of the AnimClip.
+	Time float64
+
+	//	If set, called (on the app thread) whenever playback passes an AnimEvent of the AnimClip.
+	OnEvent func(player *AnimPlayer, evt *AnimEvent)
+
+	root    SceneNodeHandle
+	nodes   []SceneNodeHandle
+	fxInsts []animFxInst
+	bound   bool
+	fresh   bool
+	reverse bool
+}
+
+//	A node's copy of its FxMaterial and that material's DefaultEffectID, made for the AnimTargetFx* tracks of an AnimPlayer.
+type animFxInst struct {
+	node      SceneNodeHandle
+	mat, orig FxMaterialHandle
+}
+
+func (me *AnimPlayer) init(clipID int, root SceneNodeHandle) {
+	me.Clip, me.root, me.Mode, me.Playing, me.Speed = Core.Libs.AnimClips.Handle(clipID), root, AnimModeLoop, true, 1
+	me.Restart()
+}
+
+//	Moves the playback position back to the start of the AnimClip and resumes playback.
+func (me *AnimPlayer) Restart() {
+	me.Time, me.Playing, me.fresh, me.reverse = 0, true, true, false
+}
+
+//	Makes me look up the nodes of all AnimTracks by name again before its next evaluation.
+//	Only necessary after renaming, adding or removing nodes in the sub-tree of me.RootNodeID().
+func (me *AnimPlayer) Rebind() {
+	me.bound = false
+}
+
+//	Returns the ID of the node whose sub-tree me animates, or -1 if it has since been removed.
+func (me *AnimPlayer) RootNodeID() int {
+	if me.root.IsNil() {
+		return -1
+	}
+	return me.root.ID()
+}
+
+func (me *AnimPlayer) bind(scene *Scene, clip *AnimClip) {
+	me.bound, me.nodes = true, me.nodes[:0]
+	rootID := -1
+	if root := scene.allNodes.Deref(&me.root); root != nil {
+		rootID = root.ID
+	}
+	for i := 0; i < len(clip.Tracks); i++ {
+		if len(clip.Tracks[i].NodeName) == 0 {
+			me.nodes = append(me.nodes, scene.allNodes.Handle(rootID))
+		} else {
+			me.nodes = append(me.nodes, scene.allNodes.Handle(scene.NodeByName(rootID, clip.Tracks[i].NodeName)))
+		}
+	}
+}
+
+//	Advances me.Time by delta seconds (times me.Speed) according to me.Mode, firing all AnimEvents passed on the way.
+func (me *AnimPlayer) advance(clip *AnimClip, delta float64) {
+	dur, step := clip.Duration(), delta*me.Speed
+	if dur <= 0 {
+		me.fireEvents(clip, 0, 0, false)
+		if me.Time = 0; me.Mode == AnimModeOnce {
+			me.Playing = false
+		}
+		return
+	}
+	if me.Mode != AnimModeOnce && step > 2*dur {
+		//	skip whole cycles: their events aren't fired repeatedly
+		step = math.Mod(step, 2*dur)
+	}
+	for end := 0.0; step > 0 && me.Playing; {
+		if me.reverse {
+			if end = me.Time - step; end > 0 {
+				me.fireEvents(clip, end, me.Time, true)
+				me.Time, step = end, 0
+			} else {
+				me.fireEvents(clip, 0, me.Time, true)
+				step -= me.Time
+				me.Time, me.reverse = 0, false
+			}
+		} else if end = me.Time + step; end < dur {
+			me.fireEvents(clip, me.Time, end, false)
+			me.Time, step = end, 0
+		} else {
+			me.fireEvents(clip, me.Time, dur, false)
+			step -= dur - me.Time
+			switch me.Mode {
+			case AnimModeLoop:
+				me.Time, me.fresh = 0, true
+			case AnimModePingPong:
+				me.Time, me.reverse = dur, true
+			default:
+				me.Time, me.Playing = dur, false
+			}
+		}
+	}
+}
+
+//	Fires all events in (from .. to] when playing forward, or in [from .. to) when playing in reverse.
+//	Right after a (re)start, from itself is included when playing forward.
+func (me *AnimPlayer) fireEvents(clip *AnimClip, from, to float64, reverse bool) {
+	if me.OnEvent != nil {
+		var evt *AnimEvent
+		for i := 0; i < len(clip.Events); i++ {
+			if evt = &clip.Events[i]; (reverse && evt.Time >= from && evt.Time < to) || (!reverse && evt.Time <= to && (evt.Time > from || (me.fresh && evt.Time == from))) {
+				me.OnEvent(me, evt)
+			}
+		}
+	}
+	me.fresh = false
+}
+
+func (me *AnimPlayer) animate(scene *Scene, delta float64) {
+	clip := Core.Libs.AnimClips.Deref(&me.Clip)
+	if clip == nil || !me.Playing {
+		return
+	}
+	if !me.bound || len(me.nodes) != len(clip.Tracks) {
+		me.bind(scene, clip)
+	}
+	me.advance(clip, delta)
+	var (
+		val   unum.Vec3
+		rot   [4]float64
+		node  *SceneNode
+		track *AnimTrack
+		proc  *FxProc
+	)
+	for i := 0; i < len(clip.Tracks); i++ {
+		if track = &clip.Tracks[i]; len(track.Keys) > 0 {
+			if node = scene.allNodes.Deref(&me.nodes[i]); node != nil {
+				if track.Target == AnimTargetRot {
+					track.sampleRot(me.Time, &rot)
+				} else {
+					track.sample(me.Time, &val)
+				}
+				switch track.Target {
+				case AnimTargetPos:
+					node.Transform.Pos = val
+					scene.animDirty(node.ID)
+				case AnimTargetRot:
+					quatToEuler(&rot, &node.Transform.Rot)
+					scene.animDirty(node.ID)
+				case AnimTargetScale:
+					node.Transform.Scale = val
+					scene.animDirty(node.ID)
+				case AnimTargetFxMixWeight:
+					if proc = me.animFxProc(scene, node, track); proc != nil {
+						proc.SetMixWeight(val.X)
+					}
+				case AnimTargetFxColor:
+					if proc = me.animFxProc(scene, node, track); proc != nil {
+						proc.Color_SetRgb(gl.Float(val.X), gl.Float(val.Y), gl.Float(val.Z))
+					}
+				case AnimTargetMorphWeight:
+					node.SetMorphWeight(track.MorphTarget, val.X)
+				}
+			}
+		}
+	}
+}
+
+//	Returns the FxProc animated by track in the DefaultEffectID of the material of node, if any.
+//	Unless me did so before, first gives node its own copies of that FxMaterial and FxEffect.
+func (me *AnimPlayer) animFxProc(scene *Scene, node *SceneNode, track *AnimTrack) *FxProc {
+	_, mat := node.meshMat()
+	if mat == nil || Core.Libs.Effects.get(mat.DefaultEffectID) == nil {
+		return nil
+	}
+	owned := false
+	for i := 0; i < len(me.fxInsts) && !owned; i++ {
+		owned = !node.Render.Mat.IsNil() && me.fxInsts[i].mat == node.Render.Mat
+	}
+	if !owned {
+		src, srcFxID := *mat, mat.DefaultEffectID
+		fxID := Core.Libs.Effects.AddNew()
+		srcFx, fx := &Core.Libs.Effects[srcFxID], &Core.Libs.Effects[fxID]
+		fx.FxProcs, fx.ext = append(FxProcs(nil), srcFx.FxProcs...), append(FxProcs(nil), srcFx.ext...)
+		fx.KeepProcIDsLast = append([]string(nil), srcFx.KeepProcIDsLast...)
+		fx.UpdateRoutine()
+		matID := Core.Libs.Materials.AddNew()
+		mat = &Core.Libs.Materials[matID]
+		mat.DefaultEffectID = fxID
+		for _, m := range [][2]map[string]int{{mat.FaceEffects.ByTag, src.FaceEffects.ByTag}, {mat.FaceEffects.ByID, src.FaceEffects.ByID}} {
+			for key, id := range m[1] {
+				if id == srcFxID {
+					id = fxID
+				}
+				m[0][key] = id
+			}
+		}
+		me.fxInsts = append(me.fxInsts, animFxInst{node: scene.allNodes.Handle(node.ID), mat: Core.Libs.Materials.Handle(matID), orig: node.Render.Mat})
+		node.SetMatID(matID)
+	}
+	return Core.Libs.Effects[mat.DefaultEffectID].FxProcs.Get(track.FxProcID, track.FxProcN)
+}
+
+//	Creates a new AnimPlayer that plays the AnimClip with the specified clipID on the sub-tree of rootNodeID,
+//	starting with the next frame. Returns nil if rootNodeID is invalid.
+func (me *Scene) AddNewAnimPlayer(clipID, rootNodeID int) (player *AnimPlayer) {
+	if me.allNodes.IsOk(rootNodeID) {
+		player = &AnimPlayer{}
+		player.init(clipID, me.allNodes.Handle(rootNodeID))
+		me.animPlayers = append(me.animPlayers, player)
+	}
+	return
+}
+
+//	Stops and discards the specified player, which must have been created by me.AddNewAnimPlayer().
+//	Nodes it gave their own FxMaterial copies (for AnimTargetFx* tracks) get back their previous Render.Mat.
+func (me *Scene) RemoveAnimPlayer(player *AnimPlayer) {
+	for i := 0; i < len(me.animPlayers); i++ {
+		if me.animPlayers[i] == player {
+			me.animPlayers = append(me.animPlayers[:i], me.animPlayers[i+1:]...)
+			break
+		}
+	}
+	for _, inst := range player.fxInsts {
+		if node := me.allNodes.Deref(&inst.node); node != nil && node.Render.Mat == inst.mat {
+			node.Render.Mat = inst.orig
+		}
+		if mat := Core.Libs.Materials.Deref(&inst.mat); mat != nil {
+			Core.Libs.Effects.Remove(mat.DefaultEffectID, 1)
+			Core.Libs.Materials.Remove(mat.ID, 1)
+		}
+	}
+	player.fxInsts = nil
+}
+
+//	Marks the Transform of nodeID as changed by an AnimPlayer during the current onAnimate().
+func (me *Scene) animDirty(nodeID int) {
+	if !me.allNodes[nodeID].thrApp.animDirty {
+		me.allNodes[nodeID].thrApp.animDirty = true
+		me.thrApp.animDirty = append(me.thrApp.animDirty, nodeID)
+	}
+}
+
+//	Evaluates all AnimPlayers of me, then applies the resulting node transformations.
+func (me *Scene) onAnimate(delta float64) {
+	if len(me.animPlayers) > 0 {
+		for _, player := range me.animPlayers {
+			player.animate(me, delta)
+		}
+		var ancestorDirty bool
+		for _, nodeID := range me.thrApp.animDirty {
+			//	ApplyNodeTransforms() covers all child-nodes, so only apply it to the top-most animated nodes
+			ancestorDirty = false
+			for id := me.allNodes[nodeID].parentID; id > -1 && !ancestorDirty; id = me.allNodes[id].parentID {
+				ancestorDirty = me.allNodes[id].thrApp.animDirty
+			}
+			if !ancestorDirty {
+				me.ApplyNodeTransforms(nodeID)
+				me.applyAncestorBounds(nodeID)
+			}
+		}
+		for _, nodeID := range me.thrApp.animDirty {
+			me.allNodes[nodeID].thrApp.animDirty = false
+		}
+		me.thrApp.animDirty = me.thrApp.animDirty[:0]
+	}
+}
@@ -0,0 +1,181 @@
+package core
+
+import (
+	"math"
+
+	"github.com/metaleap/go-util-num"
+)
+
+//	A low-resolution CPU depth buffer and its hierarchical-Z mip chain, used for Camera.Cull.Occlusion.
+//	Depths are in [0 (near) .. 1 (far)]. Each texel in levels[l > 0] holds the greatest (farthest) depth
+//	of the 2x2 texels it covers in levels[l-1].
+type cameraOcclusionBuf struct {
+	levels  [][]float32
+	widths  []int
+	heights []int
+}
+
+func (me *cameraOcclusionBuf) ensureSize(width, height int) {
+	if len(me.levels) == 0 || me.widths[0] != width || me.heights[0] != height {
+		me.levels, me.widths, me.heights = nil, nil, nil
+		for w, h := width, height; ; w, h = (w+1)/2, (h+1)/2 {
+			me.levels, me.widths, me.heights = append(me.levels, make([]float32, w*h)), append(me.widths, w), append(me.heights, h)
+			if w == 1 && h == 1 {
+				break
+			}
+		}
+	}
+}
+
+func (me *cameraOcclusionBuf) clear() {
+	for i := 0; i < len(me.levels[0]); i++ {
+		me.levels[0][i] = 1
+	}
+}
+
+func (me *cameraOcclusionBuf) buildHiZ() {
+	var x, y, sx, sy, w, h, sw, sh int
+	var d float32
+	for l := 1; l < len(me.levels); l++ {
+		w, h, sw, sh = me.widths[l], me.heights[l], me.widths[l-1], me.heights[l-1]
+		src, dst := me.levels[l-1], me.levels[l]
+		for y = 0; y < h; y++ {
+			for x = 0; x < w; x++ {
+				d = 0
+				for sy = 2 * y; sy < 2*y+2 && sy < sh; sy++ {
+					for sx = 2 * x; sx < 2*x+2 && sx < sw; sx++ {
+						if src[sy*sw+sx] > d {
+							d = src[sy*sw+sx]
+						}
+					}
+				}
+				dst[y*w+x] = d
+			}
+		}
+	}
+}
+
+//	Rasterizes the triangle with the specified screen-space corners (in texels of levels[0], z being depth) into me.
+//	For simplicity (and conservatively so) the whole triangle is written with the farthest depth of its corners.
+func (me *cameraOcclusionBuf) rasterize(v *[3]unum.Vec3) {
+	area := (v[1].X-v[0].X)*(v[2].Y-v[0].Y) - (v[2].X-v[0].X)*(v[1].Y-v[0].Y)
+	if area == 0 {
+		return
+	}
+	w, h := me.widths[0], me.heights[0]
+	x0, x1 := int(math.Max(0, math.Floor(math.Min(v[0].X, math.Min(v[1].X, v[2].X))))), int(math.Min(float64(w-1), math.Ceil(math.Max(v[0].X, math.Max(v[1].X, v[2].X)))))
+	y0, y1 := int(math.Max(0, math.Floor(math.Min(v[0].Y, math.Min(v[1].Y, v[2].Y))))), int(math.Min(float64(h-1), math.Ceil(math.Max(v[0].Y, math.Max(v[1].Y, v[2].Y)))))
+	depth := float32(math.Max(v[0].Z, math.Max(v[1].Z, v[2].Z)))
+	var px, py, e0, e1, e2 float64
+	for y := y0; y <= y1; y++ {
+		for x := x0; x <= x1; x++ {
+			px, py = float64(x)+0.5, float64(y)+0.5
+			e0 = ((v[2].X-v[1].X)*(py-v[1].Y) - (v[2].Y-v[1].Y)*(px-v[1].X)) * area
+			e1 = ((v[0].X-v[2].X)*(py-v[2].Y) - (v[0].Y-v[2].Y)*(px-v[2].X)) * area
+			e2 = ((v[1].X-v[0].X)*(py-v[0].Y) - (v[1].Y-v[0].Y)*(px-v[0].X)) * area
+			if e0 >= 0 && e1 >= 0 && e2 >= 0 && depth < me.levels[0][y*w+x] {
+				me.levels[0][y*w+x] = depth
+			}
+		}
+	}
+}
+
+//	Returns true if the screen-space rectangle from (x0, y0) to (x1, y1) (in texels of levels[0])
+//	is fully covered by depths nearer than minDepth.
+func (me *cameraOcclusionBuf) occludes(x0, y0, x1, y1 int, minDepth float32) bool {
+	l := 0
+	for ; l < len(me.levels)-1 && ((x1>>uint(l))-(x0>>uint(l)) > 1 || (y1>>uint(l))-(y0>>uint(l)) > 1); l++ {
+	}
+	w, lx0, ly0, lx1, ly1 := me.widths[l], x0>>uint(l), y0>>uint(l), x1>>uint(l), y1>>uint(l)
+	for y := ly0; y <= ly1; y++ {
+		for x := lx0; x <= lx1; x++ {
+			if me.levels[l][y*w+x] >= minDepth {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+//	Projects the world-space point p into screen space (x, y in texels of levels[0], z as depth in [0..1]).
+//	Returns false if p is behind (or too close to) the camera's near plane.
+func (me *Camera) occlusionProject(mat *unum.Mat4, p *unum.Vec3, buf *cameraOcclusionBuf, dst *unum.Vec3) bool {
+	cw := mat[3]*p.X + mat[7]*p.Y + mat[11]*p.Z + mat[15]
+	if cw < me.Perspective.ZNear*0.5 {
+		return false
+	}
+	cx := (mat[0]*p.X + mat[4]*p.Y + mat[8]*p.Z + mat[12]) / cw
+	cy := (mat[1]*p.X + mat[5]*p.Y + mat[9]*p.Z + mat[13]) / cw
+	cz := (mat[2]*p.X + mat[6]*p.Y + mat[10]*p.Z + mat[14]) / cw
+	dst.Set((cx*0.5+0.5)*float64(buf.widths[0]), (0.5-cy*0.5)*float64(buf.heights[0]), cz*0.5+0.5)
+	return true
+}
+
+//	Rasterizes the occluders among all nodes that passed frustum culling, then removes
+//	from these all nodes whose world-space bounding box is completely hidden behind them.
+func (me *Camera) onPrepOcclusion(all SceneNodeLib, batchCounter *int) {
+	var (
+		mesh    *Mesh
+		mat     *FxMaterial
+		tri     [3]unum.Vec3
+		corner  unum.Vec3
+		ok      bool
+		fi, ci  int
+		x0, y0  float64
+		x1, y1  float64
+		minZ    float64
+		aabbMin *unum.Vec3
+		aabbMax *unum.Vec3
+	)
+	buf := &me.thrPrep.occlusion
+	buf.ensureSize(Options.Cameras.OcclusionBufSize.Width, Options.Cameras.OcclusionBufSize.Height)
+	buf.clear()
+	for nodeID := 1; nodeID < len(all) && nodeID < len(me.thrPrep.layer.thrPrep.nodeRender); nodeID++ {
+		if me.thrPrep.layer.thrPrep.nodeRender[nodeID] && all.Ok(nodeID) && all[nodeID].Render.Occluder && !all[nodeID].Render.skyMode {
+			if mesh = Core.Libs.Meshes.get(me.thrPrep.layer.thrPrep.nodeMeshIDs[nodeID]); mesh != nil {
+				for fi = 0; fi < len(mesh.raw.faces); fi++ {
+					for ci, ok = 0, true; ok && ci < 3; ci++ {
+						ok = me.occlusionProject(&me.thrPrep.layer.thrPrep.nodeProjMats[nodeID], &mesh.raw.faces[fi].pos[ci], buf, &tri[ci])
+					}
+					if ok {
+						buf.rasterize(&tri)
+					}
+				}
+			}
+		}
+	}
+	buf.buildHiZ()
+	for nodeID := 1; nodeID < len(all) && nodeID < len(me.thrPrep.layer.thrPrep.nodeRender); nodeID++ {
+		if me.thrPrep.layer.thrPrep.nodeRender[nodeID] && all.Ok(nodeID) && all[nodeID].Render.Cull.Occlusion && !all[nodeID].Render.skyMode {
+			aabbMin, aabbMax = &all[nodeID].thrPrep.bounding.self.AaBox.Min, &all[nodeID].thrPrep.bounding.self.AaBox.Max
+			x0, y0, minZ, x1, y1 = math.Inf(1), math.Inf(1), math.Inf(1), math.Inf(-1), math.Inf(-1)
+			for ci, ok = 0, true; ok && ci < 8; ci++ {
+				corner = *aabbMin
+				if ci&1 != 0 {
+					corner.X = aabbMax.X
+				}
+				if ci&2 != 0 {
+					corner.Y = aabbMax.Y
+				}
+				if ci&4 != 0 {
+					corner.Z = aabbMax.Z
+				}
+				if ok = me.occlusionProject(&me.thrPrep.matCamProj, &corner, buf, &tri[0]); ok {
+					x0, y0, minZ = math.Min(x0, tri[0].X), math.Min(y0, tri[0].Y), math.Min(minZ, tri[0].Z)
+					x1, y1 = math.Max(x1, tri[0].X), math.Max(y1, tri[0].Y)
+				}
+			}
+			//	boxes crossing the near plane are always considered visible
+			x0, y0 = math.Max(0, x0), math.Max(0, y0)
+			x1, y1 = math.Min(float64(buf.widths[0]-1), x1), math.Min(float64(buf.heights[0]-1), y1)
+			if ok && x0 <= x1 && y0 <= y1 && buf.occludes(int(x0), int(y0), int(x1), int(y1), float32(minZ)) {
+				me.thrPrep.layer.thrPrep.nodeRender[nodeID] = false
+				if mesh = Core.Libs.Meshes.get(me.thrPrep.layer.thrPrep.nodeMeshIDs[nodeID]); mesh != nil {
+					if mat = all[nodeID].matFor(mesh); mat != nil {
+						*batchCounter = *batchCounter - nodeBatchCount(mesh, mat)
+					}
+				}
+			}
+		}
+	}
+}
@@ -16,23 +16,61 @@ type Camera struct {
 
 	Cull struct {
 		Frustum bool
+
+		//	If true, nodes completely hidden behind nodes that have Render.Occluder set are not rendered.
+		//	Occluders are rasterized into a low-resolution CPU depth buffer (see Options.Cameras.OcclusionBufSize)
+		//	by the prep thread. Only used if Perspective.Enabled. Defaults to false.
+		Occlusion bool
 	}
 
 	viewportAspectRatio float64 // copied over from the parent RenderView.Port
-	sceneID             int
+	layers              []*CameraLayer
 
 	thrApp struct {
 		matProj unum.Mat4
 	}
 	thrPrep struct {
 		matCamProj, matProj, matPos unum.Mat4
-		nodeRender                  []bool
-		nodeProjMats                []unum.Mat4
 		frustum                     u3d.Frustum
+		occlusion                   cameraOcclusionBuf
+		layers                      []*CameraLayer
+
+		//	The layer currently being prepped.
+		layer *CameraLayer
+	}
+	thrRend struct {
+		layers []*CameraLayer
+	}
+}
+
+//	A Scene rendered by a Camera, see Camera.AddLayer(). A Camera renders all its layers in order
+//	into the same viewport, so that a static level, its actors and a UI overlay can live in separate Scenes.
+//	Each layer keeps its own per-node camera data, so adding or removing one doesn't affect the others.
+type CameraLayer struct {
+	//	If true, the depth buffer is cleared before this layer is rendered, so that it
+	//	is drawn over all previous layers regardless of depth. Defaults to false.
+	ClearDepth bool
+
+	//	Set to false to skip this layer. Defaults to true.
+	Enabled bool
+
+	//	A plain ID rather than a SceneHandle: onSceneIDsChanged() remaps it whenever Core.Libs.Scenes
+	//	moves or removes the Scene (the latter resets it to -1), so it never refers to another Scene.
+	sceneID int
+
+	thrPrep struct {
+		enabled, clearDepth  bool
+		nodeRender           []bool
+		nodeProjMats         []unum.Mat4
+		nodeLod, nodeMeshIDs []int
+		batch                renderBatchList
 	}
 	thrRend struct {
-		nodeProjMats []ugl.GlMat4
-		nodeRender   []bool
+		enabled, clearDepth bool
+		nodeProjMats        []ugl.GlMat4
+		nodeRender          []bool
+		nodeMeshIDs         []int
+		batch               renderBatchList
 	}
 }
 
@@ -54,7 +92,56 @@ func (me *Camera) applyPerspective() {
 	}
 }
 
-func (me *Camera) ensureProjMats(length int) {
+//	Adds a new layer rendering the Scene with the specified sceneID after all existing layers of me.
+func (me *Camera) AddLayer(sceneID int) (layer *CameraLayer) {
+	layer = &CameraLayer{Enabled: true, sceneID: -1}
+	layer.SetScene(sceneID)
+	me.layers = append(me.layers, layer)
+	return
+}
+
+//	Returns all layers of me, in rendering order. The slice must not be modified; use AddLayer() and RemoveLayer() instead.
+func (me *Camera) Layers() []*CameraLayer {
+	return me.layers
+}
+
+//	Removes the specified layer from me, which stops rendering its Scene from the next frame on.
+func (me *Camera) RemoveLayer(layer *CameraLayer) {
+	for i := 0; i < len(me.layers); i++ {
+		if me.layers[i] == layer {
+			me.layers = append(me.layers[:i], me.layers[i+1:]...)
+			break
+		}
+	}
+}
+
+//	Returns the Scene rendered by the first layer of me, if any.
+func (me *Camera) Scene() *Scene {
+	if len(me.layers) > 0 {
+		return me.layers[0].Scene()
+	}
+	return nil
+}
+
+//	Sets the Scene rendered by the first layer of me, adding that layer if me has none.
+func (me *Camera) SetScene(sceneID int) {
+	if len(me.layers) == 0 {
+		me.AddLayer(sceneID)
+	} else {
+		me.layers[0].SetScene(sceneID)
+	}
+}
+
+//	Calls initNodeCamData() for nodeID on all layers of me that render scene.
+func (me *Camera) initNodeCamData(scene *Scene, nodeID int) {
+	for _, layer := range me.layers {
+		if layer.sceneID == scene.ID {
+			layer.initNodeCamData(scene.allNodes, nodeID)
+		}
+	}
+}
+
+func (me *CameraLayer) ensureProjMats(length int) {
 	if len(me.thrPrep.nodeProjMats) < length {
 		nu := make([]unum.Mat4, length)
 		copy(nu, me.thrPrep.nodeProjMats)
@@ -67,20 +154,28 @@ func (me *Camera) ensureProjMats(length int) {
 	}
 }
 
-func (me *Camera) initNodeCamData(all SceneNodeLib, nodeID int) {
+func (me *CameraLayer) initNodeCamData(all SceneNodeLib, nodeID int) {
 	me.ensureProjMats(len(all))
 	me.thrPrep.nodeProjMats[nodeID].Identity()
 	uslice.BoolEnsureLen(&me.thrPrep.nodeRender, len(all))
 	uslice.BoolEnsureLen(&me.thrRend.nodeRender, len(all))
+	uslice.IntEnsureLen(&me.thrPrep.nodeLod, len(all))
+	uslice.IntEnsureLen(&me.thrPrep.nodeMeshIDs, len(all))
+	uslice.IntEnsureLen(&me.thrRend.nodeMeshIDs, len(all))
 	me.thrPrep.nodeRender[nodeID] = false
 	me.thrRend.nodeRender[nodeID] = false
+	me.thrPrep.nodeLod[nodeID], me.thrPrep.nodeMeshIDs[nodeID], me.thrRend.nodeMeshIDs[nodeID] = -1, -1, -1
 }
 
-func (me *Camera) Scene() *Scene {
+func (me *CameraLayer) Scene() *Scene {
 	return Core.Libs.Scenes.get(me.sceneID)
 }
 
-func (me *Camera) SetScene(sceneID int) {
+func (me *CameraLayer) SceneID() int {
+	return me.sceneID
+}
+
+func (me *CameraLayer) SetScene(sceneID int) {
 	if sceneID != me.sceneID {
 		me.sceneID = sceneID
 		if scene := me.Scene(); scene != nil {
@@ -86,6 +86,17 @@ func (me *Controller) Dir() *unum.Vec3 {
 	return &me.dir
 }
 
+//	Points me in the specified direction, which need not be normalized.
+func (me *Controller) SetDir(dir *unum.Vec3) {
+	me.dir = *dir
+	me.dir.Normalize()
+	//	inverts applyRotation(): the horizontal turn of (0, 0, -1) about UpVec, then the vertical tilt
+	me.hAngle = unum.RadToDeg(math.Atan2(-me.dir.X, -me.dir.Z))
+	me.vAngle = -unum.RadToDeg(math.Asin(math.Max(-1, math.Min(1, me.dir.Y))))
+	me.applyRotation()
+	me.applyTranslation()
+}
+
 //	Applies all changes made to Pos, Dir or UpAxis since BeginUpdate() was last
 //	called, and recalculates this Controller's final 4x4 transformation matrix.
 //	Also resumes all matrix re-calculations typically occuring inside the
@@ -0,0 +1,294 @@
+package core
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"io"
+	"math"
+
+	"github.com/metaleap/go-util-num"
+)
+
+//	A file format written by Mesh.Export() and Scene.Export().
+type ExportFormat int
+
+const (
+	//	Wavefront OBJ. Faces are grouped by their first face tag via "usemtl" statements, but no MTL file is written.
+	ExportObj ExportFormat = iota
+
+	//	ASCII PLY, with positions, normals and texcoords ("s", "t") per vertex.
+	ExportPly
+
+	//	glTF 2.0 JSON with an embedded buffer. Faces are split into one primitive (and material) per first face tag.
+	ExportGltf
+)
+
+//	A mesh as exported: the final (de-duplicated) vertices of a Mesh, optionally transformed.
+type exportMesh struct {
+	name    string
+	pos     []unum.Vec3
+	normals []unum.Vec3
+	tex     [][2]float64
+	groups  []exportGroup
+}
+
+//	The triangles of an exportMesh sharing the same first face tag.
+type exportGroup struct {
+	tag     string
+	indices []uint32
+}
+
+//	Writes the geometry that me was loaded with (as de-duplicated by Load()), in the bind pose if skinned
+//	and without morphs, to w in the specified format. me must be Loaded().
+func (me *Mesh) Export(w io.Writer, format ExportFormat) (err error) {
+	var em *exportMesh
+	if em, err = me.exportMesh(me.Name, nil); err == nil {
+		err = exportWrite(w, format, []*exportMesh{em})
+	}
+	if err != nil {
+		err = errf("Cannot export mesh '%v': %v", me.Name, err)
+	}
+	return
+}
+
+//	Writes the geometry of all nodes in the sub-tree of rootNodeID that have a Loaded() mesh to w in the
+//	specified format, with their current world transformations baked in. Each node becomes a separate
+//	object, named after the node, with the same caveats as for Mesh.Export().
+func (me *Scene) Export(w io.Writer, rootNodeID int, format ExportFormat) (err error) {
+	var (
+		meshes []*exportMesh
+		em     *exportMesh
+		mat    unum.Mat4
+	)
+	if !me.allNodes.IsOk(rootNodeID) {
+		return errf("Cannot export node %v: invalid node ID", rootNodeID)
+	}
+	ids := []int{rootNodeID}
+	for i := 0; i < len(ids); i++ {
+		node := &me.allNodes[ids[i]]
+		ids = append(ids, node.childNodeIDs...)
+		if mesh := node.mesh(); mesh != nil && mesh.Loaded() {
+			name := node.Name
+			if len(name) == 0 {
+				name = strf("node%d", node.ID)
+			}
+			me.nodeWorldMatrix(node.ID, &mat)
+			if em, err = mesh.exportMesh(name, &mat); err != nil {
+				return errf("Cannot export node '%v': %v", name, err)
+			}
+			meshes = append(meshes, em)
+		}
+	}
+	if err = exportWrite(w, format, meshes); err != nil {
+		err = errf("Cannot export node %v: %v", rootNodeID, err)
+	}
+	return
+}
+
+//	Extracts the final vertices and faces of me, transformed by mat unless nil.
+func (me *Mesh) exportMesh(name string, mat *unum.Mat4) (em *exportMesh, err error) {
+	if !me.Loaded() {
+		err = errf("not loaded")
+		return
+	}
+	var (
+		matNorm unum.Mat4
+		v       unum.Vec3
+	)
+	fpv, numVerts := meshVertexBaseFloats, len(me.raw.vertSrc)
+	em = &exportMesh{name: name, pos: make([]unum.Vec3, numVerts), normals: make([]unum.Vec3, numVerts), tex: make([][2]float64, numVerts)}
+	if mat != nil {
+		//	normals are transformed by the inverse transpose
+		mat4InvertAffine(&matNorm, mat)
+	}
+	for i := 0; i < numVerts; i++ {
+		f := me.raw.verts[i*fpv:]
+		em.pos[i].Set(float64(f[0]), float64(f[1]), float64(f[2]))
+		em.tex[i] = [2]float64{float64(f[3]), float64(f[4])}
+		em.normals[i].Set(float64(f[5]), float64(f[6]), float64(f[7]))
+		if mat != nil {
+			v = em.pos[i]
+			mat4MultPoint(&em.pos[i], mat, &v)
+			v = em.normals[i]
+			em.normals[i].Set(
+				matNorm[0]*v.X+matNorm[1]*v.Y+matNorm[2]*v.Z,
+				matNorm[4]*v.X+matNorm[5]*v.Y+matNorm[6]*v.Z,
+				matNorm[8]*v.X+matNorm[9]*v.Y+matNorm[10]*v.Z)
+			em.normals[i].Normalize()
+		}
+	}
+	//	a mirroring transformation flips the winding of all faces
+	mirror := mat != nil && mat[0]*(mat[5]*mat[10]-mat[9]*mat[6])-mat[4]*(mat[1]*mat[10]-mat[9]*mat[2])+mat[8]*(mat[1]*mat[6]-mat[5]*mat[2]) < 0
+	groups := map[string]int{}
+	for fi := 0; fi < len(me.raw.faces); fi++ {
+		face := &me.raw.faces[fi]
+		tag := ""
+		if len(face.base.Tags) > 0 {
+			tag = face.base.Tags[0]
+		}
+		g, ok := groups[tag]
+		if !ok {
+			g, groups[tag] = len(em.groups), len(em.groups)
+			em.groups = append(em.groups, exportGroup{tag: tag})
+		}
+		tri := [3]uint32{me.raw.indices[face.entries[0]], me.raw.indices[face.entries[1]], me.raw.indices[face.entries[2]]}
+		if mirror {
+			tri[1], tri[2] = tri[2], tri[1]
+		}
+		em.groups[g].indices = append(em.groups[g].indices, tri[:]...)
+	}
+	return
+}
+
+func exportWrite(w io.Writer, format ExportFormat, meshes []*exportMesh) (err error) {
+	bw := bufio.NewWriter(w)
+	switch format {
+	case ExportObj:
+		exportObj(bw, meshes)
+	case ExportPly:
+		exportPly(bw, meshes)
+	case ExportGltf:
+		err = exportGltf(bw, meshes)
+	default:
+		err = errf("unknown ExportFormat %v", format)
+	}
+	if err == nil {
+		err = bw.Flush()
+	}
+	return
+}
+
+func exportObj(w *bufio.Writer, meshes []*exportMesh) {
+	offset := 1
+	for _, em := range meshes {
+		w.WriteString(strf("o %s\n", em.name))
+		for _, p := range em.pos {
+			w.WriteString(strf("v %g %g %g\n", p.X, p.Y, p.Z))
+		}
+		for _, t := range em.tex {
+			w.WriteString(strf("vt %g %g\n", t[0], t[1]))
+		}
+		for _, n := range em.normals {
+			w.WriteString(strf("vn %g %g %g\n", n.X, n.Y, n.Z))
+		}
+		for _, g := range em.groups {
+			if len(g.tag) > 0 {
+				w.WriteString(strf("usemtl %s\n", g.tag))
+			}
+			for i := 0; i < len(g.indices); i += 3 {
+				a, b, c := int(g.indices[i])+offset, int(g.indices[i+1])+offset, int(g.indices[i+2])+offset
+				w.WriteString(strf("f %d/%d/%d %d/%d/%d %d/%d/%d\n", a, a, a, b, b, b, c, c, c))
+			}
+		}
+		offset += len(em.pos)
+	}
+}
+
+func exportPly(w *bufio.Writer, meshes []*exportMesh) {
+	numVerts, numFaces := 0, 0
+	for _, em := range meshes {
+		numVerts += len(em.pos)
+		for _, g := range em.groups {
+			numFaces += len(g.indices) / 3
+		}
+	}
+	w.WriteString("ply\nformat ascii 1.0\ncomment exported by go:ngine\n")
+	w.WriteString(strf("element vertex %d\nproperty float x\nproperty float y\nproperty float z\n", numVerts))
+	w.WriteString("property float nx\nproperty float ny\nproperty float nz\nproperty float s\nproperty float t\n")
+	w.WriteString(strf("element face %d\nproperty list uchar uint vertex_indices\nend_header\n", numFaces))
+	for _, em := range meshes {
+		for i, p := range em.pos {
+			n, t := &em.normals[i], &em.tex[i]
+			w.WriteString(strf("%g %g %g %g %g %g %g %g\n", p.X, p.Y, p.Z, n.X, n.Y, n.Z, t[0], t[1]))
+		}
+	}
+	offset := uint32(0)
+	for _, em := range meshes {
+		for _, g := range em.groups {
+			for i := 0; i < len(g.indices); i += 3 {
+				w.WriteString(strf("3 %d %d %d\n", g.indices[i]+offset, g.indices[i+1]+offset, g.indices[i+2]+offset))
+			}
+		}
+		offset += uint32(len(em.pos))
+	}
+}
+
+func exportGltf(w *bufio.Writer, meshes []*exportMesh) (err error) {
+	type jsonObj map[string]interface{}
+	var (
+		buf                                       bytes.Buffer
+		accessors, views, gmeshes, nodes, nodeIDs []interface{}
+		materials                                 []interface{}
+	)
+	matIndices := map[string]int{}
+	//	appends a buffer view (padded to 4 bytes) and an accessor for it, returning the accessor index
+	addAccessor := func(data interface{}, target, componentType, count int, kind string, minMax ...[]float64) int {
+		offset := buf.Len()
+		binary.Write(&buf, binary.LittleEndian, data)
+		views = append(views, jsonObj{"buffer": 0, "byteOffset": offset, "byteLength": buf.Len() - offset, "target": target})
+		for buf.Len()%4 != 0 {
+			buf.WriteByte(0)
+		}
+		acc := jsonObj{"bufferView": len(views) - 1, "componentType": componentType, "count": count, "type": kind}
+		if len(minMax) == 2 {
+			acc["min"], acc["max"] = minMax[0], minMax[1]
+		}
+		accessors = append(accessors, acc)
+		return len(accessors) - 1
+	}
+	for _, em := range meshes {
+		if len(em.groups) == 0 {
+			continue
+		}
+		pos, normals, tex := make([]float32, 0, 3*len(em.pos)), make([]float32, 0, 3*len(em.pos)), make([]float32, 0, 2*len(em.pos))
+		min, max := []float64{math.Inf(1), math.Inf(1), math.Inf(1)}, []float64{math.Inf(-1), math.Inf(-1), math.Inf(-1)}
+		for i, p := range em.pos {
+			pos = append(pos, float32(p.X), float32(p.Y), float32(p.Z))
+			for c, f := range pos[len(pos)-3:] {
+				min[c], max[c] = math.Min(min[c], float64(f)), math.Max(max[c], float64(f))
+			}
+			normals = append(normals, float32(em.normals[i].X), float32(em.normals[i].Y), float32(em.normals[i].Z))
+			//	glTF texcoords start at the top of the image
+			tex = append(tex, float32(em.tex[i][0]), float32(1-em.tex[i][1]))
+		}
+		attribs := jsonObj{
+			"POSITION":   addAccessor(pos, 34962, 5126, len(em.pos), "VEC3", min, max),
+			"NORMAL":     addAccessor(normals, 34962, 5126, len(em.pos), "VEC3"),
+			"TEXCOORD_0": addAccessor(tex, 34962, 5126, len(em.pos), "VEC2"),
+		}
+		var prims []interface{}
+		for _, g := range em.groups {
+			prim := jsonObj{"attributes": attribs, "indices": addAccessor(g.indices, 34963, 5125, len(g.indices), "SCALAR"), "mode": 4}
+			if len(g.tag) > 0 {
+				if _, ok := matIndices[g.tag]; !ok {
+					matIndices[g.tag], materials = len(materials), append(materials, jsonObj{"name": g.tag})
+				}
+				prim["material"] = matIndices[g.tag]
+			}
+			prims = append(prims, prim)
+		}
+		gmeshes = append(gmeshes, jsonObj{"name": em.name, "primitives": prims})
+		nodes = append(nodes, jsonObj{"name": em.name, "mesh": len(gmeshes) - 1})
+		nodeIDs = append(nodeIDs, len(nodes)-1)
+	}
+	doc := jsonObj{
+		"asset":  jsonObj{"version": "2.0", "generator": "go:ngine"},
+		"scene":  0,
+		"scenes": []interface{}{jsonObj{"nodes": nodeIDs}},
+	}
+	if len(nodes) > 0 {
+		doc["nodes"], doc["meshes"], doc["accessors"], doc["bufferViews"] = nodes, gmeshes, accessors, views
+		doc["buffers"] = []interface{}{jsonObj{"byteLength": buf.Len(), "uri": "data:application/octet-stream;base64," + base64.StdEncoding.EncodeToString(buf.Bytes())}}
+	}
+	if len(materials) > 0 {
+		doc["materials"] = materials
+	}
+	var data []byte
+	if data, err = json.MarshalIndent(doc, "", "\t"); err == nil {
+		_, err = w.Write(data)
+	}
+	return
+}
@@ -4,6 +4,11 @@ import (
 	"github.com/metaleap/go-util-str"
 )
 
+//	The number of texture units available to the FxProcs of an FxEffect. The units right above
+//	are reserved for the buffer textures of the "Scene" technique variants, within the 16 units
+//	that GL 3.3 guarantees per shader stage.
+const renderFxTexUnits = 14
+
 //	Declares the visual appearance of a surface.
 //	An FxEffect can be reused for multiple surfaces, it is bound to geometry via an FxMaterial.
 type FxEffect struct {
@@ -19,6 +24,7 @@ type FxEffect struct {
 	KeepProcIDsLast []string
 
 	ext        FxProcs
+	libGen     uint64
 	uberName   string
 	uberPnames map[string]string
 }
@@ -45,6 +51,10 @@ func (me *FxEffect) UpdateRoutine() {
 	ops, ext, counts := me.FxProcs, len(me.ext) > 0, make(map[string]int, len(me.FxProcs)+len(me.ext))
 doOps:
 	for o := 0; o < len(ops); o++ {
+		if ops[o].Enabled && ops[o].IsTex() && counts[ops[o].procID] >= renderFxTexUnits {
+			Diag.LogErr(errf("FxEffect %v: disabling %s FxProc beyond the %v texture units available to effects", me.ID, ops[o].procID, renderFxTexUnits))
+			ops[o].Enabled = false
+		}
 		if ops[o].Enabled {
 			buf.Write("_%s", ops[o].procID)
 			i = counts[ops[o].procID]
@@ -64,6 +74,9 @@ doOps:
 	for rt, _ = range Core.Render.KnownTechniques {
 		me.uberPnames[rt] = strf("uber_%s%s", rt, me.uberName)
 	}
+	for _, rt = range Core.Render.techVariants {
+		me.uberPnames[rt] = strf("uber_%s%s", rt, me.uberName)
+	}
 	thrRend.curEffect = nil
 	if tc > Stats.Programs.maxTexUnits {
 		Stats.Programs.maxTexUnits = tc
@@ -85,6 +98,24 @@ func (me *FxEffect) useProcs(ops FxProcs) {
 
 //#begin-gt -gen-lib.gt T:FxEffect L:Core.Libs.Effects
 
+//	A generational handle to a FxEffect in Core.Libs.Effects.
+//	Unlike a plain ID, it is never silently resolved to another FxEffect that
+//	later re-uses the same ID after the original was removed or moved by Compact().
+type FxEffectHandle struct {
+	id  int
+	gen uint64
+}
+
+//	Returns the ID that me referred to when last resolved. It may since have become invalid.
+func (me FxEffectHandle) ID() int {
+	return me.id
+}
+
+//	Returns true if me is the zero FxEffectHandle (or was detected to be stale), which never resolves to a FxEffect.
+func (me FxEffectHandle) IsNil() bool {
+	return me.gen == 0
+}
+
 //	Only used for Core.Libs.Effects
 type FxEffectLib []FxEffect
 
@@ -105,7 +136,7 @@ func (me *FxEffectLib) AddNew() (id int) {
 		*me = append(*me, FxEffect{})
 	}
 	ref := &(*me)[id]
-	ref.ID = id
+	ref.ID, ref.libGen = id, libGenNext()
 	ref.init()
 	return
 }
@@ -121,6 +152,8 @@ func (me *FxEffectLib) Compact() {
 		if (*me)[i].ID == -1 {
 			compact, before, after = true, (*me)[:i], (*me)[i+1:]
 			*me = append(before, after...)
+			//	re-check i, which now holds the next entry
+			i--
 		}
 	}
 	if compact {
@@ -129,6 +162,7 @@ func (me *FxEffectLib) Compact() {
 			if ref = &(*me)[i]; ref.ID != i {
 				oldID, ref.ID = ref.ID, i
 				changed[oldID] = i
+				libGenMove(ref.libGen, i)
 			}
 		}
 		if len(changed) > 0 {
@@ -153,6 +187,39 @@ func (me FxEffectLib) get(id int) (ref *FxEffect) {
 	return
 }
 
+//	Returns the FxEffect that h refers to, or nil if it has since been removed.
+//	If it was moved by Compact(), h is updated to its new ID. If it was removed, h becomes nil.
+func (me FxEffectLib) Deref(h *FxEffectHandle) (ref *FxEffect) {
+	if ref = me.deref(*h); ref != nil {
+		h.id = ref.ID
+	} else if h.gen != 0 {
+		*h = FxEffectHandle{id: -1}
+	}
+	return
+}
+
+//	Like Deref(), but leaves h as is: safe to call on handles owned by another thread.
+func (me FxEffectLib) deref(h FxEffectHandle) (ref *FxEffect) {
+	if h.gen != 0 {
+		id := h.id
+		if id < 0 || id >= len(me) || me[id].libGen != h.gen {
+			id = libGenMovedTo(h.gen)
+		}
+		if id > -1 && id < len(me) && me[id].ID == id && me[id].libGen == h.gen {
+			ref = &me[id]
+		}
+	}
+	return
+}
+
+//	Returns a FxEffectHandle to the FxEffect with the specified ID, or a nil FxEffectHandle if id is invalid.
+func (me FxEffectLib) Handle(id int) (h FxEffectHandle) {
+	if h.id = -1; me.IsOk(id) {
+		h.id, h.gen = id, me[id].libGen
+	}
+	return
+}
+
 func (me FxEffectLib) IsOk(id int) (ok bool) {
 	if id > -1 && id < len(me) {
 		ok = me[id].ID == id
@@ -172,7 +239,8 @@ func (me FxEffectLib) Remove(fromID, num int) {
 		changed := make(map[int]int, num)
 		for id := fromID; id < fromID+num; id++ {
 			me[id].dispose()
-			changed[id], me[id].ID = -1, -1
+			libGenForget(me[id].libGen)
+			changed[id], me[id].ID, me[id].libGen = -1, -1, 0
 		}
 		me.onFxEffectIDsChanged(changed)
 	}
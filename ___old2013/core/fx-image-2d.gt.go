@@ -56,6 +56,24 @@ func (me *FxImage2D) Unload() {
 
 //#begin-gt -gen-lib.gt T:FxImage2D L:Core.Libs.Images.Tex2D
 
+//	A generational handle to a FxImage2D in Core.Libs.Images.Tex2D.
+//	Unlike a plain ID, it is never silently resolved to another FxImage2D that
+//	later re-uses the same ID after the original was removed or moved by Compact().
+type FxImage2DHandle struct {
+	id  int
+	gen uint64
+}
+
+//	Returns the ID that me referred to when last resolved. It may since have become invalid.
+func (me FxImage2DHandle) ID() int {
+	return me.id
+}
+
+//	Returns true if me is the zero FxImage2DHandle (or was detected to be stale), which never resolves to a FxImage2D.
+func (me FxImage2DHandle) IsNil() bool {
+	return me.gen == 0
+}
+
 //	Only used for Core.Libs.Images.Tex2D
 type FxImage2DLib []FxImage2D
 
@@ -76,7 +94,7 @@ func (me *FxImage2DLib) AddNew() (id int) {
 		*me = append(*me, FxImage2D{})
 	}
 	ref := &(*me)[id]
-	ref.ID = id
+	ref.ID, ref.libGen = id, libGenNext()
 	ref.init()
 	return
 }
@@ -92,6 +110,8 @@ func (me *FxImage2DLib) Compact() {
 		if (*me)[i].ID == -1 {
 			compact, before, after = true, (*me)[:i], (*me)[i+1:]
 			*me = append(before, after...)
+			//	re-check i, which now holds the next entry
+			i--
 		}
 	}
 	if compact {
@@ -100,6 +120,7 @@ func (me *FxImage2DLib) Compact() {
 			if ref = &(*me)[i]; ref.ID != i {
 				oldID, ref.ID = ref.ID, i
 				changed[oldID] = i
+				libGenMove(ref.libGen, i)
 			}
 		}
 		if len(changed) > 0 {
@@ -124,6 +145,39 @@ func (me FxImage2DLib) get(id int) (ref *FxImage2D) {
 	return
 }
 
+//	Returns the FxImage2D that h refers to, or nil if it has since been removed.
+//	If it was moved by Compact(), h is updated to its new ID. If it was removed, h becomes nil.
+func (me FxImage2DLib) Deref(h *FxImage2DHandle) (ref *FxImage2D) {
+	if ref = me.deref(*h); ref != nil {
+		h.id = ref.ID
+	} else if h.gen != 0 {
+		*h = FxImage2DHandle{id: -1}
+	}
+	return
+}
+
+//	Like Deref(), but leaves h as is: safe to call on handles owned by another thread.
+func (me FxImage2DLib) deref(h FxImage2DHandle) (ref *FxImage2D) {
+	if h.gen != 0 {
+		id := h.id
+		if id < 0 || id >= len(me) || me[id].libGen != h.gen {
+			id = libGenMovedTo(h.gen)
+		}
+		if id > -1 && id < len(me) && me[id].ID == id && me[id].libGen == h.gen {
+			ref = &me[id]
+		}
+	}
+	return
+}
+
+//	Returns a FxImage2DHandle to the FxImage2D with the specified ID, or a nil FxImage2DHandle if id is invalid.
+func (me FxImage2DLib) Handle(id int) (h FxImage2DHandle) {
+	if h.id = -1; me.IsOk(id) {
+		h.id, h.gen = id, me[id].libGen
+	}
+	return
+}
+
 func (me FxImage2DLib) IsOk(id int) (ok bool) {
 	if id > -1 && id < len(me) {
 		ok = me[id].ID == id
@@ -143,7 +197,8 @@ func (me FxImage2DLib) Remove(fromID, num int) {
 		changed := make(map[int]int, num)
 		for id := fromID; id < fromID+num; id++ {
 			me[id].dispose()
-			changed[id], me[id].ID = -1, -1
+			libGenForget(me[id].libGen)
+			changed[id], me[id].ID, me[id].libGen = -1, -1, 0
 		}
 		me.onFxImage2DIDsChanged(changed)
 	}
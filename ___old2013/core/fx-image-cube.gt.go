@@ -67,6 +67,24 @@ func (me *FxImageCube) Unload() {
 
 //#begin-gt -gen-lib.gt T:FxImageCube L:Core.Libs.Images.TexCube
 
+//	A generational handle to a FxImageCube in Core.Libs.Images.TexCube.
+//	Unlike a plain ID, it is never silently resolved to another FxImageCube that
+//	later re-uses the same ID after the original was removed or moved by Compact().
+type FxImageCubeHandle struct {
+	id  int
+	gen uint64
+}
+
+//	Returns the ID that me referred to when last resolved. It may since have become invalid.
+func (me FxImageCubeHandle) ID() int {
+	return me.id
+}
+
+//	Returns true if me is the zero FxImageCubeHandle (or was detected to be stale), which never resolves to a FxImageCube.
+func (me FxImageCubeHandle) IsNil() bool {
+	return me.gen == 0
+}
+
 //	Only used for Core.Libs.Images.TexCube
 type FxImageCubeLib []FxImageCube
 
@@ -87,7 +105,7 @@ func (me *FxImageCubeLib) AddNew() (id int) {
 		*me = append(*me, FxImageCube{})
 	}
 	ref := &(*me)[id]
-	ref.ID = id
+	ref.ID, ref.libGen = id, libGenNext()
 	ref.init()
 	return
 }
@@ -103,6 +121,8 @@ func (me *FxImageCubeLib) Compact() {
 		if (*me)[i].ID == -1 {
 			compact, before, after = true, (*me)[:i], (*me)[i+1:]
 			*me = append(before, after...)
+			//	re-check i, which now holds the next entry
+			i--
 		}
 	}
 	if compact {
@@ -111,6 +131,7 @@ func (me *FxImageCubeLib) Compact() {
 			if ref = &(*me)[i]; ref.ID != i {
 				oldID, ref.ID = ref.ID, i
 				changed[oldID] = i
+				libGenMove(ref.libGen, i)
 			}
 		}
 		if len(changed) > 0 {
@@ -135,6 +156,39 @@ func (me FxImageCubeLib) get(id int) (ref *FxImageCube) {
 	return
 }
 
+//	Returns the FxImageCube that h refers to, or nil if it has since been removed.
+//	If it was moved by Compact(), h is updated to its new ID. If it was removed, h becomes nil.
+func (me FxImageCubeLib) Deref(h *FxImageCubeHandle) (ref *FxImageCube) {
+	if ref = me.deref(*h); ref != nil {
+		h.id = ref.ID
+	} else if h.gen != 0 {
+		*h = FxImageCubeHandle{id: -1}
+	}
+	return
+}
+
+//	Like Deref(), but leaves h as is: safe to call on handles owned by another thread.
+func (me FxImageCubeLib) deref(h FxImageCubeHandle) (ref *FxImageCube) {
+	if h.gen != 0 {
+		id := h.id
+		if id < 0 || id >= len(me) || me[id].libGen != h.gen {
+			id = libGenMovedTo(h.gen)
+		}
+		if id > -1 && id < len(me) && me[id].ID == id && me[id].libGen == h.gen {
+			ref = &me[id]
+		}
+	}
+	return
+}
+
+//	Returns a FxImageCubeHandle to the FxImageCube with the specified ID, or a nil FxImageCubeHandle if id is invalid.
+func (me FxImageCubeLib) Handle(id int) (h FxImageCubeHandle) {
+	if h.id = -1; me.IsOk(id) {
+		h.id, h.gen = id, me[id].libGen
+	}
+	return
+}
+
 func (me FxImageCubeLib) IsOk(id int) (ok bool) {
 	if id > -1 && id < len(me) {
 		ok = me[id].ID == id
@@ -154,7 +208,8 @@ func (me FxImageCubeLib) Remove(fromID, num int) {
 		changed := make(map[int]int, num)
 		for id := fromID; id < fromID+num; id++ {
 			me[id].dispose()
-			changed[id], me[id].ID = -1, -1
+			libGenForget(me[id].libGen)
+			changed[id], me[id].ID, me[id].libGen = -1, -1, 0
 		}
 		me.onFxImageCubeIDsChanged(changed)
 	}
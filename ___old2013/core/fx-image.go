@@ -12,6 +12,22 @@ type fxImage interface {
 	GpuSync() error
 }
 
+//	Sent by fxImageLoad() once it is done.
+type fxImageLoaded struct {
+	img fxImage
+	err error
+}
+
+//	Loads img unless it is Loaded() already, then sends it to ch along with any error.
+//	Meant to run in its own goroutine: the receiver, rather than fxImageLoad(), logs errors and uploads img.
+func fxImageLoad(ch chan<- fxImageLoaded, img fxImage) {
+	var err error
+	if !img.Loaded() {
+		err = img.Load()
+	}
+	ch <- fxImageLoaded{img, err}
+}
+
 type FxImagePreprocess struct {
 	FlipY    bool
 	ToLinear bool
@@ -36,6 +52,7 @@ type FxImageBase struct {
 	Storage    FxImageStorage
 
 	glSynced bool
+	libGen   uint64
 }
 
 func (me *FxImageBase) init() {
@@ -17,6 +17,8 @@ type FxMaterial struct {
 		//	Associates specific face IDs with effect IDs.
 		ByID map[string]int
 	}
+
+	libGen uint64
 }
 
 func (me *FxMaterial) init() {
@@ -67,6 +69,24 @@ func (me *FxMaterial) HasFaceEffects() bool {
 
 //#begin-gt -gen-lib.gt T:FxMaterial L:Core.Libs.Materials
 
+//	A generational handle to a FxMaterial in Core.Libs.Materials.
+//	Unlike a plain ID, it is never silently resolved to another FxMaterial that
+//	later re-uses the same ID after the original was removed or moved by Compact().
+type FxMaterialHandle struct {
+	id  int
+	gen uint64
+}
+
+//	Returns the ID that me referred to when last resolved. It may since have become invalid.
+func (me FxMaterialHandle) ID() int {
+	return me.id
+}
+
+//	Returns true if me is the zero FxMaterialHandle (or was detected to be stale), which never resolves to a FxMaterial.
+func (me FxMaterialHandle) IsNil() bool {
+	return me.gen == 0
+}
+
 //	Only used for Core.Libs.Materials
 type FxMaterialLib []FxMaterial
 
@@ -87,7 +107,7 @@ func (me *FxMaterialLib) AddNew() (id int) {
 		*me = append(*me, FxMaterial{})
 	}
 	ref := &(*me)[id]
-	ref.ID = id
+	ref.ID, ref.libGen = id, libGenNext()
 	ref.init()
 	return
 }
@@ -103,6 +123,8 @@ func (me *FxMaterialLib) Compact() {
 		if (*me)[i].ID == -1 {
 			compact, before, after = true, (*me)[:i], (*me)[i+1:]
 			*me = append(before, after...)
+			//	re-check i, which now holds the next entry
+			i--
 		}
 	}
 	if compact {
@@ -111,6 +133,7 @@ func (me *FxMaterialLib) Compact() {
 			if ref = &(*me)[i]; ref.ID != i {
 				oldID, ref.ID = ref.ID, i
 				changed[oldID] = i
+				libGenMove(ref.libGen, i)
 			}
 		}
 		if len(changed) > 0 {
@@ -135,6 +158,39 @@ func (me FxMaterialLib) get(id int) (ref *FxMaterial) {
 	return
 }
 
+//	Returns the FxMaterial that h refers to, or nil if it has since been removed.
+//	If it was moved by Compact(), h is updated to its new ID. If it was removed, h becomes nil.
+func (me FxMaterialLib) Deref(h *FxMaterialHandle) (ref *FxMaterial) {
+	if ref = me.deref(*h); ref != nil {
+		h.id = ref.ID
+	} else if h.gen != 0 {
+		*h = FxMaterialHandle{id: -1}
+	}
+	return
+}
+
+//	Like Deref(), but leaves h as is: safe to call on handles owned by another thread.
+func (me FxMaterialLib) deref(h FxMaterialHandle) (ref *FxMaterial) {
+	if h.gen != 0 {
+		id := h.id
+		if id < 0 || id >= len(me) || me[id].libGen != h.gen {
+			id = libGenMovedTo(h.gen)
+		}
+		if id > -1 && id < len(me) && me[id].ID == id && me[id].libGen == h.gen {
+			ref = &me[id]
+		}
+	}
+	return
+}
+
+//	Returns a FxMaterialHandle to the FxMaterial with the specified ID, or a nil FxMaterialHandle if id is invalid.
+func (me FxMaterialLib) Handle(id int) (h FxMaterialHandle) {
+	if h.id = -1; me.IsOk(id) {
+		h.id, h.gen = id, me[id].libGen
+	}
+	return
+}
+
 func (me FxMaterialLib) IsOk(id int) (ok bool) {
 	if id > -1 && id < len(me) {
 		ok = me[id].ID == id
@@ -154,7 +210,8 @@ func (me FxMaterialLib) Remove(fromID, num int) {
 		changed := make(map[int]int, num)
 		for id := fromID; id < fromID+num; id++ {
 			me[id].dispose()
-			changed[id], me[id].ID = -1, -1
+			libGenForget(me[id].libGen)
+			changed[id], me[id].ID, me[id].libGen = -1, -1, 0
 		}
 		me.onFxMaterialIDsChanged(changed)
 	}
@@ -7,7 +7,7 @@ import (
 )
 
 func fxKnownProcIDs() []string {
-	return []string{"Color", "Tex2D", "TexCube", "Grayscale", "Orangify", "Coords", "Gamma"}
+	return []string{"Color", "Tex2D", "TexCube", "Grayscale", "Orangify", "Coords", "Gamma", "Normals", "Tex2DUv2", "VertColor"}
 }
 
 type FxProc struct {
@@ -71,7 +71,7 @@ func (me *FxProc) use() {
 	}
 	if me.IsTex() {
 		me.Tex.Sampler.Bind(me.Tex.glUnitU)
-		if me.IsTex2D() {
+		if me.IsTex2D() || me.IsTex2DUv2() {
 			thrRend.curProg.Uniform1i(me.unifName("sampler2D", "Img"), me.Tex.glUnitI)
 			if Core.Libs.Images.Tex2D.IsOk(me.Tex.ImageID) {
 				ugl.Cache.BindTextureTo(me.Tex.glUnitU, Core.Libs.Images.Tex2D[me.Tex.ImageID].glTex.GlHandle, Core.Libs.Images.Tex2D[me.Tex.ImageID].glTex.GlTarget)
@@ -104,7 +104,7 @@ func (me *FxProc) Tex_SetImageID(imageID int) *FxProc {
 }
 
 func (me *FxProc) IsTex() bool {
-	return me.IsTex2D() || me.IsTexCube()
+	return me.IsTex2D() || me.IsTex2DUv2() || me.IsTexCube()
 }
 
 //	Used for FxEffect.Procs and Camera.Rendering.FxProcs.
@@ -221,7 +221,7 @@ func (me *FxProcs) Toggle(procID string, n int) {
 
 
 
-//#begin-gt -gen-fx-procs.gt GT_MULT_SEP:, N:Tex2D,TexCube,Orangify,Grayscale,Coords,Color,Gamma
+//#begin-gt -gen-fx-procs.gt GT_MULT_SEP:, N:Tex2D,TexCube,Orangify,Grayscale,Coords,Color,Gamma,Normals,Tex2DUv2,VertColor
 
 func (me *FxProc) IsTex2D() bool {
 	return me.procID == "Tex2D"
@@ -412,4 +412,85 @@ func (me *FxProcs) ToggleGamma(n int) {
 	me.Toggle("Gamma", n)
 }
 
+func (me *FxProc) IsNormals() bool {
+	return me.procID == "Normals"
+}
+
+//	Convenience short-hand for me.Disable("Normals", n).
+//	For this change to be applied, call FxEffect.UpdateRoutine() subsequently.
+func (me FxProcs) DisableNormals(n int) {
+	me.Disable("Normals", n)
+}
+
+//	Convenience short-hand for me.Enable("Normals", n).
+//	For this change to be applied, call FxEffect.UpdateRoutine() subsequently.
+func (me *FxProcs) EnableNormals(n int) *FxProc {
+	return me.Enable("Normals", n)
+}
+
+//	Convenience short-hand for me.Get("Normals", n).
+func (me FxProcs) GetNormals(n int) *FxProc {
+	return me.Get("Normals", n)
+}
+
+//	Convenience short-hand for me.Toggle("Normals", n).
+//	For this change to be applied, call FxEffect.UpdateRoutine() subsequently.
+func (me *FxProcs) ToggleNormals(n int) {
+	me.Toggle("Normals", n)
+}
+
+func (me *FxProc) IsTex2DUv2() bool {
+	return me.procID == "Tex2DUv2"
+}
+
+//	Convenience short-hand for me.Disable("Tex2DUv2", n).
+//	For this change to be applied, call FxEffect.UpdateRoutine() subsequently.
+func (me FxProcs) DisableTex2DUv2(n int) {
+	me.Disable("Tex2DUv2", n)
+}
+
+//	Convenience short-hand for me.Enable("Tex2DUv2", n).
+//	For this change to be applied, call FxEffect.UpdateRoutine() subsequently.
+func (me *FxProcs) EnableTex2DUv2(n int) *FxProc {
+	return me.Enable("Tex2DUv2", n)
+}
+
+//	Convenience short-hand for me.Get("Tex2DUv2", n).
+func (me FxProcs) GetTex2DUv2(n int) *FxProc {
+	return me.Get("Tex2DUv2", n)
+}
+
+//	Convenience short-hand for me.Toggle("Tex2DUv2", n).
+//	For this change to be applied, call FxEffect.UpdateRoutine() subsequently.
+func (me *FxProcs) ToggleTex2DUv2(n int) {
+	me.Toggle("Tex2DUv2", n)
+}
+
+func (me *FxProc) IsVertColor() bool {
+	return me.procID == "VertColor"
+}
+
+//	Convenience short-hand for me.Disable("VertColor", n).
+//	For this change to be applied, call FxEffect.UpdateRoutine() subsequently.
+func (me FxProcs) DisableVertColor(n int) {
+	me.Disable("VertColor", n)
+}
+
+//	Convenience short-hand for me.Enable("VertColor", n).
+//	For this change to be applied, call FxEffect.UpdateRoutine() subsequently.
+func (me *FxProcs) EnableVertColor(n int) *FxProc {
+	return me.Enable("VertColor", n)
+}
+
+//	Convenience short-hand for me.Get("VertColor", n).
+func (me FxProcs) GetVertColor(n int) *FxProc {
+	return me.Get("VertColor", n)
+}
+
+//	Convenience short-hand for me.Toggle("VertColor", n).
+//	For this change to be applied, call FxEffect.UpdateRoutine() subsequently.
+func (me *FxProcs) ToggleVertColor(n int) {
+	me.Toggle("VertColor", n)
+}
+
 //#end-gt
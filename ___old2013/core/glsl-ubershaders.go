@@ -65,6 +65,14 @@ func (me *uberShaders) inoutTypeSpec(inout string) string {
 	return inout[:strings.Index(inout, "_")]
 }
 
+//	Returns the array length suffix for the declaration of inout, or "" if it isn't an array.
+func (me *uberShaders) inoutArraySpec(inout string) (spec string) {
+	if inout == "uni_mat4_SkinJoints" {
+		spec = strf("[%d]", Options.Rendering.SkinMaxJoints)
+	}
+	return
+}
+
 func (me *uberShaders) loadFromRawSources() {
 	var (
 		lines, funcLines []string
@@ -310,7 +318,7 @@ func (me *uberShaders) setShaderSourceVert(job *uberShaderJob, vertTech string,
 			srcHead.Writeln("in %s %s;", me.inoutTypeSpec(inout), inout)
 		case "uni_":
 			uslice.StrAppendUnique(&job.progUnis, inout)
-			srcHead.Writeln("uniform %s %s;", me.inoutTypeSpec(inout), inout)
+			srcHead.Writeln("uniform %s %s%s;", me.inoutTypeSpec(inout), inout, me.inoutArraySpec(inout))
 		}
 	}
 	srcBody.Writeln("void main () {")
@@ -0,0 +1,946 @@
+package core
+
+import (
+	"encoding/xml"
+	"io"
+	"math"
+	"sort"
+	"strings"
+
+	"github.com/metaleap/go-util-num"
+	u3d "github.com/metaleap/go-util-3d"
+	gl "github.com/metaleap/go-opengl/core"
+)
+
+type colladaDoc struct {
+	Asset struct {
+		UpAxis string `xml:"up_axis"`
+	} `xml:"asset"`
+	Images       []colladaImage       `xml:"library_images>image"`
+	Effects      []colladaEffect      `xml:"library_effects>effect"`
+	Materials    []colladaMaterial    `xml:"library_materials>material"`
+	Geometries   []colladaGeometry    `xml:"library_geometries>geometry"`
+	Controllers  []colladaController  `xml:"library_controllers>controller"`
+	Cameras      []colladaCamera      `xml:"library_cameras>camera"`
+	Nodes        []colladaNode        `xml:"library_nodes>node"`
+	VisualScenes []colladaVisualScene `xml:"library_visual_scenes>visual_scene"`
+	Scene        struct {
+		InstanceVisualScene colladaInstance `xml:"instance_visual_scene"`
+	} `xml:"scene"`
+}
+
+type colladaInstance struct {
+	Url string `xml:"url,attr"`
+}
+
+type colladaImage struct {
+	Id       string `xml:"id,attr"`
+	InitFrom string `xml:"init_from"`
+}
+
+type colladaEffect struct {
+	Id      string `xml:"id,attr"`
+	Profile struct {
+		NewParams []colladaNewParam `xml:"newparam"`
+		Technique struct {
+			NewParams []colladaNewParam `xml:"newparam"`
+			Blinn     *colladaShading   `xml:"blinn"`
+			Constant  *colladaShading   `xml:"constant"`
+			Lambert   *colladaShading   `xml:"lambert"`
+			Phong     *colladaShading   `xml:"phong"`
+		} `xml:"technique"`
+	} `xml:"profile_COMMON"`
+}
+
+type colladaNewParam struct {
+	Sid     string `xml:"sid,attr"`
+	Surface *struct {
+		InitFrom string `xml:"init_from"`
+	} `xml:"surface"`
+	Sampler2D *struct {
+		Source string `xml:"source"`
+	} `xml:"sampler2D"`
+}
+
+type colladaShading struct {
+	Emission colladaColorOrTexture `xml:"emission"`
+	Diffuse  colladaColorOrTexture `xml:"diffuse"`
+}
+
+type colladaColorOrTexture struct {
+	Color   string `xml:"color"`
+	Texture *struct {
+		Texture string `xml:"texture,attr"`
+	} `xml:"texture"`
+}
+
+type colladaMaterial struct {
+	Id             string          `xml:"id,attr"`
+	InstanceEffect colladaInstance `xml:"instance_effect"`
+}
+
+type colladaGeometry struct {
+	Id   string `xml:"id,attr"`
+	Name string `xml:"name,attr"`
+	Mesh *struct {
+		Sources  []colladaSource `xml:"source"`
+		Vertices struct {
+			Id     string         `xml:"id,attr"`
+			Inputs []colladaInput `xml:"input"`
+		} `xml:"vertices"`
+		Triangles []colladaPrims `xml:"triangles"`
+		Polylists []colladaPrims `xml:"polylist"`
+		Polygons  []colladaPrims `xml:"polygons"`
+	} `xml:"mesh"`
+}
+
+type colladaSource struct {
+	Id         string `xml:"id,attr"`
+	FloatArray string `xml:"float_array"`
+	NameArray  string `xml:"Name_array"`
+	IdRefArray string `xml:"IDREF_array"`
+	Accessor   struct {
+		Count  int `xml:"count,attr"`
+		Offset int `xml:"offset,attr"`
+		Stride int `xml:"stride,attr"`
+	} `xml:"technique_common>accessor"`
+}
+
+type colladaInput struct {
+	Semantic string `xml:"semantic,attr"`
+	Source   string `xml:"source,attr"`
+	Offset   int    `xml:"offset,attr"`
+	Set      int    `xml:"set,attr"`
+}
+
+type colladaPrims struct {
+	Material string         `xml:"material,attr"`
+	Count    int            `xml:"count,attr"`
+	Inputs   []colladaInput `xml:"input"`
+	VCount   string         `xml:"vcount"`
+	P        []string       `xml:"p"`
+}
+
+type colladaController struct {
+	Id   string `xml:"id,attr"`
+	Name string `xml:"name,attr"`
+	Skin *struct {
+		Source          string          `xml:"source,attr"`
+		BindShapeMatrix string          `xml:"bind_shape_matrix"`
+		Sources         []colladaSource `xml:"source"`
+		Joints          []colladaInput  `xml:"joints>input"`
+		VertexWeights   struct {
+			Count  int            `xml:"count,attr"`
+			Inputs []colladaInput `xml:"input"`
+			VCount string         `xml:"vcount"`
+			V      string         `xml:"v"`
+		} `xml:"vertex_weights"`
+	} `xml:"skin"`
+}
+
+type colladaCamera struct {
+	Id          string `xml:"id,attr"`
+	Name        string `xml:"name,attr"`
+	Perspective *struct {
+		XFov        float64 `xml:"xfov"`
+		YFov        float64 `xml:"yfov"`
+		AspectRatio float64 `xml:"aspect_ratio"`
+		ZNear       float64 `xml:"znear"`
+		ZFar        float64 `xml:"zfar"`
+	} `xml:"optics>technique_common>perspective"`
+}
+
+type colladaVisualScene struct {
+	Id    string        `xml:"id,attr"`
+	Name  string        `xml:"name,attr"`
+	Nodes []colladaNode `xml:"node"`
+}
+
+type colladaNode struct {
+	Id                  string                    `xml:"id,attr"`
+	Sid                 string                    `xml:"sid,attr"`
+	Name                string                    `xml:"name,attr"`
+	Nodes               []colladaNode             `xml:"node"`
+	InstanceGeometries  []colladaInstanceGeometry `xml:"instance_geometry"`
+	InstanceControllers []colladaInstanceGeometry `xml:"instance_controller"`
+	InstanceCameras     []colladaInstance         `xml:"instance_camera"`
+	InstanceNodes       []colladaInstance         `xml:"instance_node"`
+
+	//	All other child elements in document order, including the transformation elements.
+	Others []struct {
+		XMLName xml.Name
+		Value   string `xml:",chardata"`
+	} `xml:",any"`
+}
+
+// An <instance_geometry> or <instance_controller>.
+type colladaInstanceGeometry struct {
+	Url string `xml:"url,attr"`
+
+	//	Only used by <instance_controller>: the nodes whose sub-trees contain the joints of the skin.
+	Skeletons []string `xml:"skeleton"`
+
+	InstanceMaterials []struct {
+		Symbol string `xml:"symbol,attr"`
+		Target string `xml:"target,attr"`
+	} `xml:"bind_material>technique_common>instance_material"`
+}
+
+// The state of a single ImportCollada() call.
+type colladaImporter struct {
+	doc      colladaDoc
+	filePath string
+	opt      ImportOptions
+	scene    *Scene
+	result   *ImportResult
+
+	meshDescs     map[string]*u3d.MeshDescriptor
+	meshCached    map[string]*meshRaw
+	imageIDs      map[string]int
+	effectIDs     map[string]int
+	materialFxIDs map[string]int
+	meshIDs       map[string]int
+	bindMatIDs    map[string]int
+	fallbackFxID  int
+	instancing    map[string]bool
+
+	//	The IDs of the created nodes by their document id, and the created nodes instancing a controller.
+	nodeIDs     map[string]int
+	skinnedInst []colladaSkinnedInst
+}
+
+type colladaSkinnedInst struct {
+	nodeID int
+	inst   *colladaInstanceGeometry
+}
+
+// Imports the COLLADA 1.4.1 document at filePath into me, below parentNodeID.
+//
+// All <library_geometries> meshes become Meshes (with their faces tagged with their material symbols), as does the
+// geometry of each <library_controllers> skin, loaded via Mesh.LoadSkinned() with its joints named like their nodes,
+// all <library_effects> become FxEffects (using their diffuse texture or color) and all <library_images>
+// become FxImage2Ds. The node hierarchy of the document's <visual_scene> is instantiated below a new node
+// that also converts the document's up-axis to Y-up, binding a new FxMaterial to each node with geometry.
+// Nodes with an <instance_controller> get its <skeleton> node (or else, the new node) as their Render.Skeleton.
+func (me *Scene) ImportCollada(filePath string, parentNodeID int, opt *ImportOptions) (result *ImportResult, err error) {
+	var rc io.ReadCloser
+	if !me.allNodes.IsOk(parentNodeID) {
+		err = errf("Cannot import '%v': invalid parent node ID %v", filePath, parentNodeID)
+		return
+	}
+	if rc, err = Core.fileIO.openLocalFile(filePath); err != nil {
+		return
+	}
+	defer rc.Close()
+	imp := &colladaImporter{filePath: filePath, scene: me, result: &ImportResult{RootNodeID: -1}, fallbackFxID: -1}
+	if opt != nil {
+		imp.opt = *opt
+	}
+	if err = xml.NewDecoder(rc).Decode(&imp.doc); err == nil {
+		if err = imp.loadMeshDescs(); err == nil {
+			imp.createLibs()
+			err = imp.createScene(parentNodeID)
+		}
+	}
+	if err != nil {
+		err = errf("Cannot import '%v': %v", filePath, err)
+	} else {
+		result = imp.result
+	}
+	return
+}
+
+// Converts all geometries of the document to u3d.MeshDescriptors, except those found in the disk cache.
+// Geometries skinned by a controller are always converted, as skins are not cached.
+func (me *colladaImporter) loadMeshDescs() (err error) {
+	me.meshDescs, me.meshCached = map[string]*u3d.MeshDescriptor{}, map[string]*meshRaw{}
+	skinned := map[string]bool{}
+	for _, ctrl := range me.doc.Controllers {
+		if ctrl.Skin != nil {
+			skinned[strings.TrimPrefix(ctrl.Skin.Source, "#")] = true
+		}
+	}
+	for gi := 0; gi < len(me.doc.Geometries) && err == nil; gi++ {
+		if geo := &me.doc.Geometries[gi]; geo.Mesh != nil {
+			var md *u3d.MeshDescriptor
+			if raw, _ := newMeshCacheKey(me.filePath, me.opt.meshSrcItem(geo.Id)).load(); raw != nil && !skinned[geo.Id] {
+				me.meshCached[geo.Id] = raw
+			} else if md, err = me.meshDesc(geo); err == nil && len(md.Faces) > 0 {
+				me.meshDescs[geo.Id] = md
+			}
+		}
+	}
+	return
+}
+
+func (me *colladaImporter) meshDesc(geo *colladaGeometry) (md *u3d.MeshDescriptor, err error) {
+	type source struct {
+		vals          []float64
+		stride, count int
+	}
+	var (
+		src                *source
+		vals               []float64
+		vcounts, p         []int
+		noTex              = -1
+		poly               []u3d.MeshDescF3V
+		posIn, normIn, tex *colladaInput
+	)
+	md = &u3d.MeshDescriptor{}
+	sources, bases := map[string]*source{}, map[string]int{}
+	for _, s := range geo.Mesh.Sources {
+		if vals, err = importParseFloats(s.FloatArray); err != nil {
+			return
+		}
+		if s.Accessor.Offset < 0 || s.Accessor.Offset > len(vals) {
+			err = errf("source '%v' has an invalid accessor offset", s.Id)
+			return
+		}
+		src = &source{vals: vals[s.Accessor.Offset:], stride: s.Accessor.Stride, count: s.Accessor.Count}
+		if src.stride < 1 {
+			src.stride = 1
+		}
+		if src.count == 0 || src.count*src.stride > len(src.vals) {
+			src.count = len(src.vals) / src.stride
+		}
+		sources["#"+s.Id] = src
+	}
+	//	Returns the index in md of the first element of the source, appending all its elements to md if necessary.
+	base := func(semantic, srcUrl string) (int, error) {
+		if b, ok := bases[semantic+srcUrl]; ok {
+			return b, nil
+		}
+		s := sources[srcUrl]
+		if s == nil {
+			return 0, errf("geometry '%v' refers to unknown source '%v'", geo.Id, srcUrl)
+		} else if (semantic == "TEXCOORD" && s.stride < 2) || (semantic != "TEXCOORD" && s.stride < 3) {
+			return 0, errf("source '%v' has too few components for %v", srcUrl, semantic)
+		}
+		var b int
+		for i := 0; i < s.count; i++ {
+			v := s.vals[i*s.stride:]
+			switch semantic {
+			case "POSITION":
+				b = len(md.Positions) - i
+				md.Positions = append(md.Positions, u3d.MeshDescVA3{float32(v[0]), float32(v[1]), float32(v[2])})
+			case "NORMAL":
+				b = len(md.Normals) - i
+				md.Normals = append(md.Normals, u3d.MeshDescVA3{float32(v[0]), float32(v[1]), float32(v[2])})
+			case "TEXCOORD":
+				b = len(md.TexCoords) - i
+				md.TexCoords = append(md.TexCoords, u3d.MeshDescVA2{float32(v[0]), float32(v[1])})
+			}
+		}
+		bases[semantic+srcUrl] = b
+		return b, nil
+	}
+	vertIns := geo.Mesh.Vertices.Inputs
+	allPrims := [][]colladaPrims{geo.Mesh.Triangles, geo.Mesh.Polylists, geo.Mesh.Polygons}
+	for kind, prims := range allPrims {
+		for pi := 0; pi < len(prims); pi++ {
+			prim, stride := &prims[pi], 0
+			posIn, normIn, tex = nil, nil, nil
+			for i := 0; i < len(prim.Inputs); i++ {
+				in := &prim.Inputs[i]
+				if in.Offset >= stride {
+					stride = in.Offset + 1
+				}
+				switch in.Semantic {
+				case "VERTEX":
+					for vi := 0; vi < len(vertIns); vi++ {
+						vin := vertIns[vi]
+						vin.Offset = in.Offset
+						switch vin.Semantic {
+						case "POSITION":
+							posIn = &vin
+						case "NORMAL":
+							normIn = &vin
+						case "TEXCOORD":
+							tex = &vin
+						}
+					}
+				case "NORMAL":
+					normIn = in
+				case "TEXCOORD":
+					if tex == nil || in.Set < tex.Set {
+						tex = in
+					}
+				}
+			}
+			if posIn == nil {
+				err = errf("geometry '%v' has primitives without positions", geo.Id)
+				return
+			}
+			var posBase, normBase, texBase int
+			if posBase, err = base("POSITION", posIn.Source); err == nil && normIn != nil {
+				normBase, err = base("NORMAL", normIn.Source)
+			}
+			if err == nil && tex != nil {
+				texBase, err = base("TEXCOORD", tex.Source)
+			} else if err == nil && noTex < 0 {
+				noTex, md.TexCoords = len(md.TexCoords), append(md.TexCoords, u3d.MeshDescVA2{0, 0})
+			}
+			if err != nil {
+				return
+			}
+			//	gather the polygons of prim as vertex counts and one index list
+			p = p[:0]
+			for _, ps := range prim.P {
+				if vals, err := importParseInts(ps); err != nil {
+					return nil, err
+				} else if p = append(p, vals...); kind == 2 {
+					vcounts = append(vcounts, len(vals)/stride)
+				}
+			}
+			switch kind {
+			case 0:
+				vcounts = vcounts[:0]
+				for i := 0; i < len(p)/(3*stride); i++ {
+					vcounts = append(vcounts, 3)
+				}
+			case 1:
+				if vcounts, err = importParseInts(prim.VCount); err != nil {
+					return
+				}
+			}
+			offset := 0
+			for _, vc := range vcounts {
+				if (offset+vc)*stride > len(p) {
+					err = errf("geometry '%v' has fewer indices than its primitives require", geo.Id)
+					return
+				}
+				poly = poly[:0]
+				for v := 0; v < vc; v++ {
+					idx := p[(offset+v)*stride:]
+					fv := u3d.MeshDescF3V{PosIndex: uint32(posBase + idx[posIn.Offset]), NormalIndex: math.MaxUint32, TexCoordIndex: uint32(noTex)}
+					if normIn != nil {
+						fv.NormalIndex = uint32(normBase + idx[normIn.Offset])
+					}
+					if tex != nil {
+						fv.TexCoordIndex = uint32(texBase + idx[tex.Offset])
+					}
+					if int(fv.PosIndex) >= len(md.Positions) || (normIn != nil && int(fv.NormalIndex) >= len(md.Normals)) || int(fv.TexCoordIndex) >= len(md.TexCoords) {
+						err = errf("geometry '%v' has an index out of range", geo.Id)
+						return
+					}
+					poly = append(poly, fv)
+				}
+				offset += vc
+				//	triangulate as a fan
+				for v := 2; v < len(poly); v++ {
+					face := u3d.MeshDescF3{V: [3]u3d.MeshDescF3V{poly[0], poly[v-1], poly[v]}}
+					if len(prim.Material) > 0 {
+						face.Tags = []string{prim.Material}
+					}
+					if normIn == nil {
+						n := importFlatNormal(md, face.V[0].PosIndex, face.V[1].PosIndex, face.V[2].PosIndex)
+						face.V[0].NormalIndex, face.V[1].NormalIndex, face.V[2].NormalIndex = n, n, n
+					}
+					md.Faces = append(md.Faces, face)
+				}
+			}
+			vcounts = vcounts[:0]
+		}
+	}
+	return
+}
+
+// Creates the FxImage2Ds, FxEffects and Meshes of the document.
+func (me *colladaImporter) createLibs() {
+	me.imageIDs, me.effectIDs, me.materialFxIDs, me.meshIDs, me.bindMatIDs = map[string]int{}, map[string]int{}, map[string]int{}, map[string]int{}, map[string]int{}
+	for _, img := range me.doc.Images {
+		id := Core.Libs.Images.Tex2D.AddNew()
+		Core.Libs.Images.Tex2D[id].InitFrom.RefUrl = importImageUrl(me.filePath, img.InitFrom)
+		me.imageIDs[img.Id], me.result.ImageIDs = id, append(me.result.ImageIDs, id)
+	}
+	for ei := 0; ei < len(me.doc.Effects); ei++ {
+		me.effectIDs[me.doc.Effects[ei].Id] = me.createEffect(&me.doc.Effects[ei])
+	}
+	for _, mat := range me.doc.Materials {
+		if fxID, ok := me.effectIDs[strings.TrimPrefix(mat.InstanceEffect.Url, "#")]; ok {
+			me.materialFxIDs[mat.Id] = fxID
+		}
+	}
+	for gi := 0; gi < len(me.doc.Geometries); gi++ {
+		geo := &me.doc.Geometries[gi]
+		md, raw := me.meshDescs[geo.Id], me.meshCached[geo.Id]
+		if md != nil || raw != nil {
+			name := geo.Name
+			if len(name) == 0 {
+				name = geo.Id
+			}
+			meshID := Core.Libs.Meshes.AddNew()
+			mesh := &Core.Libs.Meshes[meshID]
+			if mesh.Name = name; raw != nil {
+				mesh.loadRaw(raw)
+			} else {
+				provider, srcItem := me.opt.meshProvider(md, geo.Id)
+				if err := mesh.loadAndCache(newMeshCacheKey(me.filePath, srcItem), provider, nil); err != nil {
+					Diag.LogErr(err)
+					Core.Libs.Meshes.Remove(meshID, 1)
+					meshID = -1
+				}
+			}
+			if meshID > -1 {
+				if me.opt.MeshBuffer != nil {
+					if err := me.opt.MeshBuffer.Add(meshID); err != nil {
+						Diag.LogErr(err)
+					}
+				}
+				me.meshIDs[geo.Id], me.result.MeshIDs = meshID, append(me.result.MeshIDs, meshID)
+			}
+		}
+	}
+	for ci := 0; ci < len(me.doc.Controllers); ci++ {
+		me.createSkinnedMesh(&me.doc.Controllers[ci])
+	}
+	if len(me.opt.Lods) > 0 {
+		me.result.addLods(&me.opt, me.result.MeshIDs)
+	}
+}
+
+// Creates a Mesh from the geometry skinned by ctrl (if any), loaded via Mesh.LoadSkinned() or else (logging why) unskinned.
+func (me *colladaImporter) createSkinnedMesh(ctrl *colladaController) {
+	if ctrl.Skin == nil {
+		return
+	}
+	geoID := strings.TrimPrefix(ctrl.Skin.Source, "#")
+	md := me.meshDescs[geoID]
+	if md == nil {
+		return
+	}
+	name := ctrl.Name
+	if len(name) == 0 {
+		name = ctrl.Id
+	}
+	meshID := Core.Libs.Meshes.AddNew()
+	mesh := &Core.Libs.Meshes[meshID]
+	mesh.Name = name
+	provider, srcItem := me.opt.meshProvider(md, ctrl.Id)
+	skin, err := me.meshSkin(ctrl)
+	if err == nil {
+		err = mesh.LoadSkinned(provider, skin)
+	}
+	if err != nil {
+		Diag.LogErr(errf("Mesh '%v' will not be skinned: %v", name, err))
+		err = mesh.loadAndCache(newMeshCacheKey(me.filePath, srcItem), provider, nil)
+	}
+	if err == nil && me.opt.MeshBuffer != nil {
+		err = me.opt.MeshBuffer.Add(meshID)
+	}
+	if err != nil {
+		Diag.LogErr(err)
+		if !mesh.Loaded() {
+			Core.Libs.Meshes.Remove(meshID, 1)
+			return
+		}
+	}
+	me.meshIDs[ctrl.Id], me.result.MeshIDs = meshID, append(me.result.MeshIDs, meshID)
+}
+
+// Converts the <skin> of ctrl to a MeshSkin, with its Influences indexed like the positions of its geometry's u3d.MeshDescriptor.
+func (me *colladaImporter) meshSkin(ctrl *colladaController) (skin *MeshSkin, err error) {
+	var (
+		vals         []float64
+		vcounts, v   []int
+		names        []string
+		invBinds     *colladaSource
+		jointIn, wIn *colladaInput
+		weights      []float64
+	)
+	cs := ctrl.Skin
+	source := func(url string) *colladaSource {
+		for i := 0; i < len(cs.Sources); i++ {
+			if "#"+cs.Sources[i].Id == url {
+				return &cs.Sources[i]
+			}
+		}
+		return nil
+	}
+	//	Returns the float values of s, starting at its accessor offset.
+	floats := func(s *colladaSource) ([]float64, error) {
+		vals, err := importParseFloats(s.FloatArray)
+		if err == nil && (s.Accessor.Offset < 0 || s.Accessor.Offset > len(vals)) {
+			err = errf("source '%v' has an invalid accessor offset", s.Id)
+		}
+		if err != nil {
+			return nil, err
+		}
+		return vals[s.Accessor.Offset:], nil
+	}
+	skin = &MeshSkin{}
+	if vals, err = importParseFloats(cs.BindShapeMatrix); err != nil {
+		return
+	} else if len(vals) >= 16 {
+		mat4FromRowMajor(&skin.BindShapeMatrix, vals)
+	}
+	for i := 0; i < len(cs.Joints); i++ {
+		switch in := &cs.Joints[i]; in.Semantic {
+		case "JOINT":
+			if s := source(in.Source); s != nil {
+				if names = strings.Fields(s.NameArray); len(names) == 0 {
+					names = strings.Fields(s.IdRefArray)
+				}
+			}
+		case "INV_BIND_MATRIX":
+			invBinds = source(in.Source)
+		}
+	}
+	if len(names) == 0 || invBinds == nil {
+		err = errf("controller '%v' has no joints or no INV_BIND_MATRIX", ctrl.Id)
+		return
+	}
+	if vals, err = floats(invBinds); err != nil {
+		return
+	} else if len(vals) < 16*len(names) {
+		err = errf("controller '%v' has fewer inverse bind matrices than joints", ctrl.Id)
+		return
+	}
+	skin.Joints = make([]MeshSkinJoint, len(names))
+	for j, name := range names {
+		skin.Joints[j].Name = me.jointName(name)
+		mat4FromRowMajor(&skin.Joints[j].InvBindMatrix, vals[j*16:])
+	}
+	vw, stride := &cs.VertexWeights, 0
+	for i := 0; i < len(vw.Inputs); i++ {
+		in := &vw.Inputs[i]
+		if in.Offset >= stride {
+			stride = in.Offset + 1
+		}
+		switch in.Semantic {
+		case "JOINT":
+			jointIn = in
+		case "WEIGHT":
+			wIn = in
+		}
+	}
+	if jointIn == nil || wIn == nil {
+		err = errf("controller '%v' has vertex_weights without JOINT or WEIGHT", ctrl.Id)
+		return
+	}
+	if s := source(wIn.Source); s == nil {
+		err = errf("controller '%v' refers to unknown source '%v'", ctrl.Id, wIn.Source)
+		return
+	} else if weights, err = floats(s); err != nil {
+		return
+	}
+	if vcounts, err = importParseInts(vw.VCount); err != nil {
+		return
+	}
+	if v, err = importParseInts(vw.V); err != nil {
+		return
+	}
+	skin.Influences = make([][]MeshSkinInfluence, len(vcounts))
+	offset := 0
+	for pi, vc := range vcounts {
+		if (offset+vc)*stride > len(v) {
+			err = errf("controller '%v' has fewer vertex weights than its vcount requires", ctrl.Id)
+			return
+		}
+		for i := 0; i < vc; i++ {
+			idx := v[(offset+i)*stride:]
+			//	a joint index of -1 refers to the bind shape, which is not skinned
+			if joint, w := idx[jointIn.Offset], idx[wIn.Offset]; joint >= 0 {
+				if w < 0 || w >= len(weights) {
+					err = errf("controller '%v' has a weight index out of range", ctrl.Id)
+					return
+				}
+				skin.Influences[pi] = append(skin.Influences[pi], MeshSkinInfluence{Joint: joint, Weight: weights[w]})
+			}
+		}
+		offset += vc
+	}
+	return
+}
+
+// Returns the SceneNode.Name of the node with the specified sid (or else, id) that a skin refers to as a joint.
+func (me *colladaImporter) jointName(ref string) string {
+	var find func(nodes []colladaNode, sid bool) (string, bool)
+	find = func(nodes []colladaNode, sid bool) (string, bool) {
+		for i := 0; i < len(nodes); i++ {
+			if n := &nodes[i]; (sid && n.Sid == ref) || (!sid && n.Id == ref) {
+				if len(n.Name) > 0 {
+					return n.Name, true
+				}
+				return n.Id, true
+			} else if name, ok := find(n.Nodes, sid); ok {
+				return name, true
+			}
+		}
+		return "", false
+	}
+	for _, sid := range []bool{true, false} {
+		for i := 0; i < len(me.doc.VisualScenes); i++ {
+			if name, ok := find(me.doc.VisualScenes[i].Nodes, sid); ok {
+				return name
+			}
+		}
+		if name, ok := find(me.doc.Nodes, sid); ok {
+			return name
+		}
+	}
+	return ref
+}
+
+// Creates an FxEffect from the diffuse texture (or else, color) of the effect.
+func (me *colladaImporter) createEffect(effect *colladaEffect) (fxID int) {
+	tech := &effect.Profile.Technique
+	var shading *colladaShading
+	for _, shading = range []*colladaShading{tech.Phong, tech.Blinn, tech.Lambert, tech.Constant} {
+		if shading != nil {
+			break
+		}
+	}
+	fxID = Core.Libs.Effects.AddNew()
+	me.result.EffectIDs = append(me.result.EffectIDs, fxID)
+	fx := &Core.Libs.Effects[fxID]
+	if shading != nil {
+		cot := &shading.Diffuse
+		if tech.Constant == shading {
+			cot = &shading.Emission
+		}
+		if cot.Texture != nil {
+			if imgID := me.effectImageID(effect, cot.Texture.Texture); imgID > -1 {
+				fx.FxProcs.EnableTex2D(0).Tex_SetImageID(imgID)
+			}
+		} else if rgba, _ := importParseFloats(cot.Color); len(rgba) >= 3 {
+			fx.FxProcs.EnableColor(0).Color_SetRgb(gl.Float(rgba[0]), gl.Float(rgba[1]), gl.Float(rgba[2]))
+		}
+	}
+	if len(fx.FxProcs) == 0 {
+		fx.FxProcs.EnableColor(0).Color_SetRgb(0.8, 0.8, 0.8)
+	}
+	fx.UpdateRoutine()
+	return
+}
+
+// Resolves the texture attribute of a <texture> in effect (a sampler2D newparam, or non-conformingly an image ID) to an FxImage2D ID.
+func (me *colladaImporter) effectImageID(effect *colladaEffect, texture string) int {
+	params := append(append([]colladaNewParam(nil), effect.Profile.NewParams...), effect.Profile.Technique.NewParams...)
+	param := func(sid string) *colladaNewParam {
+		for i := 0; i < len(params); i++ {
+			if params[i].Sid == sid {
+				return &params[i]
+			}
+		}
+		return nil
+	}
+	if sampler := param(texture); sampler != nil && sampler.Sampler2D != nil {
+		if surface := param(strings.TrimSpace(sampler.Sampler2D.Source)); surface != nil && surface.Surface != nil {
+			texture = strings.TrimSpace(surface.Surface.InitFrom)
+		}
+	}
+	if id, ok := me.imageIDs[texture]; ok {
+		return id
+	}
+	return -1
+}
+
+// Returns an FxMaterial binding the effects of the specified instance_materials to the face tags of
+// their symbols. Nodes with the same bindings share their FxMaterial.
+func (me *colladaImporter) bindMaterial(inst *colladaInstanceGeometry) (matID int) {
+	var keys []string
+	fxIDs := map[string]int{}
+	for _, im := range inst.InstanceMaterials {
+		if fxID, ok := me.materialFxIDs[strings.TrimPrefix(im.Target, "#")]; ok {
+			fxIDs[im.Symbol] = fxID
+			keys = append(keys, strf("%s=%d", im.Symbol, fxID))
+		}
+	}
+	sort.Strings(keys)
+	key := strings.Join(keys, ";")
+	if matID, ok := me.bindMatIDs[key]; ok {
+		return matID
+	}
+	matID = Core.Libs.Materials.AddNew()
+	me.result.MaterialIDs, me.bindMatIDs[key] = append(me.result.MaterialIDs, matID), matID
+	mat := &Core.Libs.Materials[matID]
+	if len(inst.InstanceMaterials) == 0 || len(fxIDs) == 0 {
+		if me.fallbackFxID < 0 {
+			me.fallbackFxID = Core.Libs.Effects.AddNew()
+			me.result.EffectIDs = append(me.result.EffectIDs, me.fallbackFxID)
+			Core.Libs.Effects[me.fallbackFxID].FxProcs.EnableColor(0).Color_SetRgb(0.8, 0.8, 0.8)
+			Core.Libs.Effects[me.fallbackFxID].UpdateRoutine()
+		}
+		mat.DefaultEffectID = me.fallbackFxID
+	} else {
+		for _, im := range inst.InstanceMaterials {
+			if fxID, ok := fxIDs[im.Symbol]; ok {
+				if mat.DefaultEffectID < 0 {
+					mat.DefaultEffectID = fxID
+				}
+				if len(fxIDs) > 1 {
+					mat.FaceEffects.ByTag[im.Symbol] = fxID
+				}
+			}
+		}
+	}
+	return
+}
+
+// Instantiates the document's visual scene below a new child node of parentNodeID.
+func (me *colladaImporter) createScene(parentNodeID int) (err error) {
+	var vs *colladaVisualScene
+	url := strings.TrimPrefix(me.doc.Scene.InstanceVisualScene.Url, "#")
+	for i := 0; i < len(me.doc.VisualScenes); i++ {
+		if vs = &me.doc.VisualScenes[i]; len(url) == 0 || vs.Id == url {
+			break
+		}
+		vs = nil
+	}
+	if vs == nil {
+		return errf("no visual_scene '%v'", url)
+	}
+	rootID := me.scene.AddNewChildNode(parentNodeID, -1)
+	me.result.RootNodeID = rootID
+	root := &me.scene.allNodes[rootID]
+	if root.Name = vs.Name; len(root.Name) == 0 {
+		root.Name = vs.Id
+	}
+	switch strings.TrimSpace(me.doc.Asset.UpAxis) {
+	case "Z_UP":
+		root.Transform.Rot.X = -math.Pi / 2
+	case "X_UP":
+		root.Transform.Rot.Z = math.Pi / 2
+	}
+	me.instancing, me.nodeIDs = map[string]bool{}, map[string]int{}
+	for i := 0; i < len(vs.Nodes) && err == nil; i++ {
+		err = me.createNode(&vs.Nodes[i], rootID)
+	}
+	//	skeletons are only bound once all nodes exist, as a <skeleton> may refer to a node following its instance_controller
+	for _, si := range me.skinnedInst {
+		skelID := rootID
+		if len(si.inst.Skeletons) == 1 {
+			if id, ok := me.nodeIDs[strings.TrimPrefix(strings.TrimSpace(si.inst.Skeletons[0]), "#")]; ok {
+				skelID = id
+			}
+		}
+		me.scene.SetNodeSkeletonID(si.nodeID, skelID)
+	}
+	me.scene.ApplyNodeTransforms(rootID)
+	return
+}
+
+func (me *colladaImporter) createNode(node *colladaNode, parentID int) (err error) {
+	var (
+		mat, matElem, matTmp unum.Mat4
+		vals                 []float64
+		v                    unum.Vec3
+	)
+	mat.Identity()
+	for _, elem := range node.Others {
+		if vals, err = importParseFloats(elem.Value); err != nil {
+			return
+		}
+		switch elem.XMLName.Local {
+		case "matrix":
+			if len(vals) < 16 {
+				continue
+			}
+			mat4FromRowMajor(&matElem, vals)
+		case "translate":
+			if len(vals) < 3 {
+				continue
+			}
+			v.Set(vals[0], vals[1], vals[2])
+			matElem.Translation(&v)
+		case "rotate":
+			if len(vals) < 4 {
+				continue
+			}
+			v.Set(vals[0], vals[1], vals[2])
+			mat4RotationAxisDeg(&matElem, &v, vals[3])
+		case "scale":
+			if len(vals) < 3 {
+				continue
+			}
+			v.Set(vals[0], vals[1], vals[2])
+			matElem.Scaling(&v)
+		default:
+			continue
+		}
+		matTmp.SetFromMult4(&mat, &matElem)
+		mat = matTmp
+	}
+	nodeID, meshID, nodeInst := -1, -1, -1
+	insts := append(append([]colladaInstanceGeometry(nil), node.InstanceGeometries...), node.InstanceControllers...)
+	//	the first instance with geometry goes to the node itself, any others to child-nodes
+	for i := 0; i < len(insts) && nodeInst < 0; i++ {
+		if meshID = me.meshID(&insts[i]); meshID > -1 {
+			nodeInst = i
+		}
+	}
+	nodeID = me.scene.AddNewChildNode(parentID, meshID)
+	sn := &me.scene.allNodes[nodeID]
+	if sn.Name = node.Name; len(sn.Name) == 0 {
+		sn.Name = node.Id
+	}
+	if len(node.Id) > 0 {
+		me.nodeIDs[node.Id] = nodeID
+	}
+	if !sn.Transform.setFromMatrix(&mat) {
+		Diag.LogMisc("Import of '%v': the shearing in the transformation of node '%v' is lost", me.filePath, sn.Name)
+	}
+	for i := 0; i < len(insts); i++ {
+		if meshID = me.meshID(&insts[i]); meshID > -1 {
+			instNodeID := nodeID
+			if i != nodeInst {
+				instNodeID = me.scene.AddNewChildNode(nodeID, meshID)
+			}
+			me.scene.allNodes[instNodeID].SetMatID(me.bindMaterial(&insts[i]))
+			if i >= len(node.InstanceGeometries) && Core.Libs.Meshes[meshID].Skinned() {
+				me.skinnedInst = append(me.skinnedInst, colladaSkinnedInst{nodeID: instNodeID, inst: &node.InstanceControllers[i-len(node.InstanceGeometries)]})
+			}
+		}
+	}
+	for _, ic := range node.InstanceCameras {
+		me.createCamera(strings.TrimPrefix(ic.Url, "#"), nodeID)
+	}
+	for i := 0; i < len(node.Nodes) && err == nil; i++ {
+		err = me.createNode(&node.Nodes[i], nodeID)
+	}
+	for _, in := range node.InstanceNodes {
+		url := strings.TrimPrefix(in.Url, "#")
+		if me.instancing[url] {
+			return errf("node '%v' instantiates itself", url)
+		}
+		for i := 0; i < len(me.doc.Nodes) && err == nil; i++ {
+			if me.doc.Nodes[i].Id == url {
+				me.instancing[url] = true
+				err = me.createNode(&me.doc.Nodes[i], nodeID)
+				me.instancing[url] = false
+			}
+		}
+	}
+	return
+}
+
+func (me *colladaImporter) meshID(inst *colladaInstanceGeometry) int {
+	if id, ok := me.meshIDs[strings.TrimPrefix(inst.Url, "#")]; ok {
+		return id
+	}
+	return -1
+}
+
+func (me *colladaImporter) createCamera(camID string, nodeID int) {
+	for _, cam := range me.doc.Cameras {
+		if cam.Id == camID {
+			ic := ImportCamera{Name: cam.Name, NodeID: nodeID}
+			if len(ic.Name) == 0 {
+				ic.Name = cam.Id
+			}
+			if p := cam.Perspective; p != nil {
+				ic.Perspective.Enabled, ic.Perspective.ZNear, ic.Perspective.ZFar, ic.Perspective.FovY.Deg = true, p.ZNear, p.ZFar, p.YFov
+				if p.YFov == 0 && p.XFov != 0 {
+					aspect := p.AspectRatio
+					if aspect == 0 {
+						aspect = 1
+					}
+					ic.Perspective.FovY.Deg = 2 * math.Atan(math.Tan(p.XFov*math.Pi/360)/aspect) * 180 / math.Pi
+				}
+			}
+			me.result.Cameras = append(me.result.Cameras, ic)
+			return
+		}
+	}
+}
@@ -0,0 +1,46 @@
+package core
+
+import (
+	"testing"
+)
+
+func TestImportColladaNodeInstances(t *testing.T) {
+	geo, geo2 := sceneTestMesh(t, "geo", 1), sceneTestMesh(t, "geo2", 1)
+	defer Core.Libs.Meshes.Remove(geo, 1)
+	defer Core.Libs.Meshes.Remove(geo2, 1)
+	for _, test := range []struct {
+		name  string
+		urls  []string
+		mesh  int
+		child []int
+	}{
+		{"one", []string{"#geo"}, geo, nil},
+		{"two", []string{"#geo", "#geo2"}, geo, []int{geo2}},
+		{"first without geometry", []string{"#missing", "#geo"}, geo, nil},
+		{"only later with geometry", []string{"#missing", "#geo", "#missing", "#geo2"}, geo, []int{geo2}},
+		{"none with geometry", []string{"#missing"}, -1, nil},
+	} {
+		imp := &colladaImporter{scene: sceneTestNew(), result: &ImportResult{RootNodeID: -1}, fallbackFxID: -1,
+			meshIDs: map[string]int{"geo": geo, "geo2": geo2}, bindMatIDs: map[string]int{}, nodeIDs: map[string]int{}, instancing: map[string]bool{}}
+		node := &colladaNode{Id: "node"}
+		for _, url := range test.urls {
+			node.InstanceGeometries = append(node.InstanceGeometries, colladaInstanceGeometry{Url: url})
+		}
+		if err := imp.createNode(node, 0); err != nil {
+			t.Errorf("%v: %v", test.name, err)
+			continue
+		}
+		sn := &imp.scene.allNodes[imp.nodeIDs["node"]]
+		if sn.meshID() != test.mesh {
+			t.Errorf("%v: node has mesh %v, want %v", test.name, sn.meshID(), test.mesh)
+		}
+		var child []int
+		for _, cid := range sn.childNodeIDs {
+			child = append(child, imp.scene.allNodes[cid].meshID())
+		}
+		if strf("%v", child) != strf("%v", test.child) {
+			t.Errorf("%v: child-nodes have meshes %v, want %v", test.name, child, test.child)
+		}
+		imp.result.remove()
+	}
+}
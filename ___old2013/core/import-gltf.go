@@ -0,0 +1,1104 @@
+package core
+
+import (
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"io"
+	"io/ioutil"
+	"math"
+	"net/url"
+	"sort"
+	"strings"
+
+	"github.com/metaleap/go-util-num"
+	u3d "github.com/metaleap/go-util-3d"
+	gl "github.com/metaleap/go-opengl/core"
+)
+
+const (
+	gltfGlbMagic     = 0x46546C67
+	gltfGlbChunkJson = 0x4E4F534A
+	gltfGlbChunkBin  = 0x004E4942
+)
+
+type gltfDoc struct {
+	Accessors   []gltfAccessor   `json:"accessors"`
+	Animations  []gltfAnimation  `json:"animations"`
+	Buffers     []gltfBuffer     `json:"buffers"`
+	BufferViews []gltfBufferView `json:"bufferViews"`
+	Cameras     []gltfCamera     `json:"cameras"`
+	Images      []gltfImage      `json:"images"`
+	Materials   []gltfMaterial   `json:"materials"`
+	Meshes      []gltfMesh       `json:"meshes"`
+	Nodes       []gltfNode       `json:"nodes"`
+	Scene       *int             `json:"scene"`
+	Scenes      []gltfScene      `json:"scenes"`
+	Skins       []gltfSkin       `json:"skins"`
+	Textures    []gltfTexture    `json:"textures"`
+}
+
+type gltfAccessor struct {
+	BufferView    *int   `json:"bufferView"`
+	ByteOffset    int    `json:"byteOffset"`
+	ComponentType int    `json:"componentType"`
+	Normalized    bool   `json:"normalized"`
+	Count         int    `json:"count"`
+	Type          string `json:"type"`
+	Sparse        *struct {
+		Count   int `json:"count"`
+		Indices struct {
+			BufferView    int `json:"bufferView"`
+			ByteOffset    int `json:"byteOffset"`
+			ComponentType int `json:"componentType"`
+		} `json:"indices"`
+		Values struct {
+			BufferView int `json:"bufferView"`
+			ByteOffset int `json:"byteOffset"`
+		} `json:"values"`
+	} `json:"sparse"`
+}
+
+type gltfAnimation struct {
+	Name     string `json:"name"`
+	Channels []struct {
+		Sampler int `json:"sampler"`
+		Target  struct {
+			Node *int   `json:"node"`
+			Path string `json:"path"`
+		} `json:"target"`
+	} `json:"channels"`
+	Samplers []struct {
+		Input         int    `json:"input"`
+		Output        int    `json:"output"`
+		Interpolation string `json:"interpolation"`
+	} `json:"samplers"`
+}
+
+type gltfBuffer struct {
+	ByteLength int    `json:"byteLength"`
+	Uri        string `json:"uri"`
+}
+
+type gltfBufferView struct {
+	Buffer     int `json:"buffer"`
+	ByteLength int `json:"byteLength"`
+	ByteOffset int `json:"byteOffset"`
+	ByteStride int `json:"byteStride"`
+}
+
+type gltfCamera struct {
+	Name        string `json:"name"`
+	Perspective *struct {
+		Yfov  float64 `json:"yfov"`
+		Zfar  float64 `json:"zfar"`
+		Znear float64 `json:"znear"`
+	} `json:"perspective"`
+}
+
+type gltfImage struct {
+	Uri        string `json:"uri"`
+	BufferView *int   `json:"bufferView"`
+}
+
+type gltfMaterial struct {
+	Name string `json:"name"`
+	Pbr  struct {
+		BaseColorFactor  []float64 `json:"baseColorFactor"`
+		BaseColorTexture *struct {
+			Index int `json:"index"`
+		} `json:"baseColorTexture"`
+	} `json:"pbrMetallicRoughness"`
+}
+
+type gltfMesh struct {
+	Name       string          `json:"name"`
+	Primitives []gltfPrimitive `json:"primitives"`
+	Weights    []float64       `json:"weights"`
+	Extras     struct {
+		TargetNames []string `json:"targetNames"`
+	} `json:"extras"`
+}
+
+type gltfPrimitive struct {
+	Attributes map[string]int   `json:"attributes"`
+	Indices    *int             `json:"indices"`
+	Material   *int             `json:"material"`
+	Mode       *int             `json:"mode"`
+	Targets    []map[string]int `json:"targets"`
+}
+
+type gltfNode struct {
+	Name        string    `json:"name"`
+	Camera      *int      `json:"camera"`
+	Children    []int     `json:"children"`
+	Matrix      []float64 `json:"matrix"`
+	Mesh        *int      `json:"mesh"`
+	Rotation    []float64 `json:"rotation"`
+	Scale       []float64 `json:"scale"`
+	Skin        *int      `json:"skin"`
+	Translation []float64 `json:"translation"`
+	Weights     []float64 `json:"weights"`
+}
+
+type gltfScene struct {
+	Name  string `json:"name"`
+	Nodes []int  `json:"nodes"`
+}
+
+type gltfSkin struct {
+	InverseBindMatrices *int  `json:"inverseBindMatrices"`
+	Joints              []int `json:"joints"`
+}
+
+type gltfTexture struct {
+	Source *int `json:"source"`
+}
+
+//	The geometry of a glTF mesh, converted before any lib entries are created.
+type gltfMeshData struct {
+	desc       *u3d.MeshDescriptor
+	influences [][]MeshSkinInfluence
+	morphs     []MeshMorphTarget
+
+	//	The glTF material index of each primitive, or -1.
+	materials []int
+
+	//	If set, desc is nil since the mesh was found in the disk cache.
+	cached *meshRaw
+}
+
+//	The state of a single ImportGltf() call.
+type gltfImporter struct {
+	doc      gltfDoc
+	filePath string
+	opt      ImportOptions
+	scene    *Scene
+	result   *ImportResult
+
+	bin          []byte
+	buffers      [][]byte
+	meshData     []*gltfMeshData
+	nodeNames    []string
+	imageIDs     []int
+	effectIDs    []int
+	fallbackFxID int
+	meshIDs      []int
+	modelIDs     []int
+	matIDs       map[string]int
+	nodeIDs      []int
+}
+
+//	Imports the glTF 2.0 asset at filePath (either a .gltf file, with external or embedded buffers and images,
+//	or a binary .glb file) into me, below parentNodeID.
+//
+//	Each glTF mesh becomes a Mesh with a Model, whose FxMaterial applies the FxEffect of each primitive's
+//	material (using its base-color texture or else factor) to the faces of that primitive. The node hierarchy
+//	of the asset's scene is instantiated below a new node, with skins bound to it as the Render.Skeleton, and
+//	each animation becomes an AnimClip meant for an AnimPlayer with that node as its root. Nodes without a unique
+//	name are named "node<index>" (suffixed if that is taken), so that skins and AnimTracks can address them.
+//
+//	Not supported: points and lines, all but the first set of texcoords and joints, and any extensions.
+//	Cubic splines become AnimInterpHermite tracks, except for rotations, which are slerped between keys. Since glTF
+//	scales before it rotates, rotated nodes should only be scaled uniformly.
+func (me *Scene) ImportGltf(filePath string, parentNodeID int, opt *ImportOptions) (result *ImportResult, err error) {
+	var (
+		rc   io.ReadCloser
+		data []byte
+	)
+	if !me.allNodes.IsOk(parentNodeID) {
+		err = errf("Cannot import '%v': invalid parent node ID %v", filePath, parentNodeID)
+		return
+	}
+	if rc, err = Core.fileIO.openLocalFile(filePath); err != nil {
+		return
+	}
+	data, err = ioutil.ReadAll(rc)
+	rc.Close()
+	if err != nil {
+		return
+	}
+	imp := &gltfImporter{filePath: filePath, scene: me, result: &ImportResult{RootNodeID: -1}, fallbackFxID: -1, matIDs: map[string]int{}}
+	if opt != nil {
+		imp.opt = *opt
+	}
+	if err = imp.decode(data); err == nil {
+		if err = imp.loadBuffers(); err == nil {
+			if err = imp.loadMeshData(); err == nil {
+				imp.createLibs()
+				if err = imp.createScene(parentNodeID); err == nil {
+					err = imp.createAnimClips()
+				}
+			}
+		}
+	}
+	if err != nil {
+		err = errf("Cannot import '%v': %v", filePath, err)
+	} else {
+		result = imp.result
+	}
+	return
+}
+
+//	Parses either a .glb container or plain glTF JSON.
+func (me *gltfImporter) decode(data []byte) (err error) {
+	if len(data) >= 12 && binary.LittleEndian.Uint32(data) == gltfGlbMagic {
+		if version := binary.LittleEndian.Uint32(data[4:]); version != 2 {
+			return errf("unsupported glb version %v", version)
+		}
+		var jsonChunk []byte
+		for pos := 12; pos+8 <= len(data); {
+			size, kind := int(binary.LittleEndian.Uint32(data[pos:])), binary.LittleEndian.Uint32(data[pos+4:])
+			if pos += 8; size < 0 || pos+size > len(data) {
+				return errf("truncated glb chunk")
+			}
+			switch kind {
+			case gltfGlbChunkJson:
+				jsonChunk = data[pos : pos+size]
+			case gltfGlbChunkBin:
+				if me.bin == nil {
+					me.bin = data[pos : pos+size]
+				}
+			}
+			pos += size
+		}
+		data = jsonChunk
+	}
+	return json.Unmarshal(data, &me.doc)
+}
+
+//	Resolves all buffers: the glb binary chunk, data URIs and external files.
+func (me *gltfImporter) loadBuffers() (err error) {
+	var rc io.ReadCloser
+	me.buffers = make([][]byte, len(me.doc.Buffers))
+	for i, buf := range me.doc.Buffers {
+		switch {
+		case len(buf.Uri) == 0:
+			if me.bin == nil {
+				return errf("buffer %v has no uri and there is no glb binary chunk", i)
+			}
+			me.buffers[i] = me.bin
+		case strings.HasPrefix(buf.Uri, "data:"):
+			if me.buffers[i], err = gltfDataUri(buf.Uri); err != nil {
+				return
+			}
+		default:
+			if rc, err = Core.fileIO.openLocalFile(importImageUrl(me.filePath, gltfUnescape(buf.Uri))); err != nil {
+				return
+			}
+			me.buffers[i], err = ioutil.ReadAll(rc)
+			if rc.Close(); err != nil {
+				return
+			}
+		}
+		if len(me.buffers[i]) < buf.ByteLength {
+			return errf("buffer %v has %v bytes rather than %v", i, len(me.buffers[i]), buf.ByteLength)
+		}
+	}
+	return
+}
+
+//	Returns the bytes of the specified buffer view and its stride (0 if tightly packed).
+func (me *gltfImporter) bufferView(index int) (data []byte, stride int, err error) {
+	if index < 0 || index >= len(me.doc.BufferViews) {
+		err = errf("invalid bufferView %v", index)
+		return
+	}
+	bv := &me.doc.BufferViews[index]
+	if bv.Buffer < 0 || bv.Buffer >= len(me.buffers) || bv.ByteOffset < 0 || bv.ByteLength < 0 || bv.ByteOffset+bv.ByteLength > len(me.buffers[bv.Buffer]) {
+		err = errf("bufferView %v exceeds its buffer", index)
+		return
+	}
+	data, stride = me.buffers[bv.Buffer][bv.ByteOffset:bv.ByteOffset+bv.ByteLength], bv.ByteStride
+	return
+}
+
+//	Returns all elements of the specified accessor as a flat list of numComps values each.
+func (me *gltfImporter) accessor(index int) (vals []float64, numComps int, err error) {
+	if index < 0 || index >= len(me.doc.Accessors) {
+		err = errf("invalid accessor %v", index)
+		return
+	}
+	acc := &me.doc.Accessors[index]
+	if numComps = map[string]int{"SCALAR": 1, "VEC2": 2, "VEC3": 3, "VEC4": 4, "MAT2": 4, "MAT3": 9, "MAT4": 16}[acc.Type]; numComps == 0 || acc.Count < 0 {
+		err = errf("accessor %v has invalid type '%v'", index, acc.Type)
+		return
+	}
+	vals = make([]float64, acc.Count*numComps)
+	if acc.BufferView != nil {
+		var (
+			data   []byte
+			stride int
+		)
+		if data, stride, err = me.bufferView(*acc.BufferView); err == nil {
+			err = gltfReadComponents(vals, data, acc.ByteOffset, stride, acc.ComponentType, numComps, acc.Normalized)
+		}
+	}
+	if sp := acc.Sparse; sp != nil && err == nil {
+		var idxData, valData []byte
+		if idxData, _, err = me.bufferView(sp.Indices.BufferView); err == nil {
+			valData, _, err = me.bufferView(sp.Values.BufferView)
+		}
+		indices, sparseVals := make([]float64, sp.Count), make([]float64, sp.Count*numComps)
+		if err == nil {
+			if err = gltfReadComponents(indices, idxData, sp.Indices.ByteOffset, 0, sp.Indices.ComponentType, 1, false); err == nil {
+				err = gltfReadComponents(sparseVals, valData, sp.Values.ByteOffset, 0, acc.ComponentType, numComps, acc.Normalized)
+			}
+		}
+		for i := 0; i < sp.Count && err == nil; i++ {
+			if idx := int(indices[i]); idx >= acc.Count {
+				err = errf("accessor %v has a sparse index out of range", index)
+			} else {
+				copy(vals[idx*numComps:(idx+1)*numComps], sparseVals[i*numComps:])
+			}
+		}
+	}
+	if err != nil {
+		err = errf("accessor %v: %v", index, err)
+	}
+	return
+}
+
+//	Reads len(vals) / numComps elements of numComps components each from data into vals.
+func gltfReadComponents(vals []float64, data []byte, offset, stride, componentType, numComps int, normalized bool) (err error) {
+	size := map[int]int{5120: 1, 5121: 1, 5122: 2, 5123: 2, 5125: 4, 5126: 4}[componentType]
+	if size == 0 {
+		return errf("invalid componentType %v", componentType)
+	}
+	if stride == 0 {
+		stride = size * numComps
+	}
+	if count := len(vals) / numComps; count > 0 && (offset < 0 || offset+(count-1)*stride+size*numComps > len(data)) {
+		return errf("exceeds its bufferView")
+	}
+	for i := 0; i < len(vals); i++ {
+		b := data[offset+(i/numComps)*stride+(i%numComps)*size:]
+		switch componentType {
+		case 5120:
+			if vals[i] = float64(int8(b[0])); normalized {
+				vals[i] = math.Max(vals[i]/127, -1)
+			}
+		case 5121:
+			if vals[i] = float64(b[0]); normalized {
+				vals[i] /= 255
+			}
+		case 5122:
+			if vals[i] = float64(int16(binary.LittleEndian.Uint16(b))); normalized {
+				vals[i] = math.Max(vals[i]/32767, -1)
+			}
+		case 5123:
+			if vals[i] = float64(binary.LittleEndian.Uint16(b)); normalized {
+				vals[i] /= 65535
+			}
+		case 5125:
+			vals[i] = float64(binary.LittleEndian.Uint32(b))
+		case 5126:
+			vals[i] = float64(math.Float32frombits(binary.LittleEndian.Uint32(b)))
+		}
+	}
+	return
+}
+
+//	Decodes a base64 data URI.
+func gltfDataUri(uri string) ([]byte, error) {
+	i := strings.Index(uri, ",")
+	if i < 0 || !strings.HasSuffix(uri[:i], ";base64") {
+		return nil, errf("unsupported data URI")
+	}
+	return base64.StdEncoding.DecodeString(uri[i+1:])
+}
+
+//	Decodes the percent-encoding of a relative URI.
+func gltfUnescape(uri string) string {
+	if s, err := url.QueryUnescape(strings.Replace(uri, "+", "%2B", -1)); err == nil {
+		return s
+	}
+	return uri
+}
+
+//	Converts all glTF meshes (except those found in the disk cache) and assigns unique names to all nodes.
+func (me *gltfImporter) loadMeshData() (err error) {
+	me.meshData = make([]*gltfMeshData, len(me.doc.Meshes))
+	skinned := make([]bool, len(me.doc.Meshes))
+	for _, node := range me.doc.Nodes {
+		if node.Mesh != nil && node.Skin != nil && *node.Mesh >= 0 && *node.Mesh < len(skinned) {
+			skinned[*node.Mesh] = true
+		}
+	}
+	for i := 0; i < len(me.doc.Meshes) && err == nil; i++ {
+		//	skins and morph targets aren't cached, so such meshes are always converted
+		if !skinned[i] && !me.doc.Meshes[i].morphed() {
+			if raw, _ := newMeshCacheKey(me.filePath, me.opt.meshSrcItem(strf("mesh%d", i))).load(); raw != nil {
+				me.meshData[i] = &gltfMeshData{cached: raw, materials: me.doc.Meshes[i].materials()}
+				continue
+			}
+		}
+		if me.meshData[i], err = me.convertMesh(&me.doc.Meshes[i]); err != nil {
+			err = errf("mesh %v: %v", i, err)
+		}
+	}
+	counts := map[string]int{}
+	for _, node := range me.doc.Nodes {
+		counts[node.Name]++
+	}
+	me.nodeNames = make([]string, len(me.doc.Nodes))
+	for i, node := range me.doc.Nodes {
+		if me.nodeNames[i] = node.Name; len(node.Name) == 0 || counts[node.Name] > 1 {
+			//	the generated name must not be taken by another node either
+			name := strf("node%d", i)
+			for n := 1; counts[name] > 0; n++ {
+				name = strf("node%d_%d", i, n)
+			}
+			me.nodeNames[i], counts[name] = name, 1
+		}
+	}
+	return
+}
+
+func (me *gltfImporter) convertMesh(mesh *gltfMesh) (md *gltfMeshData, err error) {
+	var (
+		positions, normals, texCoords, indices, joints, weights, deltas []float64
+		n, numJoints                                                     int
+		noTex                                                            = -1
+		tris                                                             [][3]uint32
+	)
+	md = &gltfMeshData{desc: &u3d.MeshDescriptor{}}
+	desc := md.desc
+	for pi := 0; pi < len(mesh.Primitives); pi++ {
+		prim := &mesh.Primitives[pi]
+		mode := 4
+		if prim.Mode != nil {
+			mode = *prim.Mode
+		}
+		posAcc, ok := prim.Attributes["POSITION"]
+		if mode < 4 || !ok {
+			//	points and lines are not supported
+			continue
+		}
+		if positions, n, err = me.accessor(posAcc); err == nil && n != 3 {
+			err = errf("POSITION is not VEC3")
+		}
+		if err != nil {
+			return
+		}
+		count, posBase, normBase := len(positions)/3, len(desc.Positions), len(desc.Normals)
+		for i := 0; i < count; i++ {
+			desc.Positions = append(desc.Positions, u3d.MeshDescVA3{float32(positions[i*3]), float32(positions[i*3+1]), float32(positions[i*3+2])})
+		}
+		normals, texCoords = nil, nil
+		if acc, ok := prim.Attributes["NORMAL"]; ok {
+			if normals, n, err = me.accessor(acc); err == nil && (n != 3 || len(normals) != len(positions)) {
+				err = errf("NORMAL does not match POSITION")
+			}
+		}
+		if acc, ok := prim.Attributes["TEXCOORD_0"]; ok && err == nil {
+			if texCoords, n, err = me.accessor(acc); err == nil && (n != 2 || len(texCoords)/2 != count) {
+				err = errf("TEXCOORD_0 does not match POSITION")
+			}
+		}
+		if err != nil {
+			return
+		}
+		for i := 0; i < len(normals)/3; i++ {
+			desc.Normals = append(desc.Normals, u3d.MeshDescVA3{float32(normals[i*3]), float32(normals[i*3+1]), float32(normals[i*3+2])})
+		}
+		texBase := len(desc.TexCoords)
+		for i := 0; i < len(texCoords)/2; i++ {
+			//	glTF texcoords start at the top of the image
+			desc.TexCoords = append(desc.TexCoords, u3d.MeshDescVA2{float32(texCoords[i*2]), float32(1 - texCoords[i*2+1])})
+		}
+		if len(texCoords) == 0 && noTex < 0 {
+			noTex, desc.TexCoords = len(desc.TexCoords), append(desc.TexCoords, u3d.MeshDescVA2{0, 0})
+		}
+		//	gather the triangles, as indices into the vertices of this primitive
+		if prim.Indices != nil {
+			if indices, _, err = me.accessor(*prim.Indices); err != nil {
+				return
+			}
+		} else {
+			indices = make([]float64, count)
+			for i := range indices {
+				indices[i] = float64(i)
+			}
+		}
+		for _, idx := range indices {
+			if int(idx) >= count {
+				err = errf("primitive %v has an index out of range", pi)
+				return
+			}
+		}
+		tris = tris[:0]
+		switch mode {
+		case 4:
+			for i := 2; i < len(indices); i += 3 {
+				tris = append(tris, [3]uint32{uint32(indices[i-2]), uint32(indices[i-1]), uint32(indices[i])})
+			}
+		case 5:
+			for i := 2; i < len(indices); i++ {
+				if i%2 == 0 {
+					tris = append(tris, [3]uint32{uint32(indices[i-2]), uint32(indices[i-1]), uint32(indices[i])})
+				} else {
+					tris = append(tris, [3]uint32{uint32(indices[i-1]), uint32(indices[i-2]), uint32(indices[i])})
+				}
+			}
+		case 6:
+			for i := 2; i < len(indices); i++ {
+				tris = append(tris, [3]uint32{uint32(indices[0]), uint32(indices[i-1]), uint32(indices[i])})
+			}
+		}
+		matIndex := -1
+		if prim.Material != nil {
+			if matIndex = *prim.Material; matIndex < 0 || matIndex >= len(me.doc.Materials) {
+				err = errf("primitive %v has invalid material %v", pi, matIndex)
+				return
+			}
+		}
+		md.materials = append(md.materials, matIndex)
+		for _, tri := range tris {
+			var face u3d.MeshDescF3
+			if matIndex >= 0 {
+				face.Tags = []string{gltfMaterialTag(matIndex)}
+			}
+			for v := 0; v < 3; v++ {
+				face.V[v].PosIndex, face.V[v].NormalIndex, face.V[v].TexCoordIndex = uint32(posBase)+tri[v], uint32(normBase)+tri[v], uint32(noTex)
+				if len(texCoords) > 0 {
+					face.V[v].TexCoordIndex = uint32(texBase) + tri[v]
+				}
+			}
+			if len(normals) == 0 {
+				n := importFlatNormal(desc, face.V[0].PosIndex, face.V[1].PosIndex, face.V[2].PosIndex)
+				face.V[0].NormalIndex, face.V[1].NormalIndex, face.V[2].NormalIndex = n, n, n
+			}
+			desc.Faces = append(desc.Faces, face)
+		}
+		//	skinning influences, indexed like desc.Positions
+		joints, weights = nil, nil
+		if acc, ok := prim.Attributes["JOINTS_0"]; ok {
+			if joints, numJoints, err = me.accessor(acc); err == nil {
+				if acc, ok = prim.Attributes["WEIGHTS_0"]; !ok {
+					err = errf("primitive %v has JOINTS_0 but no WEIGHTS_0", pi)
+				} else if weights, n, err = me.accessor(acc); err == nil && (n != numJoints || len(weights) != len(joints) || len(joints)/numJoints != count) {
+					err = errf("primitive %v has mismatching JOINTS_0 and WEIGHTS_0", pi)
+				}
+			}
+			if err != nil {
+				return
+			}
+			for len(md.influences) < posBase {
+				md.influences = append(md.influences, nil)
+			}
+			for i := 0; i < count; i++ {
+				var infl []MeshSkinInfluence
+				for j := 0; j < numJoints; j++ {
+					if w := weights[i*numJoints+j]; w > 0 {
+						infl = append(infl, MeshSkinInfluence{Joint: int(joints[i*numJoints+j]), Weight: w})
+					}
+				}
+				md.influences = append(md.influences, infl)
+			}
+		}
+		//	morph targets, with deltas indexed like desc.Positions and desc.Normals
+		for t, target := range prim.Targets {
+			for len(md.morphs) <= t {
+				name := strf("target%d", len(md.morphs))
+				if len(md.morphs) < len(mesh.Extras.TargetNames) {
+					name = mesh.Extras.TargetNames[len(md.morphs)]
+				}
+				md.morphs = append(md.morphs, MeshMorphTarget{Name: name})
+			}
+			morph := &md.morphs[t]
+			if acc, ok := target["POSITION"]; ok {
+				if deltas, n, err = me.accessor(acc); err == nil && (n != 3 || len(deltas) != len(positions)) {
+					err = errf("morph target %v does not match POSITION", t)
+				}
+				if err != nil {
+					return
+				}
+				for len(morph.PosDeltas) < posBase {
+					morph.PosDeltas = append(morph.PosDeltas, u3d.MeshDescVA3{})
+				}
+				for i := 0; i < count; i++ {
+					morph.PosDeltas = append(morph.PosDeltas, u3d.MeshDescVA3{float32(deltas[i*3]), float32(deltas[i*3+1]), float32(deltas[i*3+2])})
+				}
+			}
+			if acc, ok := target["NORMAL"]; ok && len(normals) > 0 {
+				if deltas, n, err = me.accessor(acc); err == nil && (n != 3 || len(deltas) != len(normals)) {
+					err = errf("morph target %v does not match NORMAL", t)
+				}
+				if err != nil {
+					return
+				}
+				for len(morph.NormalDeltas) < normBase {
+					morph.NormalDeltas = append(morph.NormalDeltas, u3d.MeshDescVA3{})
+				}
+				for i := 0; i < count; i++ {
+					morph.NormalDeltas = append(morph.NormalDeltas, u3d.MeshDescVA3{float32(deltas[i*3]), float32(deltas[i*3+1]), float32(deltas[i*3+2])})
+				}
+			}
+		}
+	}
+	for t := 0; t < len(md.morphs); t++ {
+		for len(md.morphs[t].PosDeltas) < len(desc.Positions) {
+			md.morphs[t].PosDeltas = append(md.morphs[t].PosDeltas, u3d.MeshDescVA3{})
+		}
+		if len(md.morphs[t].NormalDeltas) > 0 {
+			for len(md.morphs[t].NormalDeltas) < len(desc.Normals) {
+				md.morphs[t].NormalDeltas = append(md.morphs[t].NormalDeltas, u3d.MeshDescVA3{})
+			}
+		}
+	}
+	return
+}
+
+//	Returns true if any primitive of me has morph targets.
+func (me *gltfMesh) morphed() bool {
+	for pi := 0; pi < len(me.Primitives); pi++ {
+		if len(me.Primitives[pi].Targets) > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+//	Returns the glTF material index (or -1) of each triangle primitive of me, just like gltfMeshData.materials.
+func (me *gltfMesh) materials() (matIndices []int) {
+	for pi := 0; pi < len(me.Primitives); pi++ {
+		prim := &me.Primitives[pi]
+		if _, ok := prim.Attributes["POSITION"]; ok && (prim.Mode == nil || *prim.Mode >= 4) {
+			matIndex := -1
+			if prim.Material != nil {
+				matIndex = *prim.Material
+			}
+			matIndices = append(matIndices, matIndex)
+		}
+	}
+	return
+}
+
+//	The face tag of all faces of primitives using the specified glTF material.
+func gltfMaterialTag(matIndex int) string {
+	return strf("material%d", matIndex)
+}
+
+//	Creates the FxImage2Ds, FxEffects, Meshes, Models and FxMaterials of the asset.
+func (me *gltfImporter) createLibs() {
+	me.imageIDs = make([]int, len(me.doc.Images))
+	for i, img := range me.doc.Images {
+		id := Core.Libs.Images.Tex2D.AddNew()
+		me.imageIDs[i], me.result.ImageIDs = id, append(me.result.ImageIDs, id)
+		initFrom := &Core.Libs.Images.Tex2D[id].InitFrom
+		switch {
+		case img.BufferView != nil:
+			if data, _, err := me.bufferView(*img.BufferView); err != nil {
+				Diag.LogErr(errf("image %v: %v", i, err))
+			} else {
+				initFrom.RawData = append([]byte(nil), data...)
+			}
+		case strings.HasPrefix(img.Uri, "data:"):
+			if data, err := gltfDataUri(img.Uri); err != nil {
+				Diag.LogErr(errf("image %v: %v", i, err))
+			} else {
+				initFrom.RawData = data
+			}
+		default:
+			initFrom.RefUrl = importImageUrl(me.filePath, gltfUnescape(img.Uri))
+		}
+	}
+	me.effectIDs = make([]int, len(me.doc.Materials))
+	for i := 0; i < len(me.doc.Materials); i++ {
+		me.effectIDs[i] = me.createEffect(&me.doc.Materials[i])
+	}
+	skins := me.meshSkins()
+	me.meshIDs, me.modelIDs = make([]int, len(me.doc.Meshes)), make([]int, len(me.doc.Meshes))
+	for i, md := range me.meshData {
+		me.meshIDs[i], me.modelIDs[i] = -1, -1
+		if md.cached == nil && len(md.desc.Faces) == 0 {
+			continue
+		}
+		name := me.doc.Meshes[i].Name
+		if len(name) == 0 {
+			name = strf("mesh%d", i)
+		}
+		meshID := Core.Libs.Meshes.AddNew()
+		mesh := &Core.Libs.Meshes[meshID]
+		mesh.Name = name
+		var err error
+		if md.cached != nil {
+			mesh.loadRaw(md.cached)
+		} else {
+			opt := me.opt
+			if opt.Repair != nil && len(md.morphs) > 0 {
+				repair := *opt.Repair
+				repair.Normals, opt.Repair = MeshNormalsKeep, &repair
+			}
+			provider, srcItem := opt.meshProvider(md.desc, strf("mesh%d", i))
+			if skin := skins[i]; skin != nil && len(md.influences) > 0 {
+				skin.Influences = md.influences
+				if err = mesh.LoadSkinned(provider, skin); err != nil {
+					Diag.LogErr(errf("Mesh '%v' will not be skinned: %v", name, err))
+				}
+			}
+			if !mesh.Loaded() {
+				err = mesh.loadAndCache(newMeshCacheKey(me.filePath, srcItem), provider, nil)
+			}
+		}
+		if err != nil {
+			Diag.LogErr(err)
+			Core.Libs.Meshes.Remove(meshID, 1)
+			continue
+		}
+		for t := 0; t < len(md.morphs); t++ {
+			if _, err = mesh.AddMorphTarget(&md.morphs[t]); err != nil {
+				Diag.LogErr(err)
+			}
+		}
+		if me.opt.MeshBuffer != nil {
+			if err = me.opt.MeshBuffer.Add(meshID); err != nil {
+				Diag.LogErr(err)
+			}
+		}
+		modelID := Core.Libs.Models.AddNew()
+		Core.Libs.Models[modelID].Name, Core.Libs.Models[modelID].MatID = name, me.bindMaterials(md.materials)
+		mesh.DefaultModelID = modelID
+		me.meshIDs[i], me.modelIDs[i] = meshID, modelID
+		me.result.MeshIDs, me.result.ModelIDs = append(me.result.MeshIDs, meshID), append(me.result.ModelIDs, modelID)
+	}
+	if len(me.opt.Lods) > 0 {
+		me.result.addLods(&me.opt, me.result.MeshIDs)
+	}
+}
+
+//	Returns, per glTF mesh, the MeshSkin (without Influences) of the first skinned node using it, if any.
+func (me *gltfImporter) meshSkins() (skins []*MeshSkin) {
+	skins = make([]*MeshSkin, len(me.doc.Meshes))
+	for _, node := range me.doc.Nodes {
+		if node.Mesh == nil || node.Skin == nil || *node.Mesh < 0 || *node.Mesh >= len(skins) || *node.Skin < 0 || *node.Skin >= len(me.doc.Skins) || skins[*node.Mesh] != nil {
+			continue
+		}
+		gs, skin := &me.doc.Skins[*node.Skin], &MeshSkin{}
+		var mats []float64
+		if gs.InverseBindMatrices != nil {
+			var err error
+			if mats, _, err = me.accessor(*gs.InverseBindMatrices); err != nil {
+				Diag.LogErr(err)
+				mats = nil
+			}
+		}
+		for j, jointNode := range gs.Joints {
+			joint := MeshSkinJoint{}
+			if jointNode >= 0 && jointNode < len(me.nodeNames) {
+				joint.Name = me.nodeNames[jointNode]
+			}
+			if len(mats) >= (j+1)*16 {
+				copy(joint.InvBindMatrix[:], mats[j*16:])
+			} else {
+				joint.InvBindMatrix.Identity()
+			}
+			skin.Joints = append(skin.Joints, joint)
+		}
+		skins[*node.Mesh] = skin
+	}
+	return
+}
+
+//	Creates an FxEffect from the base-color texture (or else, factor) of the material.
+func (me *gltfImporter) createEffect(mat *gltfMaterial) (fxID int) {
+	fxID = Core.Libs.Effects.AddNew()
+	me.result.EffectIDs = append(me.result.EffectIDs, fxID)
+	fx := &Core.Libs.Effects[fxID]
+	if tex := mat.Pbr.BaseColorTexture; tex != nil && tex.Index >= 0 && tex.Index < len(me.doc.Textures) {
+		if src := me.doc.Textures[tex.Index].Source; src != nil && *src >= 0 && *src < len(me.imageIDs) {
+			fx.FxProcs.EnableTex2D(0).Tex_SetImageID(me.imageIDs[*src])
+		}
+	}
+	if len(fx.FxProcs) == 0 {
+		if rgba := mat.Pbr.BaseColorFactor; len(rgba) >= 3 {
+			fx.FxProcs.EnableColor(0).Color_SetRgb(gl.Float(rgba[0]), gl.Float(rgba[1]), gl.Float(rgba[2]))
+		} else {
+			//	the glTF default base color
+			fx.FxProcs.EnableColor(0).Color_SetRgb(1, 1, 1)
+		}
+	}
+	fx.UpdateRoutine()
+	return
+}
+
+//	Returns an FxMaterial applying the effects of the specified glTF materials (-1 for none) to the faces
+//	tagged with them. Meshes using the same set of glTF materials share their FxMaterial.
+func (me *gltfImporter) bindMaterials(materials []int) (matID int) {
+	var keys []string
+	used := map[int]bool{}
+	for _, m := range materials {
+		if !used[m] {
+			used[m], keys = true, append(keys, strf("%d", m))
+		}
+	}
+	sort.Strings(keys)
+	key := strings.Join(keys, ";")
+	if matID, ok := me.matIDs[key]; ok {
+		return matID
+	}
+	matID = Core.Libs.Materials.AddNew()
+	me.result.MaterialIDs, me.matIDs[key] = append(me.result.MaterialIDs, matID), matID
+	mat := &Core.Libs.Materials[matID]
+	for _, m := range materials {
+		fxID := me.fallbackEffect()
+		if m >= 0 {
+			fxID = me.effectIDs[m]
+		}
+		if mat.DefaultEffectID < 0 {
+			mat.DefaultEffectID = fxID
+		}
+		if len(used) > 1 && m >= 0 {
+			mat.FaceEffects.ByTag[gltfMaterialTag(m)] = fxID
+		}
+	}
+	if mat.DefaultEffectID < 0 {
+		mat.DefaultEffectID = me.fallbackEffect()
+	}
+	return
+}
+
+//	Returns the effect for primitives without a material, creating it on first use.
+func (me *gltfImporter) fallbackEffect() int {
+	if me.fallbackFxID < 0 {
+		me.fallbackFxID = me.createEffect(&gltfMaterial{})
+	}
+	return me.fallbackFxID
+}
+
+//	Instantiates the asset's scene below a new child node of parentNodeID.
+func (me *gltfImporter) createScene(parentNodeID int) (err error) {
+	var roots []int
+	name := "gltf"
+	if len(me.doc.Scenes) > 0 {
+		si := 0
+		if me.doc.Scene != nil {
+			si = *me.doc.Scene
+		}
+		if si < 0 || si >= len(me.doc.Scenes) {
+			return errf("invalid scene %v", si)
+		}
+		if roots = me.doc.Scenes[si].Nodes; len(me.doc.Scenes[si].Name) > 0 {
+			name = me.doc.Scenes[si].Name
+		}
+	} else {
+		//	no scene: use all nodes that aren't children
+		isChild := make([]bool, len(me.doc.Nodes))
+		for _, node := range me.doc.Nodes {
+			for _, c := range node.Children {
+				if c >= 0 && c < len(isChild) {
+					isChild[c] = true
+				}
+			}
+		}
+		for i := range me.doc.Nodes {
+			if !isChild[i] {
+				roots = append(roots, i)
+			}
+		}
+	}
+	rootID := me.scene.AddNewChildNode(parentNodeID, -1)
+	me.result.RootNodeID, me.scene.allNodes[rootID].Name = rootID, name
+	me.nodeIDs = make([]int, len(me.doc.Nodes))
+	for i := range me.nodeIDs {
+		me.nodeIDs[i] = -1
+	}
+	for i := 0; i < len(roots) && err == nil; i++ {
+		err = me.createNode(roots[i], rootID)
+	}
+	me.scene.ApplyNodeTransforms(rootID)
+	return
+}
+
+func (me *gltfImporter) createNode(index, parentID int) (err error) {
+	if index < 0 || index >= len(me.doc.Nodes) {
+		return errf("invalid node %v", index)
+	}
+	if me.nodeIDs[index] >= 0 {
+		return errf("node %v has more than one parent", index)
+	}
+	gn, meshID := &me.doc.Nodes[index], -1
+	if gn.Mesh != nil && *gn.Mesh >= 0 && *gn.Mesh < len(me.meshIDs) {
+		meshID = me.meshIDs[*gn.Mesh]
+	}
+	nodeID := me.scene.AddNewChildNode(parentID, meshID)
+	me.nodeIDs[index] = nodeID
+	node := &me.scene.allNodes[nodeID]
+	node.Name = me.nodeNames[index]
+	var mat unum.Mat4
+	if len(gn.Matrix) == 16 {
+		copy(mat[:], gn.Matrix)
+		if !node.Transform.setFromMatrix(&mat) {
+			Diag.LogMisc("Import of '%v': the shearing in the transformation of node '%v' is lost", me.filePath, node.Name)
+		}
+	} else {
+		if len(gn.Translation) == 3 {
+			node.Transform.Pos.Set(gn.Translation[0], gn.Translation[1], gn.Translation[2])
+		}
+		if len(gn.Rotation) == 4 {
+			var q [4]float64
+			copy(q[:], gn.Rotation)
+			quatToEuler(&q, &node.Transform.Rot)
+		}
+		if len(gn.Scale) == 3 {
+			node.Transform.Scale.Set(gn.Scale[0], gn.Scale[1], gn.Scale[2])
+		}
+	}
+	if meshID > -1 {
+		node.SetModelID(me.modelIDs[*gn.Mesh])
+		if node.Render.MorphWeights = gn.Weights; len(gn.Weights) == 0 {
+			node.Render.MorphWeights = me.doc.Meshes[*gn.Mesh].Weights
+		}
+		node.Render.MorphWeights = append([]float64(nil), node.Render.MorphWeights...)
+		if gn.Skin != nil && Core.Libs.Meshes[meshID].Skinned() {
+			//	joints are looked up by their (unique) names anywhere in the imported hierarchy
+			node.Render.Skeleton = me.scene.allNodes.Handle(me.result.RootNodeID)
+		}
+	}
+	if gn.Camera != nil && *gn.Camera >= 0 && *gn.Camera < len(me.doc.Cameras) {
+		cam := &me.doc.Cameras[*gn.Camera]
+		ic := ImportCamera{Name: cam.Name, NodeID: nodeID}
+		if len(ic.Name) == 0 {
+			ic.Name = node.Name
+		}
+		if p := cam.Perspective; p != nil {
+			ic.Perspective.Enabled, ic.Perspective.FovY.Deg, ic.Perspective.ZNear, ic.Perspective.ZFar = true, unum.RadToDeg(p.Yfov), p.Znear, p.Zfar
+			if p.Zfar <= 0 {
+				//	an infinite projection
+				ic.Perspective.ZFar = Options.Cameras.PerspectiveDefaults.ZFar
+			}
+		}
+		me.result.Cameras = append(me.result.Cameras, ic)
+	}
+	for i := 0; i < len(gn.Children) && err == nil; i++ {
+		err = me.createNode(gn.Children[i], nodeID)
+	}
+	return
+}
+
+//	Creates an AnimClip for each glTF animation.
+func (me *gltfImporter) createAnimClips() (err error) {
+	for ai := 0; ai < len(me.doc.Animations) && err == nil; ai++ {
+		anim := &me.doc.Animations[ai]
+		var tracks []AnimTrack
+		for ci := 0; ci < len(anim.Channels) && err == nil; ci++ {
+			var more []AnimTrack
+			if more, err = me.animTracks(anim, ci); err != nil {
+				err = errf("animation %v: %v", ai, err)
+			} else {
+				tracks = append(tracks, more...)
+			}
+		}
+		if err == nil && len(tracks) > 0 {
+			clipID := Core.Libs.AnimClips.AddNew()
+			clip := &Core.Libs.AnimClips[clipID]
+			if clip.Name, clip.Tracks = anim.Name, tracks; len(clip.Name) == 0 {
+				clip.Name = strf("animation%d", ai)
+			}
+			me.result.AnimClipIDs = append(me.result.AnimClipIDs, clipID)
+		}
+	}
+	return
+}
+
+//	Converts the specified channel of anim into AnimTracks.
+func (me *gltfImporter) animTracks(anim *gltfAnimation, channel int) (tracks []AnimTrack, err error) {
+	var (
+		times, vals []float64
+		numComps    int
+	)
+	ch := &anim.Channels[channel]
+	if ch.Target.Node == nil || *ch.Target.Node < 0 || *ch.Target.Node >= len(me.doc.Nodes) || ch.Sampler < 0 || ch.Sampler >= len(anim.Samplers) {
+		//	targets of extensions, or invalid
+		return
+	}
+	sampler, nodeIndex := &anim.Samplers[ch.Sampler], *ch.Target.Node
+	if times, _, err = me.accessor(sampler.Input); err == nil {
+		vals, numComps, err = me.accessor(sampler.Output)
+	}
+	if err != nil || len(times) == 0 {
+		return
+	}
+	interp, elems := AnimInterpLinear, 1
+	switch sampler.Interpolation {
+	case "STEP":
+		interp = AnimInterpStep
+	case "CUBICSPLINE":
+		//	each key has an in-tangent, a value and an out-tangent
+		interp, elems = AnimInterpHermite, 3
+	}
+	//	the number of values per key (more than 1 only for morph weights)
+	perKey := len(vals) / (numComps * len(times) * elems)
+	if perKey == 0 || len(vals) != perKey*numComps*len(times)*elems {
+		err = errf("channel %v has %v output values for %v keys", channel, len(vals), len(times))
+		return
+	}
+	//	Returns the values of the specified key: its in-tangent (elem 0), value (1) or out-tangent (2) for CUBICSPLINE.
+	elem := func(key, elem, i int) []float64 {
+		at := ((key*elems+elem)*perKey + i) * numComps
+		return vals[at : at+numComps]
+	}
+	value := func(key, i int) []float64 {
+		return elem(key, elems/2, i)
+	}
+	//	Sets the Hermite tangents of the k-th key of track from the i-th value of each CUBICSPLINE key.
+	tangents := func(track *AnimTrack, k, i int) {
+		if interp == AnimInterpHermite {
+			in, out := elem(k, 0, i), elem(k, 2, i)
+			if numComps >= 3 {
+				track.Keys[k].InTangent.Set(in[0], in[1], in[2])
+				track.Keys[k].OutTangent.Set(out[0], out[1], out[2])
+			} else {
+				track.Keys[k].InTangent.X, track.Keys[k].OutTangent.X = in[0], out[0]
+			}
+		}
+	}
+	newTrack := func(target AnimTarget) AnimTrack {
+		return AnimTrack{NodeName: me.nodeNames[nodeIndex], Target: target, Interp: interp, Keys: make([]AnimKey, len(times))}
+	}
+	switch ch.Target.Path {
+	case "translation", "scale":
+		if numComps != 3 {
+			err = errf("channel %v is not VEC3", channel)
+			return
+		}
+		track := newTrack(AnimTargetPos)
+		if ch.Target.Path == "scale" {
+			track.Target = AnimTargetScale
+		}
+		for k := range times {
+			v := value(k, 0)
+			track.Keys[k].Time = times[k]
+			track.Keys[k].Value.Set(v[0], v[1], v[2])
+			tangents(&track, k, 0)
+		}
+		tracks = append(tracks, track)
+	case "rotation":
+		if numComps != 4 {
+			err = errf("channel %v is not VEC4", channel)
+			return
+		}
+		track := newTrack(AnimTargetRot)
+		for k := range times {
+			track.Keys[k].Time = times[k]
+			copy(track.Keys[k].Rot[:], value(k, 0))
+		}
+		tracks = append(tracks, track)
+	case "weights":
+		var morphs []MeshMorphTarget
+		if mesh := me.doc.Nodes[nodeIndex].Mesh; mesh != nil && *mesh >= 0 && *mesh < len(me.meshData) {
+			morphs = me.meshData[*mesh].morphs
+		}
+		for i := 0; i < perKey && i < len(morphs); i++ {
+			track := newTrack(AnimTargetMorphWeight)
+			track.MorphTarget = morphs[i].Name
+			for k := range times {
+				track.Keys[k].Time, track.Keys[k].Value.X = times[k], value(k, i)[0]
+				tangents(&track, k, i)
+			}
+			tracks = append(tracks, track)
+		}
+	}
+	return
+}
@@ -0,0 +1,388 @@
+package core
+
+import (
+	"bufio"
+	"io"
+	"math"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/metaleap/go-util-num"
+	u3d "github.com/metaleap/go-util-3d"
+	gl "github.com/metaleap/go-opengl/core"
+)
+
+//	Returns a provider of the mesh described by the Wavefront OBJ file at filePath.
+//
+//	Polygons are triangulated, and all faces following a "usemtl" statement are tagged with
+//	its material name, so that FxMaterial.FaceEffects.ByTag can apply the effects returned by
+//	Core.Libs.Effects.AddNewFromMtl(). Vertices without normals get normals computed from the
+//	faces of their smoothing group (or per face, if none), vertices without texcoords get (0, 0).
+func (_ MeshLib) MeshObj(filePath string) u3d.MeshProvider {
+	return func() (md *u3d.MeshDescriptor, err error) {
+		md, _, _, err = importObjFile(filePath)
+		return
+	}
+}
+
+//	Creates one FxEffect for each material in the Wavefront MTL file at filePath, with a Tex2D
+//	proc for its diffuse map ("map_Kd") or else a Color proc for its diffuse color ("Kd").
+//	The new FxImage2Ds are only loaded and uploaded by a subsequent call to Core.GpuSyncImageLibs().
+//
+//	Returns the new effect IDs by material name.
+func (_ FxEffectLib) AddNewFromMtl(filePath string) (fxIDs map[string]int, err error) {
+	fxIDs = map[string]int{}
+	err = importMtlFile(filePath, fxIDs, map[string]int{}, &ImportResult{})
+	return
+}
+
+//	Imports the Wavefront OBJ file at filePath (and all MTL files it refers to) into me,
+//	as a single new node below parentNodeID. Its FxMaterial applies the effect of each
+//	"usemtl" material to the faces following it.
+func (me *Scene) ImportObj(filePath string, parentNodeID int, opt *ImportOptions) (result *ImportResult, err error) {
+	var (
+		md      *u3d.MeshDescriptor
+		raw     *meshRaw
+		extra   [][]string
+		mtlLibs []string
+		usedMtl []string
+		srcItem string
+	)
+	if !me.allNodes.IsOk(parentNodeID) {
+		err = errf("Cannot import '%v': invalid parent node ID %v", filePath, parentNodeID)
+		return
+	}
+	if opt != nil {
+		srcItem = opt.meshSrcItem("")
+	}
+	//	on a disk cache hit, the OBJ file needn't be parsed at all: its MTL files and materials are cached along with its mesh
+	cacheKey := newMeshCacheKey(filePath, srcItem)
+	if raw, extra = cacheKey.load(); raw != nil && len(extra) == 2 {
+		mtlLibs, usedMtl = extra[0], extra[1]
+	} else {
+		raw = nil
+		if md, mtlLibs, usedMtl, err = importObjFile(filePath); err != nil {
+			return
+		}
+	}
+	res, fxIDs, imgIDs := &ImportResult{RootNodeID: -1}, map[string]int{}, map[string]int{}
+	for _, mtlLib := range mtlLibs {
+		if err = importMtlFile(importImageUrl(filePath, mtlLib), fxIDs, imgIDs, res); err != nil {
+			res.remove()
+			return
+		}
+	}
+	meshID := Core.Libs.Meshes.AddNew()
+	mesh := &Core.Libs.Meshes[meshID]
+	res.MeshIDs = append(res.MeshIDs, meshID)
+	if mesh.Name = strings.TrimSuffix(filepath.Base(filePath), filepath.Ext(filePath)); raw != nil {
+		mesh.loadRaw(raw)
+	} else {
+		provider := u3d.MeshProvider(func() (*u3d.MeshDescriptor, error) { return md, nil })
+		if opt != nil {
+			provider, _ = opt.meshProvider(md, "")
+		}
+		if err = mesh.loadAndCache(cacheKey, provider, [][]string{mtlLibs, usedMtl}); err != nil {
+			res.remove()
+			err = errf("Cannot import '%v': mesh failed to load: %v", filePath, err)
+			return
+		}
+	}
+	if opt != nil && opt.MeshBuffer != nil {
+		if err = opt.MeshBuffer.Add(meshID); err != nil {
+			res.remove()
+			return
+		}
+	}
+	if opt != nil && len(opt.Lods) > 0 {
+		res.addLods(opt, []int{meshID})
+	}
+	matID := Core.Libs.Materials.AddNew()
+	res.MaterialIDs = append(res.MaterialIDs, matID)
+	mat := &Core.Libs.Materials[matID]
+	for _, name := range usedMtl {
+		if fxID, ok := fxIDs[name]; ok {
+			if mat.DefaultEffectID < 0 {
+				mat.DefaultEffectID = fxID
+			}
+			mat.FaceEffects.ByTag[name] = fxID
+		}
+	}
+	if mat.DefaultEffectID < 0 {
+		fxID := Core.Libs.Effects.AddNew()
+		res.EffectIDs = append(res.EffectIDs, fxID)
+		Core.Libs.Effects[fxID].FxProcs.EnableColor(0).Color_SetRgb(0.8, 0.8, 0.8)
+		Core.Libs.Effects[fxID].UpdateRoutine()
+		mat.DefaultEffectID = fxID
+	}
+	if len(mat.FaceEffects.ByTag) < 2 {
+		mat.FaceEffects.ByTag = map[string]int{}
+	}
+	res.RootNodeID = me.AddNewChildNode(parentNodeID, meshID)
+	node := &me.allNodes[res.RootNodeID]
+	node.Name = Core.Libs.Meshes[meshID].Name
+	node.SetMatID(matID)
+	result = res
+	return
+}
+
+//	Parses the OBJ file at filePath, returning its mesh, the "mtllib" files it refers to
+//	and the names of all materials it uses, in order of first use.
+func importObjFile(filePath string) (md *u3d.MeshDescriptor, mtlLibs, usedMtl []string, err error) {
+	var rc io.ReadCloser
+	if rc, err = Core.fileIO.openLocalFile(filePath); err != nil {
+		return
+	}
+	defer rc.Close()
+	if md, mtlLibs, usedMtl, err = importObj(rc); err != nil {
+		err = errf("Cannot import '%v': %v", filePath, err)
+	}
+	return
+}
+
+func importObj(r io.Reader) (md *u3d.MeshDescriptor, mtlLibs, usedMtl []string, err error) {
+	type smoothKey struct {
+		group string
+		pos   uint32
+	}
+	var (
+		vals      []float64
+		tags      []string
+		poly      []u3d.MeshDescF3V
+		lineNo    int
+		line      string
+		smooth    string
+		smoothFcs []string
+		noTex     = -1
+		used      = map[string]bool{}
+		scanner   = bufio.NewScanner(r)
+	)
+	md = &u3d.MeshDescriptor{}
+	//	resolves the 1-based (or, if negative, relative) OBJ index s into a 0-based one for a list of length num
+	index := func(s string, num int) (uint32, error) {
+		i, err := strconv.Atoi(s)
+		if err == nil {
+			if i < 0 {
+				i += num
+			} else {
+				i--
+			}
+			if i < 0 || i >= num {
+				err = errf("index %v out of range", s)
+			}
+		}
+		return uint32(i), err
+	}
+	for scanner.Scan() {
+		lineNo++
+		if line = strings.TrimSpace(scanner.Text()); strings.HasSuffix(line, "\\") {
+			for line = strings.TrimSuffix(line, "\\"); scanner.Scan(); {
+				lineNo++
+				next := strings.TrimSpace(scanner.Text())
+				if line += " " + strings.TrimSuffix(next, "\\"); !strings.HasSuffix(next, "\\") {
+					break
+				}
+			}
+		}
+		if i := strings.Index(line, "#"); i >= 0 {
+			line = line[:i]
+		}
+		fields := strings.Fields(line)
+		if len(fields) == 0 {
+			continue
+		}
+		switch args := fields[1:]; fields[0] {
+		case "v", "vn", "vt":
+			if vals, err = importParseFloats(strings.Join(args, " ")); err == nil {
+				if fields[0] == "vt" {
+					//	the v coordinate (and the w coordinate, which is ignored) is optional
+					vals = append(vals, 0, 0)
+					md.TexCoords = append(md.TexCoords, u3d.MeshDescVA2{float32(vals[0]), float32(vals[1])})
+				} else if len(vals) < 3 {
+					err = errf("too few coordinates")
+				} else if fields[0] == "v" {
+					md.Positions = append(md.Positions, u3d.MeshDescVA3{float32(vals[0]), float32(vals[1]), float32(vals[2])})
+				} else {
+					md.Normals = append(md.Normals, u3d.MeshDescVA3{float32(vals[0]), float32(vals[1]), float32(vals[2])})
+				}
+			}
+		case "f":
+			poly = poly[:0]
+			for _, arg := range args {
+				fv, refs := u3d.MeshDescF3V{NormalIndex: math.MaxUint32}, strings.Split(arg, "/")
+				if fv.PosIndex, err = index(refs[0], len(md.Positions)); err == nil && len(refs) > 1 && len(refs[1]) > 0 {
+					fv.TexCoordIndex, err = index(refs[1], len(md.TexCoords))
+				} else if err == nil {
+					if noTex < 0 {
+						noTex, md.TexCoords = len(md.TexCoords), append(md.TexCoords, u3d.MeshDescVA2{0, 0})
+					}
+					fv.TexCoordIndex = uint32(noTex)
+				}
+				if err == nil && len(refs) > 2 && len(refs[2]) > 0 {
+					fv.NormalIndex, err = index(refs[2], len(md.Normals))
+				}
+				if err != nil {
+					break
+				}
+				poly = append(poly, fv)
+			}
+			//	triangulate as a fan
+			for v := 2; v < len(poly) && err == nil; v++ {
+				md.Faces = append(md.Faces, u3d.MeshDescF3{MeshFaceBase: u3d.MeshFaceBase{Tags: tags}, V: [3]u3d.MeshDescF3V{poly[0], poly[v-1], poly[v]}})
+				smoothFcs = append(smoothFcs, smooth)
+			}
+		case "s":
+			if smooth = strings.Join(args, " "); smooth == "off" || smooth == "0" {
+				smooth = ""
+			}
+		case "usemtl":
+			if tags = nil; len(args) > 0 {
+				name := strings.Join(args, " ")
+				if tags = []string{name}; !used[name] {
+					used[name], usedMtl = true, append(usedMtl, name)
+				}
+			}
+		case "mtllib":
+			mtlLibs = append(mtlLibs, args...)
+		}
+		if err != nil {
+			err = errf("line %v: %v", lineNo, err)
+			return
+		}
+	}
+	if err = scanner.Err(); err != nil {
+		return
+	}
+	//	compute missing normals: per face outside of smoothing groups, else averaged over the faces sharing a position in the same group
+	var fn unum.Vec3
+	smoothSums, smoothIndices := map[smoothKey]*unum.Vec3{}, map[smoothKey]uint32{}
+	for pass := 0; pass < 2; pass++ {
+		for fi := 0; fi < len(md.Faces); fi++ {
+			face := &md.Faces[fi]
+			if face.V[0].NormalIndex != math.MaxUint32 && face.V[1].NormalIndex != math.MaxUint32 && face.V[2].NormalIndex != math.MaxUint32 {
+				continue
+			}
+			if len(smoothFcs[fi]) == 0 {
+				if pass == 0 {
+					n := importFlatNormal(md, face.V[0].PosIndex, face.V[1].PosIndex, face.V[2].PosIndex)
+					for v := 0; v < 3; v++ {
+						if face.V[v].NormalIndex == math.MaxUint32 {
+							face.V[v].NormalIndex = n
+						}
+					}
+				}
+				continue
+			}
+			if pass == 0 {
+				//	the unnormalized cross product weighs each face by its area
+				var pa, pb, pc unum.Vec3
+				md.Positions[face.V[0].PosIndex].ToVec3(&pa)
+				md.Positions[face.V[1].PosIndex].ToVec3(&pb)
+				md.Positions[face.V[2].PosIndex].ToVec3(&pc)
+				e1, e2 := unum.Vec3{pb.X - pa.X, pb.Y - pa.Y, pb.Z - pa.Z}, unum.Vec3{pc.X - pa.X, pc.Y - pa.Y, pc.Z - pa.Z}
+				fn.SetFromCrossOf(&e1, &e2)
+				for v := 0; v < 3; v++ {
+					key := smoothKey{smoothFcs[fi], face.V[v].PosIndex}
+					if smoothSums[key] == nil {
+						smoothSums[key] = &unum.Vec3{}
+					}
+					smoothSums[key].Add(&fn)
+				}
+			} else {
+				for v := 0; v < 3; v++ {
+					if face.V[v].NormalIndex == math.MaxUint32 {
+						key := smoothKey{smoothFcs[fi], face.V[v].PosIndex}
+						n, ok := smoothIndices[key]
+						if !ok {
+							fn = *smoothSums[key]
+							fn.Normalize()
+							n, md.Normals = uint32(len(md.Normals)), append(md.Normals, u3d.MeshDescVA3{float32(fn.X), float32(fn.Y), float32(fn.Z)})
+							smoothIndices[key] = n
+						}
+						face.V[v].NormalIndex = n
+					}
+				}
+			}
+		}
+	}
+	return
+}
+
+//	Parses the MTL file at filePath, adding an FxEffect for each of its materials to fxIDs (by material name)
+//	and to result. Diffuse maps are added as FxImage2Ds to imgIDs (by resolved RefUrl) unless already present.
+func importMtlFile(filePath string, fxIDs, imgIDs map[string]int, result *ImportResult) (err error) {
+	type mtl struct {
+		name  string
+		kd    []float64
+		mapKd string
+	}
+	var (
+		rc      io.ReadCloser
+		mtls    []*mtl
+		cur     *mtl
+		lineNo  int
+		scanner *bufio.Scanner
+	)
+	if rc, err = Core.fileIO.openLocalFile(filePath); err != nil {
+		return
+	}
+	defer rc.Close()
+	for scanner = bufio.NewScanner(rc); scanner.Scan() && err == nil; {
+		lineNo++
+		line := scanner.Text()
+		if i := strings.Index(line, "#"); i >= 0 {
+			line = line[:i]
+		}
+		fields := strings.Fields(line)
+		if len(fields) < 2 {
+			continue
+		}
+		switch fields[0] {
+		case "newmtl":
+			cur = &mtl{name: strings.Join(fields[1:], " ")}
+			mtls = append(mtls, cur)
+		case "Kd":
+			if cur != nil {
+				if cur.kd, err = importParseFloats(strings.Join(fields[1:], " ")); err == nil && len(cur.kd) < 3 {
+					err = errf("too few color components")
+				}
+			}
+		case "map_Kd":
+			//	any options precede the file name
+			if cur != nil {
+				cur.mapKd = fields[len(fields)-1]
+			}
+		}
+		if err != nil {
+			err = errf("Cannot import '%v': line %v: %v", filePath, lineNo, err)
+		}
+	}
+	if err == nil {
+		err = scanner.Err()
+	}
+	if err != nil {
+		return
+	}
+	for _, m := range mtls {
+		fxID := Core.Libs.Effects.AddNew()
+		fx := &Core.Libs.Effects[fxID]
+		if len(m.mapKd) > 0 {
+			url := importImageUrl(filePath, m.mapKd)
+			imgID, ok := imgIDs[url]
+			if !ok {
+				imgID = Core.Libs.Images.Tex2D.AddNew()
+				Core.Libs.Images.Tex2D[imgID].InitFrom.RefUrl = url
+				imgIDs[url], result.ImageIDs = imgID, append(result.ImageIDs, imgID)
+			}
+			fx.FxProcs.EnableTex2D(0).Tex_SetImageID(imgID)
+		} else if len(m.kd) >= 3 {
+			fx.FxProcs.EnableColor(0).Color_SetRgb(gl.Float(m.kd[0]), gl.Float(m.kd[1]), gl.Float(m.kd[2]))
+		} else {
+			fx.FxProcs.EnableColor(0).Color_SetRgb(0.8, 0.8, 0.8)
+		}
+		fx.UpdateRoutine()
+		fxIDs[m.name], result.EffectIDs = fxID, append(result.EffectIDs, fxID)
+	}
+	return
+}
@@ -0,0 +1,153 @@
+package core
+
+import (
+	"io/ioutil"
+	"math"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	u3d "github.com/metaleap/go-util-3d"
+)
+
+func TestImportObj(t *testing.T) {
+	const tri = "v 0 0 0\nv 1 0 0\nv 0 1 0\n"
+	for _, test := range []struct {
+		name, src string
+
+		//	The position indices of each face, or the expected error message (without the line number)
+		faces   [][3]uint32
+		errLine int
+		err     string
+	}{
+		{name: "positive indices", src: tri + "f 1 2 3\n",
+			faces: [][3]uint32{{0, 1, 2}}},
+		{name: "negative indices", src: tri + "f -3 -2 -1\n",
+			faces: [][3]uint32{{0, 1, 2}}},
+		{name: "negative indices are relative to the current line", src: tri + "f -3 -2 -1\nv 1 1 0\nf -4 -2 -1\n",
+			faces: [][3]uint32{{0, 1, 2}, {0, 2, 3}}},
+		{name: "negative tex-coord and normal indices", src: tri + "vt 0 0\nvt 1 0\nvt 0 1\nvn 0 0 1\nf -3/-3/-1 -2/-2/-1 -1/-1/-1\n",
+			faces: [][3]uint32{{0, 1, 2}}},
+		{name: "negative index out of range", src: tri + "f -4 -2 -1\n",
+			errLine: 4, err: "index -4 out of range"},
+		{name: "zero index", src: tri + "f 0 1 2\n",
+			errLine: 4, err: "index 0 out of range"},
+		{name: "polygon as fan", src: tri + "v 1 1 0\nv 0.5 2 0\nf 1 2 4 5 3\n",
+			faces: [][3]uint32{{0, 1, 3}, {0, 3, 4}, {0, 4, 2}}},
+		{name: "comments", src: "# a triangle\n" + tri + "f 1 2 3 # the only face\n#f 3 2 1\n",
+			faces: [][3]uint32{{0, 1, 2}}},
+		{name: "line continuation", src: "v 0 \\\n 0 0\nv 1 0 0\nv 0 1 \\\n\t0\nf 1 \\\n2 \\\n3\n",
+			faces: [][3]uint32{{0, 1, 2}}},
+		{name: "line numbers count continued lines", src: "v 0 0 \\\n0\nf 1 \\\n 1 \\\n 2\n",
+			errLine: 5, err: "index 2 out of range"},
+		{name: "too few coordinates", src: "v 0 0 0\nv 1 0\n",
+			errLine: 2, err: "too few coordinates"},
+	} {
+		md, _, _, err := importObj(strings.NewReader(test.src))
+		if test.err != "" {
+			if want := strf("line %v: %v", test.errLine, test.err); err == nil || err.Error() != want {
+				t.Errorf("%v: got error %v, want %v", test.name, err, want)
+			}
+			continue
+		} else if err != nil {
+			t.Errorf("%v: %v", test.name, err)
+			continue
+		}
+		if len(md.Faces) != len(test.faces) {
+			t.Errorf("%v: got %v faces, want %v", test.name, len(md.Faces), len(test.faces))
+			continue
+		}
+		for fi, face := range md.Faces {
+			if got := [3]uint32{face.V[0].PosIndex, face.V[1].PosIndex, face.V[2].PosIndex}; got != test.faces[fi] {
+				t.Errorf("%v: face %v has positions %v, want %v", test.name, fi, got, test.faces[fi])
+			}
+			if meshBadFace(md, &face) {
+				t.Errorf("%v: face %v refers to missing vertex data: %v", test.name, fi, face.V)
+			}
+		}
+	}
+}
+
+func TestImportObjSmoothing(t *testing.T) {
+	//	two faces folded along their shared edge from position 1 to 2: the first facing +Z, the second +Y+Z
+	const fold = "v 0 0 0\nv 1 0 0\nv 0 1 0\nv 0 -1 1\n"
+	up, slope, avg := [3]float64{0, 0, 1}, [3]float64{0, math.Sqrt(0.5), math.Sqrt(0.5)}, [3]float64{0, 1 / math.Sqrt(5), 2 / math.Sqrt(5)}
+	for _, test := range []struct {
+		name, src string
+
+		//	The normal of each corner of both faces
+		normals [2][3][3]float64
+	}{
+		{"no smoothing group", fold + "f 1 2 3\nf 2 1 4\n",
+			[2][3][3]float64{{up, up, up}, {slope, slope, slope}}},
+		{"smoothing off", fold + "s off\nf 1 2 3\ns 0\nf 2 1 4\n",
+			[2][3][3]float64{{up, up, up}, {slope, slope, slope}}},
+		{"one smoothing group", fold + "s 1\nf 1 2 3\nf 2 1 4\n",
+			[2][3][3]float64{{avg, avg, up}, {avg, avg, slope}}},
+		{"separate smoothing groups", fold + "s 1\nf 1 2 3\ns 2\nf 2 1 4\n",
+			[2][3][3]float64{{up, up, up}, {slope, slope, slope}}},
+		{"explicit normals win", fold + "vn 1 0 0\ns 1\nf 1//1 2 3\nf 2 1//1 4\n",
+			[2][3][3]float64{{{1, 0, 0}, avg, up}, {avg, {1, 0, 0}, slope}}},
+	} {
+		md, _, _, err := importObj(strings.NewReader(test.src))
+		if err != nil {
+			t.Errorf("%v: %v", test.name, err)
+			continue
+		} else if len(md.Faces) != 2 {
+			t.Errorf("%v: got %v faces, want 2", test.name, len(md.Faces))
+			continue
+		}
+		for fi := 0; fi < 2; fi++ {
+			for v := 0; v < 3; v++ {
+				if ni := md.Faces[fi].V[v].NormalIndex; int(ni) >= len(md.Normals) {
+					t.Errorf("%v: corner %v of face %v has no normal", test.name, v, fi)
+				} else if n, want := md.Normals[ni], test.normals[fi][v]; !importTestNear(n, want) {
+					t.Errorf("%v: corner %v of face %v has normal %v, want %v", test.name, v, fi, n, want)
+				}
+			}
+		}
+	}
+}
+
+func TestImportObjCleanup(t *testing.T) {
+	dir, err := ioutil.TempDir("", "importobj")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	for name, src := range map[string]string{
+		"good.mtl": "newmtl red\nKd 1 0 0\nnewmtl tex\nmap_Kd tex.png\n",
+		"bad.mtl":  "newmtl blue\nKd 0 0\n",
+		"tri.obj":  "mtllib good.mtl\nmtllib bad.mtl\nv 0 0 0\nv 1 0 0\nv 0 1 0\nusemtl red\nf 1 2 3\n",
+	} {
+		if err = ioutil.WriteFile(filepath.Join(dir, name), []byte(src), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	libs := &Core.Libs
+	count := func() [4]int {
+		var n [4]int
+		libs.Effects.Walk(func(*FxEffect) { n[0]++ })
+		libs.Images.Tex2D.Walk(func(*FxImage2D) { n[1]++ })
+		libs.Materials.Walk(func(*FxMaterial) { n[2]++ })
+		libs.Meshes.Walk(func(*Mesh) { n[3]++ })
+		return n
+	}
+	scene := sceneTestNew()
+	before := count()
+	//	the effects and images of good.mtl are already added once bad.mtl fails to parse
+	if _, err = scene.ImportObj(filepath.Join(dir, "tri.obj"), 0, nil); err == nil || !strings.Contains(err.Error(), "bad.mtl") {
+		t.Errorf("got error %v, want one for bad.mtl", err)
+	}
+	if after := count(); after != before {
+		t.Errorf("got %v effects, images, materials and meshes after the failed import, want %v", after, before)
+	}
+	if len(scene.allNodes[0].childNodeIDs) != 0 {
+		t.Errorf("failed import added nodes %v", scene.allNodes[0].childNodeIDs)
+	}
+}
+
+func importTestNear(n u3d.MeshDescVA3, want [3]float64) bool {
+	return math.Abs(float64(n[0])-want[0]) < 1e-6 && math.Abs(float64(n[1])-want[1]) < 1e-6 && math.Abs(float64(n[2])-want[2]) < 1e-6
+}
@@ -0,0 +1,237 @@
+package core
+
+import (
+	"math"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/metaleap/go-util-num"
+	u3d "github.com/metaleap/go-util-3d"
+)
+
+//	Controls how an importer such as Scene.ImportCollada() creates lib entries.
+type ImportOptions struct {
+	//	If set, all imported meshes are added to this buffer, ready for Core.Libs.Meshes.GpuSync().
+	//	Otherwise, they are left for the app to add to a MeshBuffer of its choice.
+	MeshBuffer *MeshBuffer
+
+	//	If set, each imported mesh gets a chain of simplified meshes, one per entry, ordered from the most to the least
+	//	detailed. They are added to MeshBuffer (if any) and listed in the mesh's Model.Lod (see Mesh.DefaultModelID).
+	Lods []ImportLod
+
+	//	If set, each imported mesh is fixed via Core.Libs.Meshes.Repair() before loading, and its problems are logged
+	//	via Diag.LogMeshes(). For glTF meshes with morph targets, the normals are kept regardless of Repair.Normals.
+	Repair *MeshRepairParams
+}
+
+//	A level of detail generated for each imported mesh, see ImportOptions.Lods.
+type ImportLod struct {
+	//	How to simplify the imported mesh (not the previous level) for this level.
+	MeshSimplifyParams
+
+	//	Once a node's bounding sphere covers less than this fraction of the viewport height,
+	//	this level replaces the previous one (or the imported mesh itself, for the first level).
+	ScreenSize float64
+}
+
+//	Describes everything created by an importer such as Scene.ImportCollada().
+//	Imported images are only loaded and uploaded by a subsequent call to Core.GpuSyncImageLibs().
+type ImportResult struct {
+	//	The node that all imported nodes were added to.
+	RootNodeID int
+
+	//	IDs of the new entries in Core.Libs.
+	AnimClipIDs, EffectIDs, ImageIDs, MaterialIDs, MeshIDs, ModelIDs []int
+
+	//	The cameras placed in the imported scene.
+	Cameras []ImportCamera
+}
+
+//	A camera placed in an imported scene. Since a Camera belongs to a RenderView rather than to a Scene,
+//	the imported camera is merely recorded (with a node of its own for its placement) and applied via ApplyTo().
+type ImportCamera struct {
+	Name string
+
+	//	The node positioning this camera. As in most formats, it looks along the node's local -Z axis.
+	NodeID int
+
+	//	Only Enabled if the imported camera is a perspective one.
+	Perspective u3d.Perspective
+}
+
+//	Sets cam.Perspective (if Enabled in me) and positions and orients cam.Controller like me,
+//	as per the current world transformation of me.NodeID in scene.
+func (me *ImportCamera) ApplyTo(cam *Camera, scene *Scene) {
+	if me.Perspective.Enabled {
+		cam.Perspective.FovY.Deg, cam.Perspective.ZNear, cam.Perspective.ZFar = me.Perspective.FovY.Deg, me.Perspective.ZNear, me.Perspective.ZFar
+		cam.applyPerspective()
+	}
+	if scene.allNodes.IsOk(me.NodeID) {
+		var mat unum.Mat4
+		scene.nodeWorldMatrix(me.NodeID, &mat)
+		ctl := &cam.Controller
+		ctl.BeginUpdate()
+		ctl.Pos.Set(mat[12], mat[13], mat[14])
+		//	a Controller looks along the negated Dir(), just like the node along its negated local Z axis
+		ctl.SetDir(&unum.Vec3{mat[8], mat[9], mat[10]})
+		ctl.EndUpdate()
+	}
+}
+
+//	Returns the Mesh.LoadCached() srcItem for item, which also depends on me.Repair (if set).
+func (me *ImportOptions) meshSrcItem(item string) string {
+	if me.Repair != nil {
+		return strf("%s_%v", item, *me.Repair)
+	}
+	return item
+}
+
+//	Returns a provider of md, as fixed by me.Repair (if set), along with the Mesh.LoadCached() srcItem for item.
+func (me *ImportOptions) meshProvider(md *u3d.MeshDescriptor, item string) (provider u3d.MeshProvider, srcItem string) {
+	if srcItem, provider = me.meshSrcItem(item), func() (*u3d.MeshDescriptor, error) { return md, nil }; me.Repair != nil {
+		//	repairs only once, even if the provider is called again (such as after a failed Mesh.LoadSkinned())
+		repair, repaired := me.Repair, false
+		provider = func() (*u3d.MeshDescriptor, error) {
+			if !repaired {
+				issues := Core.Libs.Meshes.Repair(md, repair)
+				repaired = true
+				Diag.LogMeshes("Import repair found %v", &issues)
+			}
+			return md, nil
+		}
+	}
+	return
+}
+
+//	Removes all the new entries in Core.Libs listed in me, for an import that failed after adding some of them.
+func (me *ImportResult) remove() {
+	for _, id := range me.ModelIDs {
+		Core.Libs.Models.Remove(id, 1)
+	}
+	for _, id := range me.MaterialIDs {
+		Core.Libs.Materials.Remove(id, 1)
+	}
+	for _, id := range me.MeshIDs {
+		Core.Libs.Meshes.Remove(id, 1)
+	}
+	for _, id := range me.EffectIDs {
+		Core.Libs.Effects.Remove(id, 1)
+	}
+	for _, id := range me.ImageIDs {
+		Core.Libs.Images.Tex2D.Remove(id, 1)
+	}
+	for _, id := range me.AnimClipIDs {
+		Core.Libs.AnimClips.Remove(id, 1)
+	}
+	me.AnimClipIDs, me.EffectIDs, me.ImageIDs, me.MaterialIDs, me.MeshIDs, me.ModelIDs = nil, nil, nil, nil, nil, nil
+}
+
+//	Generates the ImportOptions.Lods for each of the specified imported meshes, adding a Model to those without a DefaultModelID.
+func (me *ImportResult) addLods(opt *ImportOptions, meshIDs []int) {
+	for _, meshID := range meshIDs {
+		mesh := Core.Libs.Meshes.get(meshID)
+		if mesh == nil {
+			continue
+		}
+		name, levels := mesh.Name, []LodLevel{{Mesh: Core.Libs.Meshes.Handle(meshID), MinScreenSize: opt.Lods[0].ScreenSize}}
+		for i := 0; i < len(opt.Lods); i++ {
+			lodMeshID, err := Core.Libs.Meshes.AddNewSimplified(strf("%s.lod%d", name, i+1), meshID, &opt.Lods[i].MeshSimplifyParams)
+			if err == nil && opt.MeshBuffer != nil {
+				err = opt.MeshBuffer.Add(lodMeshID)
+			}
+			if err != nil {
+				Diag.LogErr(err)
+				break
+			}
+			me.MeshIDs = append(me.MeshIDs, lodMeshID)
+			levels = append(levels, LodLevel{Mesh: Core.Libs.Meshes.Handle(lodMeshID)})
+			if i+1 < len(opt.Lods) {
+				levels[len(levels)-1].MinScreenSize = opt.Lods[i+1].ScreenSize
+			}
+		}
+		if len(levels) < 2 {
+			continue
+		}
+		levels[len(levels)-1].MinScreenSize = 0
+		mesh = &Core.Libs.Meshes[meshID]
+		model := Core.Libs.Models.get(mesh.DefaultModelID)
+		if model == nil {
+			modelID := Core.Libs.Models.AddNew()
+			model, mesh.DefaultModelID = &Core.Libs.Models[modelID], modelID
+			model.Name, me.ModelIDs = name, append(me.ModelIDs, modelID)
+		}
+		model.Lod.Levels = levels
+	}
+}
+
+//	Returns the image reference refUrl, as found in a file at filePath, such that FxImageInitFrom.RefUrl can load it.
+func importImageUrl(filePath, refUrl string) string {
+	if strings.HasPrefix(refUrl, "file://") {
+		refUrl = strings.TrimPrefix(strings.TrimPrefix(refUrl, "file://"), "localhost")
+	} else if strings.Contains(refUrl, "://") {
+		return refUrl
+	}
+	if refUrl = filepath.FromSlash(refUrl); filepath.IsAbs(refUrl) {
+		return refUrl
+	}
+	return filepath.Join(filepath.Dir(filePath), refUrl)
+}
+
+//	Parses all whitespace-separated numbers in s.
+func importParseFloats(s string) (vals []float64, err error) {
+	fields := strings.Fields(s)
+	vals = make([]float64, len(fields))
+	for i := 0; i < len(fields) && err == nil; i++ {
+		vals[i], err = strconv.ParseFloat(fields[i], 64)
+	}
+	return
+}
+
+//	Parses all whitespace-separated integers in s.
+func importParseInts(s string) (vals []int, err error) {
+	fields := strings.Fields(s)
+	vals = make([]int, len(fields))
+	for i := 0; i < len(fields) && err == nil; i++ {
+		vals[i], err = strconv.Atoi(fields[i])
+	}
+	return
+}
+
+//	Computes a face normal for the triangle at positions a, b and c of md, appends it to md.Normals and returns its index.
+func importFlatNormal(md *u3d.MeshDescriptor, a, b, c uint32) uint32 {
+	var pa, pb, pc, n unum.Vec3
+	md.Positions[a].ToVec3(&pa)
+	md.Positions[b].ToVec3(&pb)
+	md.Positions[c].ToVec3(&pc)
+	e1, e2 := unum.Vec3{pb.X - pa.X, pb.Y - pa.Y, pb.Z - pa.Z}, unum.Vec3{pc.X - pa.X, pc.Y - pa.Y, pc.Z - pa.Z}
+	n.SetFromCrossOf(&e1, &e2)
+	n.Normalize()
+	md.Normals = append(md.Normals, u3d.MeshDescVA3{float32(n.X), float32(n.Y), float32(n.Z)})
+	return uint32(len(md.Normals) - 1)
+}
+
+//	Sets mat to the rotation by deg degrees about axis (which need not be normalized).
+func mat4RotationAxisDeg(mat *unum.Mat4, axis *unum.Vec3, deg float64) {
+	x, y, z := axis.X, axis.Y, axis.Z
+	if l := math.Sqrt(x*x + y*y + z*z); l > 0 {
+		x, y, z = x/l, y/l, z/l
+	}
+	s, c := math.Sincos(deg * math.Pi / 180)
+	t := 1 - c
+	*mat = unum.Mat4{
+		t*x*x + c, t*x*y + s*z, t*x*z - s*y, 0,
+		t*x*y - s*z, t*y*y + c, t*y*z + s*x, 0,
+		t*x*z + s*y, t*y*z - s*x, t*z*z + c, 0,
+		0, 0, 0, 1,
+	}
+}
+
+//	Sets mat from the 16 values of a row-major matrix.
+func mat4FromRowMajor(mat *unum.Mat4, vals []float64) {
+	for r := 0; r < 4; r++ {
+		for c := 0; c < 4; c++ {
+			mat[c*4+r] = vals[r*4+c]
+		}
+	}
+}
@@ -0,0 +1,35 @@
+package core
+
+import (
+	"math"
+	"testing"
+
+	"github.com/metaleap/go-util-num"
+)
+
+func TestImportCameraApplyTo(t *testing.T) {
+	for _, test := range []struct {
+		name      string
+		rot, view unum.Vec3
+	}{
+		{"unrotated", unum.Vec3{}, unum.Vec3{0, 0, -1}},
+		{"turned left", unum.Vec3{0, math.Pi / 2, 0}, unum.Vec3{-1, 0, 0}},
+	} {
+		scene := sceneTestNew()
+		nodeID := scene.AddNewChildNode(0, -1)
+		scene.Node(nodeID).Transform.SetPos(1, 2, 3)
+		scene.Node(nodeID).Transform.Rot = test.rot
+		scene.ApplyNodeTransforms(nodeID)
+		cam := &Camera{}
+		cam.Controller.init()
+		(&ImportCamera{NodeID: nodeID}).ApplyTo(cam, scene)
+		cam.Perspective.Enabled, cam.Perspective.FovY.Deg = true, 45
+		origin, dir := cam.ScreenPointToRay(0.5, 0.5)
+		if origin != (unum.Vec3{1, 2, 3}) {
+			t.Errorf("%v: camera is at %v, want the node's position", test.name, origin)
+		}
+		if math.Abs(dir.X-test.view.X) > 1e-9 || math.Abs(dir.Y-test.view.Y) > 1e-9 || math.Abs(dir.Z-test.view.Z) > 1e-9 {
+			t.Errorf("%v: camera looks along %v, want the node's local -Z axis %v", test.name, dir, test.view)
+		}
+	}
+}
@@ -0,0 +1,108 @@
+package core
+
+//	A contiguous range of vertices or indices inside a MeshBuffer.
+type meshBufferBlock struct {
+	offset, size int32
+}
+
+//	Sub-allocates the vertex (or index) space of a MeshBuffer, in units of vertices (or indices).
+type meshBufferAlloc struct {
+	capacity, used int32
+
+	//	Sorted by offset, never adjacent to one another (they're coalesced on release)
+	free []meshBufferBlock
+}
+
+//	Resets me to the specified capacity, with the first used units in use and the rest free.
+func (me *meshBufferAlloc) reset(capacity, used int32) {
+	me.capacity, me.used, me.free = capacity, used, me.free[:0]
+	if used < capacity {
+		me.free = append(me.free, meshBufferBlock{offset: used, size: capacity - used})
+	}
+}
+
+//	Allocates size units from the smallest free block that fits them.
+func (me *meshBufferAlloc) alloc(size int32) (offset int32, ok bool) {
+	best := -1
+	for i := 0; i < len(me.free); i++ {
+		if me.free[i].size >= size && (best < 0 || me.free[i].size < me.free[best].size) {
+			if best = i; me.free[i].size == size {
+				break
+			}
+		}
+	}
+	if ok = best >= 0; ok {
+		offset = me.free[best].offset
+		if me.free[best].size == size {
+			me.free = append(me.free[:best], me.free[best+1:]...)
+		} else {
+			me.free[best].offset, me.free[best].size = offset+size, me.free[best].size-size
+		}
+		me.used += size
+	}
+	return
+}
+
+//	Returns the size units at offset, previously returned by alloc(), to the free blocks.
+func (me *meshBufferAlloc) release(offset, size int32) {
+	if size <= 0 {
+		return
+	}
+	i := 0
+	for i < len(me.free) && me.free[i].offset < offset {
+		i++
+	}
+	me.used -= size
+	prev, next := i > 0 && me.free[i-1].offset+me.free[i-1].size == offset, i < len(me.free) && offset+size == me.free[i].offset
+	switch {
+	case prev && next:
+		me.free[i-1].size += size + me.free[i].size
+		me.free = append(me.free[:i], me.free[i+1:]...)
+	case prev:
+		me.free[i-1].size += size
+	case next:
+		me.free[i].offset, me.free[i].size = offset, me.free[i].size+size
+	default:
+		me.free = append(me.free, meshBufferBlock{})
+		copy(me.free[i+1:], me.free[i:])
+		me.free[i] = meshBufferBlock{offset: offset, size: size}
+	}
+}
+
+//	Returns the size of the largest free block.
+func (me *meshBufferAlloc) largestFree() (size int32) {
+	for i := 0; i < len(me.free); i++ {
+		if me.free[i].size > size {
+			size = me.free[i].size
+		}
+	}
+	return
+}
+
+//	Returns 0 if all free space is in a single block, approaching 1 the more it is scattered.
+func (me *meshBufferAlloc) fragmentation() float64 {
+	if free := me.capacity - me.used; free > 0 {
+		return 1 - float64(me.largestFree())/float64(free)
+	}
+	return 0
+}
+
+//	Memory usage statistics of a MeshBuffer, see MeshBuffer.Stats().
+type MeshBufferStats struct {
+	//	Allocated GPU memory, in bytes
+	MemSizeVertices, MemSizeIndices int32
+
+	//	GPU memory in use by uploaded meshes, in bytes
+	MemUsedVertices, MemUsedIndices int32
+
+	//	The size of the largest free block, in bytes: the most that a single mesh upload can take
+	//	without defragmentation or growth
+	MemLargestFreeVertices, MemLargestFreeIndices int32
+
+	//	The number of free blocks in the vertex and index memory
+	NumFreeBlocksVertices, NumFreeBlocksIndices int
+
+	//	0 if all free vertex (or index) memory is in a single block, approaching 1 the more it is
+	//	scattered. Fragmented free memory may be consolidated via MeshBuffer.Defragment().
+	FragmentationVertices, FragmentationIndices float64
+}
@@ -0,0 +1,116 @@
+package core
+
+import (
+	"reflect"
+	"testing"
+)
+
+//	A single alloc (size > 0) or release (size < 0, of the block allocated by step at) in a meshBufferAllocTest.
+type meshBufferAllocStep struct {
+	size, at int
+}
+
+type meshBufferAllocTest struct {
+	name     string
+	capacity int32
+	steps    []meshBufferAllocStep
+
+	//	The offsets returned by the alloc steps in order, -1 for a failed alloc
+	offsets []int32
+	free    []meshBufferBlock
+	used    int32
+	frag    float64
+}
+
+var meshBufferAllocTests = []meshBufferAllocTest{
+	{name: "empty", capacity: 100,
+		free: []meshBufferBlock{{0, 100}}},
+	{name: "back-to-back", capacity: 100,
+		steps:   []meshBufferAllocStep{{size: 10}, {size: 20}, {size: 70}},
+		offsets: []int32{0, 10, 30}, used: 100},
+	{name: "fragmented", capacity: 100,
+		steps:   []meshBufferAllocStep{{size: 10}, {size: 20}, {size: 30}, {size: -1, at: 0}, {size: -1, at: 2}},
+		offsets: []int32{0, 10, 30},
+		free:    []meshBufferBlock{{0, 10}, {30, 70}}, used: 20, frag: 1 - 70.0/80},
+	{name: "best fit", capacity: 100,
+		steps:   []meshBufferAllocStep{{size: 30}, {size: 10}, {size: 10}, {size: 10}, {size: -1, at: 0}, {size: -1, at: 2}, {size: 10}},
+		offsets: []int32{0, 30, 40, 50, 40},
+		free:    []meshBufferBlock{{0, 30}, {60, 40}}, used: 30, frag: 1 - 40.0/70},
+	{name: "coalesce with previous", capacity: 100,
+		steps:   []meshBufferAllocStep{{size: 10}, {size: 20}, {size: 30}, {size: -1, at: 0}, {size: -1, at: 1}},
+		offsets: []int32{0, 10, 30},
+		free:    []meshBufferBlock{{0, 30}, {60, 40}}, used: 30, frag: 1 - 40.0/70},
+	{name: "coalesce with next", capacity: 100,
+		steps:   []meshBufferAllocStep{{size: 10}, {size: 20}, {size: 30}, {size: -1, at: 2}, {size: -1, at: 1}},
+		offsets: []int32{0, 10, 30},
+		free:    []meshBufferBlock{{10, 90}}, used: 10},
+	{name: "coalesce with both", capacity: 100,
+		steps:   []meshBufferAllocStep{{size: 10}, {size: 20}, {size: 30}, {size: 40}, {size: -1, at: 0}, {size: -1, at: 2}, {size: -1, at: 1}},
+		offsets: []int32{0, 10, 30, 60},
+		free:    []meshBufferBlock{{0, 60}}, used: 40},
+	{name: "out of space", capacity: 100,
+		steps:   []meshBufferAllocStep{{size: 60}, {size: 50}, {size: 40}, {size: 1}},
+		offsets: []int32{0, -1, 60, -1}, used: 100},
+	{name: "fragmented out of space", capacity: 100,
+		steps:   []meshBufferAllocStep{{size: 25}, {size: 25}, {size: 25}, {size: 25}, {size: -1, at: 0}, {size: -1, at: 2}, {size: 50}},
+		offsets: []int32{0, 25, 50, 75, -1},
+		free:    []meshBufferBlock{{0, 25}, {50, 25}}, used: 50, frag: 0.5},
+}
+
+func (me *meshBufferAllocTest) run(alloc *meshBufferAlloc) (offsets []int32) {
+	var allocs []meshBufferBlock
+	for _, step := range me.steps {
+		if step.size > 0 {
+			off, ok := alloc.alloc(int32(step.size))
+			if !ok {
+				off = -1
+			}
+			offsets, allocs = append(offsets, off), append(allocs, meshBufferBlock{offset: off, size: int32(step.size)})
+		} else {
+			alloc.release(allocs[step.at].offset, allocs[step.at].size)
+		}
+	}
+	return
+}
+
+func TestMeshBufferAlloc(t *testing.T) {
+	var alloc meshBufferAlloc
+	for _, test := range meshBufferAllocTests {
+		alloc.reset(test.capacity, 0)
+		offsets := test.run(&alloc)
+		if !reflect.DeepEqual(offsets, test.offsets) {
+			t.Errorf("%v: got offsets %v, want %v", test.name, offsets, test.offsets)
+		}
+		if len(alloc.free) != len(test.free) || (len(test.free) > 0 && !reflect.DeepEqual(alloc.free, test.free)) {
+			t.Errorf("%v: got free blocks %v, want %v", test.name, alloc.free, test.free)
+		}
+		if alloc.used != test.used {
+			t.Errorf("%v: got %v used, want %v", test.name, alloc.used, test.used)
+		}
+		if frag := alloc.fragmentation(); frag < test.frag-1e-9 || frag > test.frag+1e-9 {
+			t.Errorf("%v: got fragmentation %v, want %v", test.name, frag, test.frag)
+		}
+	}
+}
+
+func TestMeshBufferAllocGrow(t *testing.T) {
+	var alloc meshBufferAlloc
+	alloc.reset(100, 0)
+	if _, ok := alloc.alloc(80); !ok {
+		t.Fatalf("alloc(80) failed in an empty buffer of 100")
+	}
+	if _, ok := alloc.alloc(40); ok {
+		t.Fatalf("alloc(40) succeeded with only 20 free")
+	}
+	//	as MeshBuffer.relocate() does after growing the buffer by at least the failed size
+	alloc.reset(alloc.capacity+100, alloc.used)
+	if want := []meshBufferBlock{{80, 120}}; !reflect.DeepEqual(alloc.free, want) {
+		t.Errorf("got free blocks %v after growth, want %v", alloc.free, want)
+	}
+	if off, ok := alloc.alloc(40); !ok || off != 80 {
+		t.Errorf("got alloc(40) = %v, %v after growth, want 80, true", off, ok)
+	}
+	if alloc.used != 120 || alloc.largestFree() != 80 {
+		t.Errorf("got %v used and %v largest free after growth, want 120 and 80", alloc.used, alloc.largestFree())
+	}
+}
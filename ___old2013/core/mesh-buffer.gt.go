@@ -10,24 +10,34 @@ import (
 type MeshBuffer struct {
 	Name string
 
-	memSizeIndices, memSizeVertices             int32
-	offsetBaseIndex, offsetIndices, offsetVerts int32
+	//	The vertex attributes of all meshes in me. Must not be modified after creation.
+	Layout MeshVertexLayout
 
+	//	If true, an upload that doesn't fit into me even after defragmentation
+	//	grows me (to at least twice the current capacity) instead of failing. Defaults to false.
+	AutoGrow bool
+
+	verts, indices meshBufferAlloc
+
+	glUsage      gl.Enum
 	glIbo, glVbo ugl.Buffer
 	glVaos       []ugl.VertexArray
 	meshIDs      []int
+
+	//	See SkinnedBuffer()
+	skinned *MeshBuffer
 }
 
-func newMeshBuffer(name string, capacity int32) (me *MeshBuffer, err error) {
+func newMeshBuffer(name string, capacity int32, layout *MeshVertexLayout, usage gl.Enum) (me *MeshBuffer, err error) {
 	me = &MeshBuffer{}
-	me.Name = name
+	me.Name, me.Layout, me.glUsage = name, *layout, usage
 	me.meshIDs = make([]int, 0, 256)
 	me.glVaos = make([]ugl.VertexArray, 16)
 	numVerts, numIndices := capacity, capacity
-	me.memSizeIndices = Core.Mesh.Buffers.MemSizePerIndex() * numIndices
-	me.memSizeVertices = Core.Mesh.Buffers.MemSizePerVertex() * numVerts
-	if err = me.glVbo.Recreate(gl.ARRAY_BUFFER, gl.Sizeiptr(me.memSizeVertices), ugl.PtrNil, gl.STATIC_DRAW); err == nil {
-		err = me.glIbo.Recreate(gl.ELEMENT_ARRAY_BUFFER, gl.Sizeiptr(me.memSizeIndices), ugl.PtrNil, gl.STATIC_DRAW)
+	me.verts.reset(numVerts, 0)
+	me.indices.reset(numIndices, 0)
+	if err = me.glVbo.Recreate(gl.ARRAY_BUFFER, gl.Sizeiptr(me.Layout.MemSizePerVertex()*numVerts), ugl.PtrNil, usage); err == nil {
+		err = me.glIbo.Recreate(gl.ELEMENT_ARRAY_BUFFER, gl.Sizeiptr(Core.Mesh.Buffers.MemSizePerIndex()*numIndices), ugl.PtrNil, usage)
 	}
 	// if err == nil {
 	// 	var ok bool
@@ -61,8 +71,15 @@ func (me *MeshBuffer) dispose() {
 	me.glVaos = nil
 }
 
+//	Adds the mesh with the specified ID to me. If it is Skinned() but me.Layout has no Skin,
+//	it is added to SkinnedBuffer() instead, so that its vertices keep their joint weights.
 func (me *MeshBuffer) Add(meshID int) (err error) {
-	if mesh := Core.Libs.Meshes.get(meshID); mesh != nil && mesh.meshBuffer != me {
+	if mesh := Core.Libs.Meshes.get(meshID); mesh != nil && mesh.Skinned() && !me.Layout.Skin {
+		var buf *MeshBuffer
+		if buf, err = me.SkinnedBuffer(); err == nil {
+			err = buf.Add(meshID)
+		}
+	} else if mesh != nil && mesh.meshBuffer != me {
 		if mesh.meshBuffer != nil {
 			err = errf("Cannot add mesh '%v' to mesh buffer '%v': already belongs to mesh buffer '%v'.", mesh.Name, me.Name, mesh.meshBuffer.Name)
 		} else {
@@ -73,6 +90,150 @@ func (me *MeshBuffer) Add(meshID int) (err error) {
 	return
 }
 
+//	Returns the companion of me for skinned meshes, created on first use: a MeshBuffer with the same Layout
+//	plus Skin, a quarter of the capacity of me and AutoGrow. Returns me if me.Layout already has Skin.
+func (me *MeshBuffer) SkinnedBuffer() (buf *MeshBuffer, err error) {
+	if me.Layout.Skin {
+		return me, nil
+	}
+	if me.skinned == nil {
+		layout := me.Layout
+		layout.Skin = true
+		if me.skinned, err = Core.Mesh.Buffers.addNew(me.Name+".skinned", me.verts.capacity/4+1, &layout, me.glUsage); err != nil {
+			return
+		}
+		me.skinned.AutoGrow = true
+	}
+	buf = me.skinned
+	return
+}
+
+//	Allocates space for numVerts vertices and numIndices indices of mesh, defragmenting or
+//	(if AutoGrow) growing me if necessary, and sets the mesh's buffer offsets accordingly.
+func (me *MeshBuffer) alloc(mesh *Mesh, numVerts, numIndices int32) (err error) {
+	voff, vok := me.verts.alloc(numVerts)
+	ioff, iok := me.indices.alloc(numIndices)
+	if !(vok && iok) {
+		if vok {
+			me.verts.release(voff, numVerts)
+		}
+		if iok {
+			me.indices.release(ioff, numIndices)
+		}
+		if numVerts <= me.verts.capacity-me.verts.used && numIndices <= me.indices.capacity-me.indices.used {
+			err = me.Defragment()
+		} else if me.AutoGrow {
+			growVerts, growIndices := me.verts.capacity, me.indices.capacity
+			if growVerts < numVerts {
+				growVerts = numVerts
+			}
+			if growIndices < numIndices {
+				growIndices = numIndices
+			}
+			err = me.Grow(growVerts, growIndices)
+		} else if numVerts > me.verts.capacity-me.verts.used {
+			err = errf("Cannot upload mesh '%v': vertex size (%vB) exceeds mesh buffer's available vertex memory (%vB)", mesh.Name, numVerts*me.Layout.MemSizePerVertex(), (me.verts.capacity-me.verts.used)*me.Layout.MemSizePerVertex())
+		} else {
+			err = errf("Cannot upload mesh '%v': index size (%vB) exceeds mesh buffer's available index memory (%vB)", mesh.Name, numIndices*Core.Mesh.Buffers.MemSizePerIndex(), (me.indices.capacity-me.indices.used)*Core.Mesh.Buffers.MemSizePerIndex())
+		}
+		if err == nil {
+			voff, vok = me.verts.alloc(numVerts)
+			ioff, iok = me.indices.alloc(numIndices)
+		}
+	}
+	if err == nil {
+		mesh.meshBufNumVerts, mesh.meshBufNumIndices = numVerts, numIndices
+		mesh.meshBufOffsetBaseIndex, mesh.meshBufOffsetVerts, mesh.meshBufOffsetIndices = voff, voff*me.Layout.MemSizePerVertex(), ioff*Core.Mesh.Buffers.MemSizePerIndex()
+	}
+	return
+}
+
+//	Moves all uploaded meshes to the start of me, so that all free vertex and index memory is
+//	consolidated into a single block each. This is done automatically whenever an upload
+//	doesn't fit into any free block but would fit into the total free memory.
+func (me *MeshBuffer) Defragment() error {
+	return me.relocate(me.verts.capacity, me.indices.capacity)
+}
+
+//	Grows the GPU memory of me by the specified number of vertices and indices,
+//	keeping all meshes already uploaded.
+func (me *MeshBuffer) Grow(numVerts, numIndices int32) error {
+	return me.relocate(me.verts.capacity+numVerts, me.indices.capacity+numIndices)
+}
+
+//	Re-creates the GPU buffers of me with the specified capacities
+//	and copies all uploaded meshes into them, back-to-back.
+func (me *MeshBuffer) relocate(numVerts, numIndices int32) (err error) {
+	var (
+		vbo, ibo   ugl.Buffer
+		voff, ioff int32
+		mesh       *Mesh
+	)
+	vsize, isize := me.Layout.MemSizePerVertex(), Core.Mesh.Buffers.MemSizePerIndex()
+	if err = vbo.Recreate(gl.ARRAY_BUFFER, gl.Sizeiptr(vsize*numVerts), ugl.PtrNil, me.glUsage); err == nil {
+		err = ibo.Recreate(gl.ELEMENT_ARRAY_BUFFER, gl.Sizeiptr(isize*numIndices), ugl.PtrNil, me.glUsage)
+	}
+	if err != nil {
+		vbo.Dispose()
+		ibo.Dispose()
+		return
+	}
+	Diag.LogMeshes("Relocating mesh buffer %v to %v verts, %v indices", me.Name, numVerts, numIndices)
+	for _, meshID := range me.meshIDs {
+		if mesh = Core.Libs.Meshes.get(meshID); mesh != nil && mesh.meshBuffer == me && mesh.gpuSynced {
+			meshBufferCopy(&me.glVbo, &vbo, mesh.meshBufOffsetVerts, voff*vsize, mesh.meshBufNumVerts*vsize)
+			meshBufferCopy(&me.glIbo, &ibo, mesh.meshBufOffsetIndices, ioff*isize, mesh.meshBufNumIndices*isize)
+			mesh.meshBufOffsetBaseIndex, mesh.meshBufOffsetVerts, mesh.meshBufOffsetIndices = voff, voff*vsize, ioff*isize
+			voff, ioff = voff+mesh.meshBufNumVerts, ioff+mesh.meshBufNumIndices
+		}
+	}
+	gl.BindBuffer(gl.COPY_READ_BUFFER, 0)
+	gl.BindBuffer(gl.COPY_WRITE_BUFFER, 0)
+	me.glVbo.Dispose()
+	me.glIbo.Dispose()
+	me.glVbo, me.glIbo = vbo, ibo
+	//	the vertex arrays refer to the old buffers, so have use() set them up anew
+	for i := 0; i < len(me.glVaos); i++ {
+		me.glVaos[i].Dispose()
+	}
+	me.glVaos = make([]ugl.VertexArray, len(me.glVaos))
+	me.verts.reset(numVerts, voff)
+	me.indices.reset(numIndices, ioff)
+	return
+}
+
+//	Copies size bytes at srcOffset in src to dstOffset in dst.
+func meshBufferCopy(src, dst *ugl.Buffer, srcOffset, dstOffset, size int32) {
+	if size > 0 {
+		gl.BindBuffer(gl.COPY_READ_BUFFER, src.GlHandle)
+		gl.BindBuffer(gl.COPY_WRITE_BUFFER, dst.GlHandle)
+		gl.CopyBufferSubData(gl.COPY_READ_BUFFER, gl.COPY_WRITE_BUFFER, gl.Intptr(srcOffset), gl.Intptr(dstOffset), gl.Sizeiptr(size))
+	}
+}
+
+//	Returns the space that mesh occupies in me to the free memory.
+func (me *MeshBuffer) release(mesh *Mesh) {
+	me.verts.release(mesh.meshBufOffsetBaseIndex, mesh.meshBufNumVerts)
+	me.indices.release(mesh.meshBufOffsetIndices/Core.Mesh.Buffers.MemSizePerIndex(), mesh.meshBufNumIndices)
+	mesh.meshBufNumVerts, mesh.meshBufNumIndices = 0, 0
+}
+
+//	Returns the current memory usage of me.
+func (me *MeshBuffer) Stats() (stats MeshBufferStats) {
+	vsize, isize := me.Layout.MemSizePerVertex(), Core.Mesh.Buffers.MemSizePerIndex()
+	stats.MemSizeVertices, stats.MemSizeIndices = vsize*me.verts.capacity, isize*me.indices.capacity
+	stats.MemUsedVertices, stats.MemUsedIndices = vsize*me.verts.used, isize*me.indices.used
+	stats.MemLargestFreeVertices, stats.MemLargestFreeIndices = vsize*me.verts.largestFree(), isize*me.indices.largestFree()
+	stats.NumFreeBlocksVertices, stats.NumFreeBlocksIndices = len(me.verts.free), len(me.indices.free)
+	stats.FragmentationVertices, stats.FragmentationIndices = me.verts.fragmentation(), me.indices.fragmentation()
+	return
+}
+
+//	Returns true if me was created via Core.Mesh.Buffers.AddNewDynamic().
+func (me *MeshBuffer) Dynamic() bool {
+	return me.glUsage != gl.STATIC_DRAW
+}
+
 func (me *MeshBuffer) use() {
 	if thrRend.curProg.Index >= len(me.glVaos) || me.glVaos[thrRend.curProg.Index].GlHandle == 0 {
 		me.setupVao(thrRend.curProg.Index)
@@ -101,8 +262,10 @@ func (me *MeshBuffer) setupVao(progIndex int) (err error) {
 		me.glVaos = nuVaos
 	}
 	if err = me.glVaos[progIndex].Create(); err == nil {
-		if sceneTech, ok := ogl.progs.All[progIndex].Tag.(*RenderTechniqueScene); ok {
-			if err = me.glVaos[progIndex].Setup(&ogl.progs.All[progIndex], sceneTech.vertexAttribPointers(), &me.glVbo, &me.glIbo); err != nil {
+		if sceneTech, ok := ogl.progs.All[progIndex].Tag.(interface {
+			vertexAttribPointers(*MeshVertexLayout) []ugl.VertexAttribPointer
+		}); ok {
+			if err = me.glVaos[progIndex].Setup(&ogl.progs.All[progIndex], sceneTech.vertexAttribPointers(&me.Layout), &me.glVbo, &me.glIbo); err != nil {
 				return
 			}
 		}
@@ -110,8 +273,27 @@ func (me *MeshBuffer) setupVao(progIndex int) (err error) {
 	return
 }
 
+//	Creates a new MeshBuffer with Options.Rendering.DefaultMeshVertexLayout.
 func (me *MeshBufferLib) AddNew(name string, capacity int32) (buf *MeshBuffer, err error) {
-	if buf, err = newMeshBuffer(name, capacity); err == nil {
+	return me.AddNewWithLayout(name, capacity, &Options.Rendering.DefaultMeshVertexLayout)
+}
+
+//	Creates a new MeshBuffer whose vertices have the specified layout.
+func (me *MeshBufferLib) AddNewWithLayout(name string, capacity int32, layout *MeshVertexLayout) (buf *MeshBuffer, err error) {
+	return me.addNew(name, capacity, layout, gl.STATIC_DRAW)
+}
+
+//	Creates a new MeshBuffer for meshes whose vertices are edited frequently (such as every frame) via Mesh.EditVerts().
+//	If layout is nil, Options.Rendering.DefaultMeshVertexLayout is used.
+func (me *MeshBufferLib) AddNewDynamic(name string, capacity int32, layout *MeshVertexLayout) (buf *MeshBuffer, err error) {
+	if layout == nil {
+		layout = &Options.Rendering.DefaultMeshVertexLayout
+	}
+	return me.addNew(name, capacity, layout, gl.DYNAMIC_DRAW)
+}
+
+func (me *MeshBufferLib) addNew(name string, capacity int32, layout *MeshVertexLayout, usage gl.Enum) (buf *MeshBuffer, err error) {
+	if buf, err = newMeshBuffer(name, capacity, layout, usage); err == nil {
 		me.add(buf)
 	} else if buf != nil {
 		buf.dispose()
@@ -120,20 +302,12 @@ func (me *MeshBufferLib) AddNew(name string, capacity int32) (buf *MeshBuffer, e
 	return
 }
 
-func (_ MeshBufferLib) FloatsPerVertex() int32 {
-	const numVertPosFloats, numVertTexCoordFloats, numVertNormalFloats int32 = 3, 2, 3
-	return numVertPosFloats + numVertNormalFloats + numVertTexCoordFloats
-}
-
+//	Returns the unit in which index memory is allocated: the size of a 32-bit index.
+//	Meshes with 16-bit indices (see Options.Meshes.Optimize.Indices16) take half a unit per index.
 func (_ MeshBufferLib) MemSizePerIndex() int32 {
 	return 4
 }
 
-func (_ MeshBufferLib) MemSizePerVertex() int32 {
-	const sizePerFloat int32 = 4
-	return sizePerFloat * Core.Mesh.Buffers.FloatsPerVertex()
-}
-
 //#begin-gt -gen-reflib.gt T:MeshBuffer L:Core.Mesh.Buffers
 
 //	Only used for Core.Mesh.Buffers
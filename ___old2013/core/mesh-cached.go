@@ -0,0 +1,276 @@
+package core
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/binary"
+	"hash/fnv"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	"github.com/metaleap/go-util-hash"
+	gl "github.com/metaleap/go-opengl/core"
+	u3d "github.com/metaleap/go-util-3d"
+)
+
+//	Bumped whenever the layout of mesh cache files changes, invalidating all existing ones.
+const meshCacheVersion = 2
+
+//	Locates the processed mesh data of one item of a mesh source file in the disk cache.
+//	All its methods also accept a nil key, which never hits.
+type meshCacheKey struct {
+	fullPath string
+	meta     [3]int64
+}
+
+//	Returns the key of srcItem of the file at srcFilePath, as of that file's current size and modification time.
+//	Returns nil if the disk cache is disabled or that file cannot be accessed.
+func newMeshCacheKey(srcFilePath, srcItem string) (key *meshCacheKey) {
+	if Options.Meshes.DiskCache.Enabled && len(Options.AppDir.Temp.CachedMeshes) > 0 {
+		if src, err := os.Stat(Core.fileIO.resolveLocalFilePath(srcFilePath)); err == nil {
+			opt := &Options.Meshes.Optimize
+			hash, _ := uhash.WriteAndSum(fnv.New64a(), []byte(strf("%s_%s_%t_%t_%t", srcFilePath, srcItem, opt.VertexCache, opt.Overdraw, opt.VertexFetch)), nil)
+			key = &meshCacheKey{meta: [3]int64{meshCacheVersion, src.ModTime().UnixNano(), src.Size()}}
+			key.fullPath = filepath.Join(Core.fileIO.resolveLocalFilePath(filepath.Join(Options.AppDir.Temp.BaseName, Options.AppDir.Temp.CachedMeshes)), base64.URLEncoding.EncodeToString(hash))
+		}
+	}
+	return
+}
+
+//	Loads the geometry provided by provider, just like Load(), unless the disk cache (see Options.Meshes.DiskCache
+//	and Options.AppDir.Temp.CachedMeshes) holds the processed mesh data for srcItem of the file at srcFilePath, as of
+//	that file's current size and modification time. srcItem identifies me among all meshes in that file, and may be
+//	empty if it only holds one. After the provider is used, its processed mesh data is written to the disk cache.
+//
+//	The cache also depends on Options.Meshes.Optimize, so changing those options invalidates it.
+//	Importers check the cache before even parsing their source file, so that a hit skips both.
+func (me *Mesh) LoadCached(srcFilePath, srcItem string, provider u3d.MeshProvider) (err error) {
+	key := newMeshCacheKey(srcFilePath, srcItem)
+	if raw, _ := key.load(); raw != nil {
+		me.loadRaw(raw)
+		return
+	}
+	return me.loadAndCache(key, provider, nil)
+}
+
+//	Adds a new Mesh with the specified name to me and loads it via LoadCached().
+func (me *MeshLib) AddNewAndLoadCached(name, srcFilePath, srcItem string, meshProvider u3d.MeshProvider) (meshID int, err error) {
+	meshID = me.AddNew()
+	mesh := &(*me)[meshID]
+	mesh.Name = name
+	if err = mesh.LoadCached(srcFilePath, srcItem, meshProvider); err != nil {
+		me.Remove(meshID, 1)
+		meshID = -1
+	}
+	return
+}
+
+//	Replaces the geometry of me with raw, as returned by meshCacheKey.load().
+func (me *Mesh) loadRaw(raw *meshRaw) {
+	me.gpuSynced, me.dyn, me.raw = false, nil, *raw
+	Diag.LogMeshes("mesh{%v} gave %v faces from the disk cache", me.Name, len(me.raw.faces))
+}
+
+//	Loads me via provider, then writes the processed mesh data (along with extra, if any) to the disk cache under key.
+func (me *Mesh) loadAndCache(key *meshCacheKey, provider u3d.MeshProvider, extra [][]string) (err error) {
+	if err = me.Load(provider); err == nil && key != nil {
+		if cacheErr := key.save(&me.raw, extra); cacheErr != nil {
+			Diag.LogErr(cacheErr)
+		}
+	}
+	return
+}
+
+//	Returns the processed mesh data cached under me (along with the extra strings it was saved with),
+//	or nil if there is none for the current meta data.
+func (me *meshCacheKey) load() (result *meshRaw, extra [][]string) {
+	var (
+		raw      meshRaw
+		data     []byte
+		counts   [4]uint32
+		bounds   [7]float64
+		fileMeta [3]int64
+		numExtra uint32
+	)
+	if me == nil {
+		return
+	}
+	file, err := os.Open(me.fullPath)
+	if err != nil {
+		return
+	}
+	defer file.Close()
+	unpacker := Options.Meshes.DiskCache.Decompressor(file)
+	defer unpacker.Close()
+	if data, err = ioutil.ReadAll(unpacker); err != nil {
+		return
+	}
+	r := bytes.NewReader(data)
+	if binary.Read(r, binary.LittleEndian, &fileMeta) != nil || fileMeta != me.meta || binary.Read(r, binary.LittleEndian, &counts) != nil || binary.Read(r, binary.LittleEndian, &bounds) != nil {
+		return
+	}
+	if uint64(counts[0]) != uint64(counts[2])*meshVertexBaseFloats || 4*uint64(counts[0])+4*uint64(counts[1])+12*uint64(counts[2])+(12+72)*uint64(counts[3]) > uint64(r.Len()) {
+		return
+	}
+	raw.verts, raw.indices = make([]float32, counts[0]), make([]uint32, counts[1])
+	vertSrc, faceEntries, facePos := make([][3]uint32, counts[2]), make([][3]uint32, counts[3]), make([][9]float64, counts[3])
+	for _, slice := range []interface{}{raw.verts, raw.indices, vertSrc, faceEntries, facePos} {
+		if binary.Read(r, binary.LittleEndian, slice) != nil {
+			return
+		}
+	}
+	//	a corrupt (or foreign) file must be a miss, rather than make the mesh index out of range later on
+	for _, index := range raw.indices {
+		if index >= counts[2] {
+			return
+		}
+	}
+	for fi := 0; fi < len(faceEntries); fi++ {
+		if faceEntries[fi][0] >= counts[1] || faceEntries[fi][1] >= counts[1] || faceEntries[fi][2] >= counts[1] {
+			return
+		}
+	}
+	raw.vertSrc, raw.faces = make([]u3d.MeshDescF3V, len(vertSrc)), make([]meshRawFace, len(faceEntries))
+	for v := 0; v < len(vertSrc); v++ {
+		raw.vertSrc[v] = u3d.MeshDescF3V{PosIndex: vertSrc[v][0], TexCoordIndex: vertSrc[v][1], NormalIndex: vertSrc[v][2]}
+	}
+	for fi := 0; fi < len(raw.faces); fi++ {
+		face := &raw.faces[fi]
+		face.entries = faceEntries[fi]
+		for ei := 0; ei < 3; ei++ {
+			face.pos[ei].Set(facePos[fi][3*ei], facePos[fi][3*ei+1], facePos[fi][3*ei+2])
+		}
+		face.center.Set((face.pos[0].X+face.pos[1].X+face.pos[2].X)/3, (face.pos[0].Y+face.pos[1].Y+face.pos[2].Y)/3, (face.pos[0].Z+face.pos[1].Z+face.pos[2].Z)/3)
+		if face.base.ID, err = meshCacheReadString(r); err != nil {
+			return
+		}
+		if face.base.Tags, err = meshCacheReadStrings(r); err != nil {
+			return
+		}
+	}
+	if binary.Read(r, binary.LittleEndian, &numExtra) != nil || uint64(numExtra) > uint64(r.Len()) {
+		return
+	}
+	extra = make([][]string, numExtra)
+	for i := 0; i < len(extra); i++ {
+		if extra[i], err = meshCacheReadStrings(r); err != nil {
+			return nil, nil
+		}
+	}
+	raw.bounding.AaBox.Min.Set(bounds[0], bounds[1], bounds[2])
+	raw.bounding.AaBox.Max.Set(bounds[3], bounds[4], bounds[5])
+	raw.bounding.AaBox.SetCenterExtent()
+	raw.bounding.Sphere = bounds[6]
+	raw.lastNumIndices = gl.Sizei(len(raw.indices))
+	result = &raw
+	return
+}
+
+//	Writes raw, along with the meta data of me and extra, to the cache file of me.
+//	The file is written under a temporary name first, so that it is never found incomplete.
+func (me *meshCacheKey) save(raw *meshRaw, extra [][]string) (err error) {
+	var (
+		buf  bytes.Buffer
+		file *os.File
+	)
+	min, max := &raw.bounding.AaBox.Min, &raw.bounding.AaBox.Max
+	vertSrc, faceEntries, facePos := make([][3]uint32, len(raw.vertSrc)), make([][3]uint32, len(raw.faces)), make([][9]float64, len(raw.faces))
+	for v := 0; v < len(vertSrc); v++ {
+		vertSrc[v] = [3]uint32{raw.vertSrc[v].PosIndex, raw.vertSrc[v].TexCoordIndex, raw.vertSrc[v].NormalIndex}
+	}
+	for fi := 0; fi < len(raw.faces); fi++ {
+		faceEntries[fi] = raw.faces[fi].entries
+		for ei := 0; ei < 3; ei++ {
+			facePos[fi][3*ei], facePos[fi][3*ei+1], facePos[fi][3*ei+2] = raw.faces[fi].pos[ei].X, raw.faces[fi].pos[ei].Y, raw.faces[fi].pos[ei].Z
+		}
+	}
+	for _, val := range []interface{}{
+		me.meta,
+		[4]uint32{uint32(len(raw.verts)), uint32(len(raw.indices)), uint32(len(vertSrc)), uint32(len(raw.faces))},
+		[7]float64{min.X, min.Y, min.Z, max.X, max.Y, max.Z, raw.bounding.Sphere},
+		raw.verts, raw.indices, vertSrc, faceEntries, facePos,
+	} {
+		if err = binary.Write(&buf, binary.LittleEndian, val); err != nil {
+			return
+		}
+	}
+	for fi := 0; fi < len(raw.faces) && err == nil; fi++ {
+		if err = meshCacheWriteString(&buf, raw.faces[fi].base.ID); err == nil {
+			err = meshCacheWriteStrings(&buf, raw.faces[fi].base.Tags)
+		}
+	}
+	if err == nil {
+		err = binary.Write(&buf, binary.LittleEndian, uint32(len(extra)))
+	}
+	for i := 0; i < len(extra) && err == nil; i++ {
+		err = meshCacheWriteStrings(&buf, extra[i])
+	}
+	if err != nil {
+		return
+	}
+	tmpPath := me.fullPath + ".tmp"
+	if file, err = os.Create(tmpPath); err != nil {
+		return
+	}
+	packer := Options.Meshes.DiskCache.Compressor(file)
+	_, err = packer.Write(buf.Bytes())
+	if closeErr := packer.Close(); err == nil {
+		err = closeErr
+	}
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Rename(tmpPath, me.fullPath)
+	}
+	if err != nil {
+		os.Remove(tmpPath)
+	}
+	return
+}
+
+func meshCacheReadString(r *bytes.Reader) (s string, err error) {
+	var l uint32
+	if err = binary.Read(r, binary.LittleEndian, &l); err == nil {
+		if int(l) > r.Len() {
+			err = errf("truncated mesh cache file")
+		} else {
+			b := make([]byte, l)
+			_, err = r.Read(b)
+			s = string(b)
+		}
+	}
+	return
+}
+
+func meshCacheWriteString(buf *bytes.Buffer, s string) (err error) {
+	if err = binary.Write(buf, binary.LittleEndian, uint32(len(s))); err == nil {
+		_, err = buf.WriteString(s)
+	}
+	return
+}
+
+func meshCacheReadStrings(r *bytes.Reader) (strs []string, err error) {
+	var num uint32
+	if err = binary.Read(r, binary.LittleEndian, &num); err == nil {
+		if uint64(num)*4 > uint64(r.Len()) {
+			err = errf("truncated mesh cache file")
+		}
+		for i := uint32(0); i < num && err == nil; i++ {
+			var s string
+			if s, err = meshCacheReadString(r); err == nil {
+				strs = append(strs, s)
+			}
+		}
+	}
+	return
+}
+
+func meshCacheWriteStrings(buf *bytes.Buffer, strs []string) (err error) {
+	err = binary.Write(buf, binary.LittleEndian, uint32(len(strs)))
+	for i := 0; i < len(strs) && err == nil; i++ {
+		err = meshCacheWriteString(buf, strs[i])
+	}
+	return
+}
@@ -0,0 +1,190 @@
+package core
+
+import (
+	"sync"
+
+	"github.com/metaleap/go-util-num"
+	gl "github.com/metaleap/go-opengl/core"
+)
+
+//	Guards the pending vertex edits of all meshes, see Mesh.EditVerts().
+var meshEdits sync.Mutex
+
+//	A single final vertex of a Mesh, see Mesh.EditVerts().
+type MeshVert struct {
+	Pos      [3]float32
+	TexCoord [2]float32
+	Normal   [3]float32
+}
+
+//	The pending vertex edits of a Mesh, see Mesh.EditVerts().
+type meshDynamic struct {
+	//	The vertices as edited by the app, staged on the next sync
+	verts []MeshVert
+	dirty bool
+
+	//	A snapshot of verts taken on the previous sync, applied to the meshRaw (and the bounds of
+	//	nodes) and uploaded together on the next sync
+	staged   []MeshVert
+	stagedOk bool
+
+	//	The staged vertices packed as per the MeshBuffer.Layout, re-used across uploads
+	packed []float32
+}
+
+//	Calls edit with the positions, tex-coords and normals of all final vertices of me (as per NumVerts()),
+//	to be modified in-place. me must be loaded (and not since Unload()ed).
+//
+//	While Loop.Run() is running, this may be called from any thread (typically in Loop.On.AppThread()).
+//	Once the app and prep threads are done with the current frame, the edits are staged. Once they are done with
+//	the next one, the edits are applied to the faces and bounds of me and of all SceneNodes rendering me (including
+//	via LodGroups), and uploaded to the GPU along with them, in time to be drawn with the node transforms of the frame
+//	they were made in. For meshes edited every frame, use a MeshBuffer created via
+//	Core.Mesh.Buffers.AddNewDynamic(). Outside of Loop.Run(), call GpuUpload() to apply the edits.
+func (me *Mesh) EditVerts(edit func(verts []MeshVert)) (err error) {
+	meshEdits.Lock()
+	defer meshEdits.Unlock()
+	if len(me.raw.vertSrc) == 0 {
+		err = errf("Cannot edit vertices of mesh '%v': mesh is not loaded", me.Name)
+		return
+	}
+	if me.dyn == nil {
+		me.dyn = &meshDynamic{}
+	}
+	if len(me.dyn.verts) != len(me.raw.vertSrc) {
+		me.dyn.verts = make([]MeshVert, len(me.raw.vertSrc))
+		for v := 0; v < len(me.dyn.verts); v++ {
+			f := me.raw.verts[v*meshVertexBaseFloats:]
+			copy(me.dyn.verts[v].Pos[:], f[0:3])
+			copy(me.dyn.verts[v].TexCoord[:], f[3:5])
+			copy(me.dyn.verts[v].Normal[:], f[5:8])
+		}
+	}
+	edit(me.dyn.verts)
+	me.dyn.dirty = true
+	return
+}
+
+//	Returns the number of final vertices of me, that is, of distinct position, tex-coord and normal combinations.
+func (me *Mesh) NumVerts() int {
+	return len(me.raw.vertSrc)
+}
+
+//	Applies the specified edited vertices of me to its meshRaw, then recomputes its face positions and bounds.
+//	The caller holds meshEdits.
+func (me *Mesh) applyVertEdits(verts []MeshVert) {
+	var (
+		f  []float32
+		v  unum.Vec3
+		fi int
+	)
+	if len(verts) != len(me.raw.vertSrc) {
+		return
+	}
+	me.raw.bounding.Reset()
+	for i := 0; i < len(verts); i++ {
+		f = me.raw.verts[i*meshVertexBaseFloats:]
+		copy(f[0:3], verts[i].Pos[:])
+		copy(f[3:5], verts[i].TexCoord[:])
+		copy(f[5:8], verts[i].Normal[:])
+		v.Set(float64(f[0]), float64(f[1]), float64(f[2]))
+		if m := v.Magnitude(); m > me.raw.bounding.Sphere {
+			me.raw.bounding.Sphere = m
+		}
+		me.raw.bounding.AaBox.UpdateMinMax(&v)
+	}
+	me.raw.bounding.AaBox.SetCenterExtent()
+	for i := 0; i < len(me.raw.morphs); i++ {
+		me.raw.expandMorphBounds(&me.raw.morphs[i])
+	}
+	for fi = 0; fi < len(me.raw.faces); fi++ {
+		face := &me.raw.faces[fi]
+		for ei, entry := range face.entries {
+			f = me.raw.verts[int(me.raw.indices[entry])*meshVertexBaseFloats:]
+			face.pos[ei].Set(float64(f[0]), float64(f[1]), float64(f[2]))
+		}
+		face.center.X = (face.pos[0].X + face.pos[1].X + face.pos[2].X) / 3
+		face.center.Y = (face.pos[0].Y + face.pos[1].Y + face.pos[2].Y) / 3
+		face.center.Z = (face.pos[0].Z + face.pos[1].Z + face.pos[2].Z) / 3
+	}
+}
+
+//	Called by Loop.Run() once per frame, while neither app nor prep thread is running: applies and uploads the
+//	vertex edits staged in the previous frame, then stages the edits made in this one. This lags both the GPU
+//	upload and the new bounds by one frame, matching the latency of node transforms from app thread to render.
+func (_ MeshLib) onSyncEdits() {
+	var (
+		mesh   *Mesh
+		err    error
+		edited map[int]bool
+	)
+	meshEdits.Lock()
+	defer meshEdits.Unlock()
+	for id := 0; id < len(Core.Libs.Meshes); id++ {
+		if mesh = &Core.Libs.Meshes[id]; !(Core.Libs.Meshes.Ok(id) && mesh.dyn != nil) {
+			continue
+		}
+		if mesh.dyn.stagedOk {
+			mesh.dyn.stagedOk = false
+			mesh.applyVertEdits(mesh.dyn.staged)
+			if mesh.gpuSynced {
+				mesh.dyn.packed = mesh.raw.vertsFor(&mesh.meshBuffer.Layout, mesh.dyn.packed)
+				if err = mesh.meshBuffer.uploadVerts(mesh.meshBufOffsetVerts, mesh.dyn.packed); err != nil {
+					Diag.LogErr(err)
+				}
+			}
+			if edited == nil {
+				edited = map[int]bool{}
+			}
+			edited[id] = true
+		}
+		if mesh.dyn.dirty {
+			mesh.dyn.dirty = false
+			mesh.dyn.staged, mesh.dyn.stagedOk = append(mesh.dyn.staged[:0], mesh.dyn.verts...), true
+		}
+	}
+	if len(edited) > 0 {
+		Core.Libs.Scenes.Walk(func(scene *Scene) {
+			scene.onMeshesEdited(edited)
+		})
+	}
+}
+
+//	Re-applies the bounds of all nodes rendering any of the specified meshes (as their own or in their LodGroup),
+//	after their vertices were edited. Called while neither app nor prep thread is running, so the new bounds are
+//	handed on to the prep thread right away.
+func (me *Scene) onMeshesEdited(meshIDs map[int]bool) {
+	var node *SceneNode
+	for n := 0; n < len(me.allNodes); n++ {
+		if node = &me.allNodes[n]; me.allNodes.Ok(n) && len(node.thrApp.skin.mats) == 0 && node.rendersAnyMesh(meshIDs) {
+			me.applySelfBounds(n)
+			me.applyAncestorBounds(n)
+			me.spatialUpdate(n)
+			for id := n; me.allNodes.IsOk(id); id = me.allNodes[id].parentID {
+				me.allNodes[id].thrPrep.bounding = me.allNodes[id].thrApp.bounding
+			}
+		}
+	}
+}
+
+//	Writes verts to the vertex memory of me at the specified byte offset. For dynamic buffers, the range is
+//	mapped with invalidation, so the driver can orphan its previous contents rather than stall on pending draws.
+func (me *MeshBuffer) uploadVerts(offset int32, verts []float32) (err error) {
+	if len(verts) == 0 {
+		return
+	}
+	me.glVbo.Bind()
+	defer me.glVbo.Unbind()
+	size := gl.Sizeiptr(4 * len(verts))
+	if me.glUsage != gl.STATIC_DRAW {
+		if ptr := gl.MapBufferRange(gl.ARRAY_BUFFER, gl.Intptr(offset), size, gl.MAP_WRITE_BIT|gl.MAP_INVALIDATE_RANGE_BIT); ptr != nil {
+			copy((*[1 << 28]float32)(ptr)[:len(verts)], verts)
+			if gl.UnmapBuffer(gl.ARRAY_BUFFER) == gl.FALSE {
+				err = errf("Mesh buffer '%v': vertex memory was corrupted while mapped", me.Name)
+			}
+			return
+		}
+	}
+	err = me.glVbo.SubData(gl.Intptr(offset), size, gl.Ptr(&verts[0]))
+	return
+}
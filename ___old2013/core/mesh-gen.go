@@ -0,0 +1,364 @@
+package core
+
+import (
+	"image"
+	"math"
+
+	"github.com/metaleap/go-util-num"
+	u3d "github.com/metaleap/go-util-3d"
+)
+
+//	Builds a u3d.MeshDescriptor for the procedural mesh providers below, see Core.Mesh.Desc.
+type meshGen struct {
+	md *u3d.MeshDescriptor
+}
+
+func newMeshGen() *meshGen {
+	return &meshGen{md: &u3d.MeshDescriptor{}}
+}
+
+//	Adds a vertex (position, normal and tex-coord each) and returns its indices.
+func (me *meshGen) vert(pos, normal *unum.Vec3, u, v float64) (fv u3d.MeshDescF3V) {
+	md := me.md
+	fv.PosIndex, fv.NormalIndex, fv.TexCoordIndex = uint32(len(md.Positions)), uint32(len(md.Normals)), uint32(len(md.TexCoords))
+	md.Positions = append(md.Positions, u3d.MeshDescVA3{float32(pos.X), float32(pos.Y), float32(pos.Z)})
+	md.Normals = append(md.Normals, u3d.MeshDescVA3{float32(normal.X), float32(normal.Y), float32(normal.Z)})
+	md.TexCoords = append(md.TexCoords, u3d.MeshDescVA2{float32(u), float32(v)})
+	return
+}
+
+//	Adds the triangle a, b, c with the specified tag, wound counter-clockwise as seen from the side its
+//	vertex normals face. Degenerate triangles (such as those at the poles of a sphere) are dropped.
+func (me *meshGen) tri(tag string, a, b, c u3d.MeshDescF3V) {
+	var pa, pb, pc, na, nb, nc, cross unum.Vec3
+	md := me.md
+	md.Positions[a.PosIndex].ToVec3(&pa)
+	md.Positions[b.PosIndex].ToVec3(&pb)
+	md.Positions[c.PosIndex].ToVec3(&pc)
+	e1, e2 := unum.Vec3{pb.X - pa.X, pb.Y - pa.Y, pb.Z - pa.Z}, unum.Vec3{pc.X - pa.X, pc.Y - pa.Y, pc.Z - pa.Z}
+	cross.SetFromCrossOf(&e1, &e2)
+	if cross.X*cross.X+cross.Y*cross.Y+cross.Z*cross.Z < 1e-18 {
+		return
+	}
+	md.Normals[a.NormalIndex].ToVec3(&na)
+	md.Normals[b.NormalIndex].ToVec3(&nb)
+	md.Normals[c.NormalIndex].ToVec3(&nc)
+	if cross.X*(na.X+nb.X+nc.X)+cross.Y*(na.Y+nb.Y+nc.Y)+cross.Z*(na.Z+nb.Z+nc.Z) < 0 {
+		b, c = c, b
+	}
+	face := u3d.MeshDescF3{V: [3]u3d.MeshDescF3V{a, b, c}}
+	face.ID = strf("t%d", len(md.Faces))
+	if len(tag) > 0 {
+		face.Tags = []string{tag}
+	}
+	md.Faces = append(md.Faces, face)
+}
+
+//	Adds the quad a, b, c, d (in order around its edge) as two triangles.
+func (me *meshGen) quad(tag string, a, b, c, d u3d.MeshDescF3V) {
+	me.tri(tag, a, b, c)
+	me.tri(tag, a, c, d)
+}
+
+//	Adds a grid of (cols+1) * (rows+1) vertices as returned by at() for all u and v in [0, 1],
+//	connected by quads tagged as per tag() for the u and v at their corner with the lowest indices.
+func (me *meshGen) grid(cols, rows int, at func(u, v float64, pos, normal *unum.Vec3), tag func(u, v float64) string) {
+	var pos, normal unum.Vec3
+	verts := make([]u3d.MeshDescF3V, 0, (cols+1)*(rows+1))
+	for r := 0; r <= rows; r++ {
+		for c := 0; c <= cols; c++ {
+			u, v := float64(c)/float64(cols), float64(r)/float64(rows)
+			at(u, v, &pos, &normal)
+			verts = append(verts, me.vert(&pos, &normal, u, v))
+		}
+	}
+	for r := 0; r < rows; r++ {
+		for c := 0; c < cols; c++ {
+			i := r*(cols+1) + c
+			me.quad(tag(float64(c)/float64(cols), float64(r)/float64(rows)), verts[i], verts[i+1], verts[i+cols+2], verts[i+cols+1])
+		}
+	}
+}
+
+//	Adds a surface of revolution around the Y axis: each row of the grid is a ring of the specified slices,
+//	its radius, height and normal given by profile() for v in [0, 1].
+func (me *meshGen) lathe(slices, rows int, profile func(v float64) (y, radius, normY, normR float64), tag func(v float64) string) {
+	me.grid(slices, rows, func(u, v float64, pos, normal *unum.Vec3) {
+		y, radius, normY, normR := profile(v)
+		sin, cos := math.Sincos(2 * math.Pi * u)
+		pos.Set(radius*sin, y, radius*cos)
+		normal.Set(normR*sin, normY, normR*cos)
+		normal.Normalize()
+	}, func(_, v float64) string { return tag(v) })
+}
+
+//	Adds a disc at height y facing up (or down), with planar tex-coords.
+func (me *meshGen) disc(y, radius float64, slices int, up bool, tag string) {
+	var pos, normal unum.Vec3
+	if normal.Y = -1; up {
+		normal.Y = 1
+	}
+	pos.Y = y
+	center := me.vert(&pos, &normal, 0.5, 0.5)
+	ring := make([]u3d.MeshDescF3V, slices+1)
+	for i := 0; i <= slices; i++ {
+		sin, cos := math.Sincos(2 * math.Pi * float64(i) / float64(slices))
+		pos.Set(radius*sin, y, radius*cos)
+		ring[i] = me.vert(&pos, &normal, 0.5+0.5*sin, 0.5+0.5*cos)
+	}
+	for i := 0; i < slices; i++ {
+		me.tri(tag, center, ring[i], ring[i+1])
+	}
+}
+
+func meshGenHemisphereTag(v float64) string {
+	if v < 0.5 {
+		return "bottom"
+	}
+	return "top"
+}
+
+func meshGenCheck(cond bool, shape string) error {
+	if !cond {
+		return errf("Invalid parameters for a procedural %v mesh", shape)
+	}
+	return nil
+}
+
+//	Returns a provider of a UV sphere centered at the origin, with its faces tagged "top" or "bottom" by hemisphere.
+//	slices >= 3 subdivide it around the Y axis, stacks >= 2 from pole to pole.
+func meshGenUvSphere(radius float64, slices, stacks int) u3d.MeshProvider {
+	return func() (md *u3d.MeshDescriptor, err error) {
+		if err = meshGenCheck(radius > 0 && slices >= 3 && stacks >= 2, "sphere"); err == nil {
+			gen := newMeshGen()
+			gen.lathe(slices, stacks, func(v float64) (y, r, ny, nr float64) {
+				sin, cos := math.Sincos(math.Pi * (v - 0.5))
+				return radius * sin, radius * cos, sin, cos
+			}, meshGenHemisphereTag)
+			md = gen.md
+		}
+		return
+	}
+}
+
+//	Returns a provider of a sphere centered at the origin, made from an icosahedron whose triangles are
+//	each split into 4 for the specified number of subdivisions (at most 7), for evenly sized faces.
+//	Its faces are tagged "top" or "bottom" by hemisphere and spherically mapped.
+func meshGenIcosphere(radius float64, subdivisions int) u3d.MeshProvider {
+	return func() (md *u3d.MeshDescriptor, err error) {
+		if err = meshGenCheck(radius > 0 && subdivisions >= 0 && subdivisions <= 7, "icosphere"); err != nil {
+			return
+		}
+		t := (1 + math.Sqrt(5)) / 2
+		points := []unum.Vec3{{-1, t, 0}, {1, t, 0}, {-1, -t, 0}, {1, -t, 0}, {0, -1, t}, {0, 1, t}, {0, -1, -t}, {0, 1, -t}, {t, 0, -1}, {t, 0, 1}, {-t, 0, -1}, {-t, 0, 1}}
+		tris := [][3]int{{0, 11, 5}, {0, 5, 1}, {0, 1, 7}, {0, 7, 10}, {0, 10, 11}, {1, 5, 9}, {5, 11, 4}, {11, 10, 2}, {10, 7, 6}, {7, 1, 8},
+			{3, 9, 4}, {3, 4, 2}, {3, 2, 6}, {3, 6, 8}, {3, 8, 9}, {4, 9, 5}, {2, 4, 11}, {6, 2, 10}, {8, 6, 7}, {9, 8, 1}}
+		for i := range points {
+			points[i].Normalize()
+		}
+		for s := 0; s < subdivisions; s++ {
+			midpoints := map[[2]int]int{}
+			midpoint := func(a, b int) int {
+				if a > b {
+					a, b = b, a
+				}
+				if m, ok := midpoints[[2]int{a, b}]; ok {
+					return m
+				}
+				p := unum.Vec3{(points[a].X + points[b].X) / 2, (points[a].Y + points[b].Y) / 2, (points[a].Z + points[b].Z) / 2}
+				p.Normalize()
+				points, midpoints[[2]int{a, b}] = append(points, p), len(points)
+				return len(points) - 1
+			}
+			next := make([][3]int, 0, 4*len(tris))
+			for _, tri := range tris {
+				ab, bc, ca := midpoint(tri[0], tri[1]), midpoint(tri[1], tri[2]), midpoint(tri[2], tri[0])
+				next = append(next, [3]int{tri[0], ab, ca}, [3]int{tri[1], bc, ab}, [3]int{tri[2], ca, bc}, [3]int{ab, bc, ca})
+			}
+			tris = next
+		}
+		gen := newMeshGen()
+		for _, tri := range tris {
+			var fv [3]u3d.MeshDescF3V
+			var us [3]float64
+			for i, p := range tri {
+				n := points[p]
+				us[i] = 0.5 + math.Atan2(n.X, n.Z)/(2*math.Pi)
+			}
+			for i := 0; i < 3; i++ {
+				//	avoid stretching faces across the seam of the mapping
+				if us[i] < 0.25 && (us[(i+1)%3] > 0.75 || us[(i+2)%3] > 0.75) {
+					us[i]++
+				}
+			}
+			for i, p := range tri {
+				n := points[p]
+				pos := unum.Vec3{n.X * radius, n.Y * radius, n.Z * radius}
+				fv[i] = gen.vert(&pos, &n, us[i], 0.5+math.Asin(math.Max(-1, math.Min(1, n.Y)))/math.Pi)
+			}
+			tag := "bottom"
+			if points[tri[0]].Y+points[tri[1]].Y+points[tri[2]].Y >= 0 {
+				tag = "top"
+			}
+			gen.tri(tag, fv[0], fv[1], fv[2])
+		}
+		md = gen.md
+		return
+	}
+}
+
+//	Returns a provider of an upright cylinder centered at the origin, with its faces tagged "side", "top" and "bottom".
+//	slices >= 3 subdivide it around the Y axis, stacks >= 1 along it. Without caps, it is open at both ends.
+func meshGenCylinder(radius, height float64, slices, stacks int, caps bool) u3d.MeshProvider {
+	return func() (md *u3d.MeshDescriptor, err error) {
+		if err = meshGenCheck(radius > 0 && height > 0 && slices >= 3 && stacks >= 1, "cylinder"); err == nil {
+			gen := newMeshGen()
+			gen.lathe(slices, stacks, func(v float64) (y, r, ny, nr float64) {
+				return height * (v - 0.5), radius, 0, 1
+			}, func(float64) string { return "side" })
+			if caps {
+				gen.disc(height/2, radius, slices, true, "top")
+				gen.disc(-height/2, radius, slices, false, "bottom")
+			}
+			md = gen.md
+		}
+		return
+	}
+}
+
+//	Returns a provider of an upright cone centered at the origin, its apex at the top, with its faces
+//	tagged "side" and "bottom". slices >= 3 subdivide it around the Y axis, stacks >= 1 along it.
+func meshGenCone(radius, height float64, slices, stacks int) u3d.MeshProvider {
+	return func() (md *u3d.MeshDescriptor, err error) {
+		if err = meshGenCheck(radius > 0 && height > 0 && slices >= 3 && stacks >= 1, "cone"); err == nil {
+			gen := newMeshGen()
+			slant := math.Sqrt(radius*radius + height*height)
+			gen.lathe(slices, stacks, func(v float64) (y, r, ny, nr float64) {
+				return height * (v - 0.5), radius * (1 - v), radius / slant, height / slant
+			}, func(float64) string { return "side" })
+			gen.disc(-height/2, radius, slices, false, "bottom")
+			md = gen.md
+		}
+		return
+	}
+}
+
+//	Returns a provider of a torus around the Y axis, centered at the origin, with its faces tagged "outer" or
+//	"inner" by side. radius is that of the ring, tubeRadius that of the tube; rings >= 3 subdivide it around
+//	the Y axis, sides >= 3 around the tube.
+func meshGenTorus(radius, tubeRadius float64, rings, sides int) u3d.MeshProvider {
+	return func() (md *u3d.MeshDescriptor, err error) {
+		if err = meshGenCheck(tubeRadius > 0 && radius > tubeRadius && rings >= 3 && sides >= 3, "torus"); err == nil {
+			gen := newMeshGen()
+			gen.lathe(rings, sides, func(v float64) (y, r, ny, nr float64) {
+				sin, cos := math.Sincos(2 * math.Pi * v)
+				return tubeRadius * sin, radius + tubeRadius*cos, sin, cos
+			}, func(v float64) string {
+				if v < 0.25 || v >= 0.75 {
+					return "outer"
+				}
+				return "inner"
+			})
+			md = gen.md
+		}
+		return
+	}
+}
+
+//	Returns a provider of an upright capsule centered at the origin: a cylinder of the specified height
+//	capped by hemispheres, with its faces tagged "side", "top" and "bottom". slices >= 3 subdivide it
+//	around the Y axis, stacks >= 1 each hemisphere from its pole to the cylinder.
+func meshGenCapsule(radius, height float64, slices, stacks int) u3d.MeshProvider {
+	return func() (md *u3d.MeshDescriptor, err error) {
+		if err = meshGenCheck(radius > 0 && height >= 0 && slices >= 3 && stacks >= 1, "capsule"); err == nil {
+			gen := newMeshGen()
+			rows, half := 2*stacks+1, height/2
+			//	rows 0 to stacks-1 form the bottom hemisphere, row stacks the cylinder, the rest the top hemisphere
+			row := func(v float64) int {
+				return int(math.Floor(v*float64(rows) + 0.5))
+			}
+			gen.lathe(slices, rows, func(v float64) (y, r, ny, nr float64) {
+				i, offset := row(v), -half
+				if i > stacks {
+					i, offset = i-1, half
+				}
+				sin, cos := math.Sincos(math.Pi * (float64(i)/float64(2*stacks) - 0.5))
+				return offset + radius*sin, radius * cos, sin, cos
+			}, func(v float64) string {
+				switch i := row(v); {
+				case i < stacks:
+					return "bottom"
+				case i == stacks:
+					return "side"
+				}
+				return "top"
+			})
+			md = gen.md
+		}
+		return
+	}
+}
+
+//	Returns a provider of a flat grid in the XZ plane, facing up and centered at the origin,
+//	with cols >= 1 and rows >= 1 quads along X and Z respectively and all faces tagged "top".
+func meshGenGrid(width, depth float64, cols, rows int) u3d.MeshProvider {
+	return func() (md *u3d.MeshDescriptor, err error) {
+		if err = meshGenCheck(width > 0 && depth > 0 && cols >= 1 && rows >= 1, "grid"); err == nil {
+			gen := newMeshGen()
+			gen.grid(cols, rows, func(u, v float64, pos, normal *unum.Vec3) {
+				pos.Set(width*(u-0.5), 0, depth*(0.5-v))
+				normal.Set(0, 1, 0)
+			}, func(_, _ float64) string { return "top" })
+			md = gen.md
+		}
+		return
+	}
+}
+
+//	Returns a provider of a terrain: a grid like Core.Mesh.Desc.Grid(), with each vertex raised by up to height
+//	according to the brightness of the heightmap at that point (bilinearly filtered), and smooth normals.
+//	If cols or rows is 0, the width or height of the heightmap minus 1 is used.
+func meshGenTerrain(width, depth, height float64, heightmap image.Image, cols, rows int) u3d.MeshProvider {
+	return func() (md *u3d.MeshDescriptor, err error) {
+		if heightmap == nil {
+			err = errf("Invalid parameters for a procedural terrain mesh: no heightmap")
+			return
+		}
+		bounds := heightmap.Bounds()
+		if cols == 0 {
+			cols = bounds.Dx() - 1
+		}
+		if rows == 0 {
+			rows = bounds.Dy() - 1
+		}
+		if err = meshGenCheck(width > 0 && depth > 0 && cols >= 1 && rows >= 1 && bounds.Dx() > 0 && bounds.Dy() > 0, "terrain"); err != nil {
+			return
+		}
+		texel := func(x, y int) float64 {
+			x, y = int(math.Max(0, math.Min(float64(bounds.Dx()-1), float64(x)))), int(math.Max(0, math.Min(float64(bounds.Dy()-1), float64(y))))
+			r, g, b, _ := heightmap.At(bounds.Min.X+x, bounds.Min.Y+y).RGBA()
+			return (0.299*float64(r) + 0.587*float64(g) + 0.114*float64(b)) / 0xffff
+		}
+		//	the height at u, v in [0, 1], with v = 0 at the bottom of the heightmap (towards +Z)
+		sample := func(u, v float64) float64 {
+			x, y := u*float64(bounds.Dx()-1), (1-v)*float64(bounds.Dy()-1)
+			x0, y0 := math.Floor(x), math.Floor(y)
+			fx, fy, ix, iy := x-x0, y-y0, int(x0), int(y0)
+			top := texel(ix, iy)*(1-fx) + texel(ix+1, iy)*fx
+			bottom := texel(ix, iy+1)*(1-fx) + texel(ix+1, iy+1)*fx
+			return height * (top*(1-fy) + bottom*fy)
+		}
+		du, dv := 1/float64(cols), 1/float64(rows)
+		gen := newMeshGen()
+		gen.grid(cols, rows, func(u, v float64, pos, normal *unum.Vec3) {
+			pos.Set(width*(u-0.5), sample(u, v), depth*(0.5-v))
+			//	central differences, in world units: dh/dx along +X and dh/dz along +Z (that is, -v)
+			dhdx := (sample(math.Min(1, u+du), v) - sample(math.Max(0, u-du), v)) / (width * (math.Min(1, u+du) - math.Max(0, u-du)))
+			dhdz := (sample(u, math.Max(0, v-dv)) - sample(u, math.Min(1, v+dv))) / (depth * (math.Min(1, v+dv) - math.Max(0, v-dv)))
+			normal.Set(-dhdx, 1, -dhdz)
+			normal.Normalize()
+		}, func(_, _ float64) string { return "top" })
+		md = gen.md
+		return
+	}
+}
@@ -0,0 +1,154 @@
+package core
+
+import (
+	"math"
+
+	"github.com/metaleap/go-util-num"
+	gl "github.com/metaleap/go-opengl/core"
+	ugl "github.com/metaleap/go-opengl/util"
+	u3d "github.com/metaleap/go-util-3d"
+)
+
+//	The maximum number of morph targets per Mesh: all their weights for a node fit into a single mat4 uniform.
+const meshMorphMaxTargets = 16
+
+//	A named morph target (or blend shape) for a Mesh, see Mesh.AddMorphTarget().
+type MeshMorphTarget struct {
+	Name string
+
+	//	Position deltas, indexed like the Positions of the u3d.MeshDescriptor the Mesh was loaded from.
+	PosDeltas []u3d.MeshDescVA3
+
+	//	Optional normal deltas, indexed like the Normals of the u3d.MeshDescriptor the Mesh was loaded from.
+	NormalDeltas []u3d.MeshDescVA3
+}
+
+//	A MeshMorphTarget prepared for the GPU.
+type meshRawMorph struct {
+	name string
+
+	//	Per final vertex: position delta (xyz0), then normal delta (xyz0).
+	deltas []float32
+}
+
+//	The buffer texture holding the deltas of all morph targets of a Mesh.
+type meshMorphGpu struct {
+	glBuf    ugl.Buffer
+	glTex    gl.Uint
+	numVerts gl.Int
+}
+
+func (me *meshMorphGpu) dispose() {
+	me.glBuf.Dispose()
+	if me.glTex != 0 {
+		gl.DeleteTextures(1, &me.glTex)
+		me.glTex = 0
+	}
+}
+
+func (me *meshMorphGpu) upload(morphs []meshRawMorph) (err error) {
+	me.dispose()
+	if len(morphs) > 0 && len(morphs[0].deltas) > 0 {
+		deltas := make([]float32, 0, len(morphs)*len(morphs[0].deltas))
+		for i := 0; i < len(morphs); i++ {
+			deltas = append(deltas, morphs[i].deltas...)
+		}
+		if err = me.glBuf.Recreate(gl.TEXTURE_BUFFER, gl.Sizeiptr(4*len(deltas)), gl.Ptr(&deltas[0]), gl.STATIC_DRAW); err == nil {
+			gl.GenTextures(1, &me.glTex)
+			gl.BindTexture(gl.TEXTURE_BUFFER, me.glTex)
+			gl.TexBuffer(gl.TEXTURE_BUFFER, gl.RGBA32F, me.glBuf.GlHandle)
+			gl.BindTexture(gl.TEXTURE_BUFFER, 0)
+			me.numVerts = gl.Int(len(morphs[0].deltas) / 8)
+		}
+	}
+	return
+}
+
+//	Adds the specified morph target to me. me must be loaded (and not since Unload()ed).
+//	For the new target to take effect on the GPU, call GpuUpload() subsequently.
+//	Returns the index of the new target, as used for SceneNode.Render.MorphWeights.
+//	The bounds of me are expanded to cover the target's positions for morph weights between 0 and 1.
+func (me *Mesh) AddMorphTarget(target *MeshMorphTarget) (index int, err error) {
+	index = -1
+	if len(me.raw.vertSrc) == 0 {
+		err = errf("Cannot add morph target '%v' to mesh '%v': mesh is not loaded", target.Name, me.Name)
+	} else if len(me.raw.morphs) >= meshMorphMaxTargets {
+		err = errf("Cannot add morph target '%v' to mesh '%v': it already has the maximum of %v morph targets", target.Name, me.Name, meshMorphMaxTargets)
+	} else {
+		morph := meshRawMorph{name: target.Name, deltas: make([]float32, 8*len(me.raw.vertSrc))}
+		for v, src := range me.raw.vertSrc {
+			if int(src.PosIndex) < len(target.PosDeltas) {
+				copy(morph.deltas[8*v:8*v+3], target.PosDeltas[src.PosIndex][:])
+			}
+			if int(src.NormalIndex) < len(target.NormalDeltas) {
+				copy(morph.deltas[8*v+4:8*v+7], target.NormalDeltas[src.NormalIndex][:])
+			}
+		}
+		index, me.raw.morphs = len(me.raw.morphs), append(me.raw.morphs, morph)
+		me.raw.expandMorphBounds(&me.raw.morphs[index])
+	}
+	return
+}
+
+//	Returns a copy of me for the final vertices vertSrc, each taking the deltas of the final vertex of me
+//	at its TexCoordIndex, as per meshRaw.meshDescriptor(). If flip, the normal deltas are negated.
+func (me *meshRawMorph) remap(vertSrc []u3d.MeshDescF3V, flip bool) (morph meshRawMorph) {
+	morph = meshRawMorph{name: me.name, deltas: make([]float32, 8*len(vertSrc))}
+	for v, src := range vertSrc {
+		if sv := int(src.TexCoordIndex); 8*sv+8 <= len(me.deltas) {
+			copy(morph.deltas[8*v:8*v+8], me.deltas[8*sv:8*sv+8])
+			if flip {
+				morph.deltas[8*v+4], morph.deltas[8*v+5], morph.deltas[8*v+6] = -morph.deltas[8*v+4], -morph.deltas[8*v+5], -morph.deltas[8*v+6]
+			}
+		}
+	}
+	return
+}
+
+//	Expands the bounds of me by the largest positive and negative position deltas of morph, per axis,
+//	so that they cover all morphed positions for weights between 0 and 1.
+func (me *meshRaw) expandMorphBounds(morph *meshRawMorph) {
+	var (
+		neg, pos, d unum.Vec3
+		sphere      float64
+	)
+	for v := 0; v+3 <= len(morph.deltas); v += 8 {
+		d.Set(float64(morph.deltas[v]), float64(morph.deltas[v+1]), float64(morph.deltas[v+2]))
+		neg.Set(math.Min(neg.X, d.X), math.Min(neg.Y, d.Y), math.Min(neg.Z, d.Z))
+		pos.Set(math.Max(pos.X, d.X), math.Max(pos.Y, d.Y), math.Max(pos.Z, d.Z))
+		sphere = math.Max(sphere, d.Magnitude())
+	}
+	box := &me.bounding.AaBox
+	box.Min.Set(box.Min.X+neg.X, box.Min.Y+neg.Y, box.Min.Z+neg.Z)
+	box.Max.Set(box.Max.X+pos.X, box.Max.Y+pos.Y, box.Max.Z+pos.Z)
+	box.SetCenterExtent()
+	me.bounding.Sphere += sphere
+}
+
+//	Returns the index of the morph target with the specified name, or -1 if me has no such target.
+func (me *Mesh) MorphTargetIndex(name string) int {
+	for i := 0; i < len(me.raw.morphs); i++ {
+		if me.raw.morphs[i].name == name {
+			return i
+		}
+	}
+	return -1
+}
+
+func (me *Mesh) NumMorphTargets() int {
+	return len(me.raw.morphs)
+}
+
+//	Sets the weight in me.Render.MorphWeights of the morph target with the specified name in the mesh of me.
+//	Returns false if there is no such morph target.
+func (me *SceneNode) SetMorphWeight(name string, weight float64) (ok bool) {
+	if mesh := me.mesh(); mesh != nil {
+		if i := mesh.MorphTargetIndex(name); i > -1 {
+			for len(me.Render.MorphWeights) <= i {
+				me.Render.MorphWeights = append(me.Render.MorphWeights, 0)
+			}
+			me.Render.MorphWeights[i], ok = weight, true
+		}
+	}
+	return
+}
@@ -0,0 +1,210 @@
+package core
+
+import (
+	"sort"
+
+	"github.com/metaleap/go-util-num"
+	u3d "github.com/metaleap/go-util-3d"
+)
+
+//	The post-transform vertex cache size assumed by the optimizations in meshRaw.optimize().
+const meshOptimizeCacheSize = 16
+
+//	Reorders the triangles and vertices of me as per Options.Meshes.Optimize. Called by Mesh.load().
+func (me *meshRaw) optimize(meshName string) {
+	opt := &Options.Meshes.Optimize
+	if len(me.faces) < 2 || !(opt.VertexCache || opt.VertexFetch) {
+		return
+	}
+	acmrBefore := meshAcmr(me.indices, len(me.vertSrc))
+	if opt.VertexCache {
+		order, clusters := meshTipsify(me.indices, len(me.vertSrc))
+		if opt.Overdraw {
+			order = me.overdrawOrder(order, clusters)
+		}
+		me.reorderFaces(order)
+	}
+	if opt.VertexFetch {
+		me.reorderVerts()
+	}
+	Diag.LogMeshes("mesh{%v}.optimize() changed ACMR from %.3f to %.3f", meshName, acmrBefore, meshAcmr(me.indices, len(me.vertSrc)))
+}
+
+//	Rearranges me.faces (and me.indices accordingly) such that the new face i is the old face order[i].
+func (me *meshRaw) reorderFaces(order []int) {
+	faces, indices := make([]meshRawFace, len(me.faces)), make([]uint32, len(me.indices))
+	for fi, old := range order {
+		faces[fi] = me.faces[old]
+		for ei := 0; ei < 3; ei++ {
+			indices[3*fi+ei] = me.indices[faces[fi].entries[ei]]
+			faces[fi].entries[ei] = uint32(3*fi + ei)
+		}
+	}
+	me.faces, me.indices = faces, indices
+}
+
+//	Renumbers the vertices of me in the order of their first use by me.indices, for linear vertex fetches.
+func (me *meshRaw) reorderVerts() {
+	numVerts := len(me.vertSrc)
+	remap := make([]int32, numVerts)
+	for v := 0; v < numVerts; v++ {
+		remap[v] = -1
+	}
+	verts, vertSrc, next := make([]float32, len(me.verts)), make([]u3d.MeshDescF3V, numVerts), int32(0)
+	for i, v := range me.indices {
+		if remap[v] < 0 {
+			remap[v] = next
+			copy(verts[next*meshVertexBaseFloats:(next+1)*meshVertexBaseFloats], me.verts[v*meshVertexBaseFloats:(v+1)*meshVertexBaseFloats])
+			vertSrc[next] = me.vertSrc[v]
+			next++
+		}
+		me.indices[i] = uint32(remap[v])
+	}
+	me.verts, me.vertSrc = verts[:next*meshVertexBaseFloats], vertSrc[:next]
+}
+
+//	A run of consecutive triangles in a cache-optimized order, see meshRaw.overdrawOrder().
+type meshOptimizeCluster struct {
+	from, to int
+	score    float64
+}
+
+type meshOptimizeClusters []meshOptimizeCluster
+
+func (me meshOptimizeClusters) Len() int {
+	return len(me)
+}
+
+func (me meshOptimizeClusters) Less(i, j int) bool {
+	return me[i].score > me[j].score
+}
+
+func (me meshOptimizeClusters) Swap(i, j int) {
+	me[i], me[j] = me[j], me[i]
+}
+
+//	Sorts the clusters of the specified triangle order such that those facing away from the mesh center,
+//	and thus most likely to occlude others, come first: a view-independent approximation of
+//	front-to-back drawing that keeps the cache-friendliness within each cluster.
+func (me *meshRaw) overdrawOrder(order []int, clusters []int) []int {
+	var (
+		center, c, n, e1, e2 unum.Vec3
+		f                    *meshRawFace
+	)
+	for fi := 0; fi < len(me.faces); fi++ {
+		center.Add(&me.faces[fi].center)
+	}
+	center.Set(center.X/float64(len(me.faces)), center.Y/float64(len(me.faces)), center.Z/float64(len(me.faces)))
+	all := make(meshOptimizeClusters, len(clusters))
+	for ci, from := range clusters {
+		to := len(order)
+		if ci+1 < len(clusters) {
+			to = clusters[ci+1]
+		}
+		c, n = unum.Vec3{}, unum.Vec3{}
+		for _, fi := range order[from:to] {
+			f = &me.faces[fi]
+			c.Add(&f.center)
+			e1.Set(f.pos[1].X-f.pos[0].X, f.pos[1].Y-f.pos[0].Y, f.pos[1].Z-f.pos[0].Z)
+			e2.Set(f.pos[2].X-f.pos[0].X, f.pos[2].Y-f.pos[0].Y, f.pos[2].Z-f.pos[0].Z)
+			n.Add3(e1.Y*e2.Z-e1.Z*e2.Y, e1.Z*e2.X-e1.X*e2.Z, e1.X*e2.Y-e1.Y*e2.X)
+		}
+		num := float64(to - from)
+		all[ci] = meshOptimizeCluster{from: from, to: to, score: (c.X/num-center.X)*n.X + (c.Y/num-center.Y)*n.Y + (c.Z/num-center.Z)*n.Z}
+	}
+	sort.Stable(all)
+	sorted := make([]int, 0, len(order))
+	for _, cl := range all {
+		sorted = append(sorted, order[cl.from:cl.to]...)
+	}
+	return sorted
+}
+
+//	Returns the average cache miss ratio (misses per triangle) of indices for a FIFO post-transform
+//	vertex cache of meshOptimizeCacheSize entries: 3 at worst, approaching 0.5 for large regular grids.
+func meshAcmr(indices []uint32, numVerts int) float64 {
+	if len(indices) < 3 {
+		return 0
+	}
+	var misses, t int
+	stamps := make([]int, numVerts)
+	for _, v := range indices {
+		if stamps[v] == 0 || t-stamps[v] >= meshOptimizeCacheSize {
+			misses, t = misses+1, t+1
+			stamps[v] = t
+		}
+	}
+	return float64(misses) / float64(len(indices)/3)
+}
+
+//	Returns a cache-optimized order of the triangles in indices, as per "Fast Triangle Reordering
+//	for Vertex Locality and Reduced Overdraw" (Sander, Nehab, Barczak 2007), along with the positions in
+//	that order where the algorithm hit a dead end, which delimit clusters usable for overdraw ordering.
+func meshTipsify(indices []uint32, numVerts int) (order []int, clusters []int) {
+	var (
+		i, t, v, best, prio, bestPrio int
+		candidates, deadEnd          []int
+	)
+	numTris := len(indices) / 3
+	live, offsets := make([]int, numVerts), make([]int, numVerts+1)
+	for _, v := range indices {
+		live[v]++
+	}
+	for v = 0; v < numVerts; v++ {
+		offsets[v+1] = offsets[v] + live[v]
+	}
+	adj, fill := make([]int, len(indices)), append([]int(nil), offsets[:numVerts]...)
+	for t = 0; t < numTris; t++ {
+		for i = 0; i < 3; i++ {
+			v = int(indices[3*t+i])
+			adj[fill[v]], fill[v] = t, fill[v]+1
+		}
+	}
+	stamps, emitted := make([]int, numVerts), make([]bool, numTris)
+	order, clusters = make([]int, 0, numTris), []int{0}
+	fanning, cursor, time := 0, 1, meshOptimizeCacheSize+1
+	for fanning >= 0 {
+		candidates = candidates[:0]
+		for _, t = range adj[offsets[fanning]:offsets[fanning+1]] {
+			if !emitted[t] {
+				emitted[t], order = true, append(order, t)
+				for i = 0; i < 3; i++ {
+					v = int(indices[3*t+i])
+					deadEnd, candidates = append(deadEnd, v), append(candidates, v)
+					if live[v]--; time-stamps[v] > meshOptimizeCacheSize {
+						stamps[v], time = time, time+1
+					}
+				}
+			}
+		}
+		//	pick the candidate that will still be in the cache after fanning around it
+		best, bestPrio = -1, -1
+		for _, v = range candidates {
+			if live[v] > 0 {
+				if prio = 0; time-stamps[v]+2*live[v] <= meshOptimizeCacheSize {
+					prio = time - stamps[v]
+				}
+				if prio > bestPrio {
+					best, bestPrio = v, prio
+				}
+			}
+		}
+		if fanning = best; fanning < 0 {
+			//	dead end: resume from a recently used vertex, else the next one in input order
+			if len(order) < numTris && len(order) > clusters[len(clusters)-1] {
+				clusters = append(clusters, len(order))
+			}
+			for len(deadEnd) > 0 && fanning < 0 {
+				if v, deadEnd = deadEnd[len(deadEnd)-1], deadEnd[:len(deadEnd)-1]; live[v] > 0 {
+					fanning = v
+				}
+			}
+			for ; cursor < numVerts && fanning < 0; cursor++ {
+				if live[cursor] > 0 {
+					fanning = cursor
+				}
+			}
+		}
+	}
+	return
+}
@@ -0,0 +1,135 @@
+package core
+
+import (
+	"testing"
+
+	u3d "github.com/metaleap/go-util-3d"
+)
+
+//	Loads md into a new Mesh, with all of Options.Meshes.Optimize enabled if optimize is set, else disabled.
+func meshTestLoad(t *testing.T, name string, md *u3d.MeshDescriptor, optimize bool) (mesh *Mesh) {
+	opt := &Options.Meshes.Optimize
+	defer func(vc, od, vf bool) { opt.VertexCache, opt.Overdraw, opt.VertexFetch = vc, od, vf }(opt.VertexCache, opt.Overdraw, opt.VertexFetch)
+	opt.VertexCache, opt.Overdraw, opt.VertexFetch = optimize, optimize, optimize
+	mesh = &Mesh{Name: name}
+	if err := mesh.load(md); err != nil {
+		t.Fatalf("%v: %v", name, err)
+	}
+	return
+}
+
+//	Returns a grid of cols x rows quads, with its faces in the generated order or (if scramble) in a
+//	deterministic but cache-unfriendly one.
+func meshTestGrid(t *testing.T, cols, rows int, scramble bool) *u3d.MeshDescriptor {
+	md, err := meshGenGrid(1, 1, cols, rows)()
+	if err != nil {
+		t.Fatalf("grid %vx%v: %v", cols, rows, err)
+	}
+	if scramble {
+		//	visit the faces by a stride coprime to their number, so each is visited once
+		n, stride := len(md.Faces), 7
+		for meshTestGcd(n, stride) != 1 {
+			stride++
+		}
+		faces := make([]u3d.MeshDescF3, n)
+		for i := 0; i < n; i++ {
+			faces[i] = md.Faces[(i*stride)%n]
+		}
+		md.Faces = faces
+	}
+	return md
+}
+
+func meshTestGcd(a, b int) int {
+	for b != 0 {
+		a, b = b, a%b
+	}
+	return a
+}
+
+//	A triangle by the source vertices of its corners, rotated such that the smallest comes first (keeping the winding).
+type meshTestTri [3]u3d.MeshDescF3V
+
+func newMeshTestTri(a, b, c u3d.MeshDescF3V) meshTestTri {
+	less := func(x, y u3d.MeshDescF3V) bool {
+		return x.PosIndex < y.PosIndex || (x.PosIndex == y.PosIndex && (x.TexCoordIndex < y.TexCoordIndex || (x.TexCoordIndex == y.TexCoordIndex && x.NormalIndex < y.NormalIndex)))
+	}
+	if less(b, a) && !less(c, b) {
+		return meshTestTri{b, c, a}
+	} else if less(c, a) && less(c, b) {
+		return meshTestTri{c, a, b}
+	}
+	return meshTestTri{a, b, c}
+}
+
+//	Returns the number of occurrences of each triangle in the indices of raw, also checking that
+//	the faces of raw refer to their own entries in those indices.
+func meshTestTris(t *testing.T, name string, raw *meshRaw) map[meshTestTri]int {
+	tris := map[meshTestTri]int{}
+	if len(raw.indices) != 3*len(raw.faces) {
+		t.Fatalf("%v: %v indices for %v faces", name, len(raw.indices), len(raw.faces))
+	}
+	for fi := 0; fi < len(raw.faces); fi++ {
+		var v [3]u3d.MeshDescF3V
+		for ei, entry := range raw.faces[fi].entries {
+			if entry != uint32(3*fi+ei) {
+				t.Fatalf("%v: face %v refers to index %v rather than %v", name, fi, entry, 3*fi+ei)
+			}
+			v[ei] = raw.vertSrc[raw.indices[entry]]
+			f := raw.verts[int(raw.indices[entry])*meshVertexBaseFloats:]
+			if p := &raw.faces[fi].pos[ei]; float32(p.X) != f[0] || float32(p.Y) != f[1] || float32(p.Z) != f[2] {
+				t.Fatalf("%v: corner %v of face %v is at %v, but its vertex at %v", name, ei, fi, *p, f[0:3])
+			}
+		}
+		tris[newMeshTestTri(v[0], v[1], v[2])]++
+	}
+	return tris
+}
+
+func TestMeshOptimize(t *testing.T) {
+	for _, test := range []struct {
+		name       string
+		cols, rows int
+		scramble   bool
+	}{
+		{"grid 1x1", 1, 1, false},
+		{"grid 16x16", 16, 16, false},
+		{"grid 40x25", 40, 25, false},
+		{"scrambled grid 16x16", 16, 16, true},
+		{"scrambled grid 40x25", 40, 25, true},
+	} {
+		plain := meshTestLoad(t, test.name, meshTestGrid(t, test.cols, test.rows, test.scramble), false)
+		opt := meshTestLoad(t, test.name, meshTestGrid(t, test.cols, test.rows, test.scramble), true)
+		if len(opt.raw.vertSrc) != len(plain.raw.vertSrc) || len(opt.raw.verts) != len(plain.raw.verts) {
+			t.Errorf("%v: optimizing changed the vertex count from %v to %v", test.name, len(plain.raw.vertSrc), len(opt.raw.vertSrc))
+		}
+		before, after := meshTestTris(t, test.name, &plain.raw), meshTestTris(t, test.name, &opt.raw)
+		if len(before) != len(after) {
+			t.Errorf("%v: optimizing changed the number of distinct triangles from %v to %v", test.name, len(before), len(after))
+		}
+		for tri, n := range before {
+			if after[tri] != n {
+				t.Errorf("%v: triangle %v occurs %v times after optimizing, want %v", test.name, tri, after[tri], n)
+			}
+		}
+		if acmrBefore, acmrAfter := meshAcmr(plain.raw.indices, len(plain.raw.vertSrc)), meshAcmr(opt.raw.indices, len(opt.raw.vertSrc)); acmrAfter > acmrBefore {
+			t.Errorf("%v: optimizing made the ACMR worse, from %.3f to %.3f", test.name, acmrBefore, acmrAfter)
+		}
+	}
+}
+
+func TestMeshOptimizeVertexFetch(t *testing.T) {
+	mesh := meshTestLoad(t, "grid", meshTestGrid(t, 16, 16, true), true)
+	//	after reorderVerts(), each vertex is first used right after all lower-numbered ones
+	next := uint32(0)
+	for i, v := range mesh.raw.indices {
+		if v > next {
+			t.Fatalf("index %v refers to vertex %v before vertex %v was used", i, v, next)
+		} else if v == next {
+			next++
+		}
+	}
+	if int(next) != len(mesh.raw.vertSrc) {
+		t.Errorf("%v of %v vertices are used", next, len(mesh.raw.vertSrc))
+	}
+}
@@ -0,0 +1,354 @@
+package core
+
+import (
+	"math"
+
+	"github.com/metaleap/go-util-num"
+	u3d "github.com/metaleap/go-util-3d"
+)
+
+//	How MeshLib.Repair() treats the normals of a mesh.
+type MeshNormals int
+
+const (
+	//	Keeps the normals of the mesh.
+	MeshNormalsKeep MeshNormals = iota
+
+	//	Recomputes one normal per face.
+	MeshNormalsFlat
+
+	//	Recomputes normals per vertex, averaged over all adjacent faces within MeshRepairParams.SmoothAngleDeg.
+	MeshNormalsSmooth
+)
+
+//	Controls MeshLib.Repair() and Mesh.Repair().
+type MeshRepairParams struct {
+	//	If greater than 0, positions closer to one another than this distance are merged into one.
+	WeldEpsilon float64
+
+	//	Reverses the winding order of all faces, and negates all normals (unless recomputed as per Normals).
+	//	For Mesh.Repair(), also negates the normal deltas of all morph targets.
+	FlipWinding bool
+
+	//	Whether and how to recompute the normals of the mesh.
+	Normals MeshNormals
+
+	//	For MeshNormalsSmooth, the maximum angle between two adjacent faces for their shared vertices to be
+	//	smoothed, such as 60. Sharper edges keep a separate normal per side. If 0, all edges are smoothed.
+	SmoothAngleDeg float64
+}
+
+//	The problems found in a mesh by MeshLib.Validate() or MeshLib.Repair().
+type MeshIssues struct {
+	//	The number of faces referring to positions, tex-coords or normals that don't exist.
+	BadIndices int
+
+	//	The number of positions with NaN or infinite coordinates.
+	NaNPositions int
+
+	//	The number of faces with (nearly) no area: two of their corners coincide, or all three are collinear.
+	Degenerate int
+
+	//	The number of faces that repeat an earlier face with the same positions in the same winding order.
+	Duplicate int
+
+	//	The number of edges shared by more than two faces.
+	NonManifoldEdges int
+
+	//	The number of positions merged into others by MeshRepairParams.WeldEpsilon (only set by Repair()).
+	Welded int
+}
+
+//	Returns true if no problems were found.
+func (me *MeshIssues) Ok() bool {
+	return me.BadIndices == 0 && me.NaNPositions == 0 && me.Degenerate == 0 && me.Duplicate == 0 && me.NonManifoldEdges == 0
+}
+
+func (me *MeshIssues) String() string {
+	return strf("%v bad indices, %v NaN positions, %v degenerate faces, %v duplicate faces, %v non-manifold edges, %v welded positions", me.BadIndices, me.NaNPositions, me.Degenerate, me.Duplicate, me.NonManifoldEdges, me.Welded)
+}
+
+//	Returns the problems found in md, without modifying it.
+func (_ MeshLib) Validate(md *u3d.MeshDescriptor) (issues MeshIssues) {
+	meshRepair(md, nil, &issues)
+	return
+}
+
+//	Fixes md in-place: drops all faces with bad indices, NaN positions, no area or duplicating earlier faces,
+//	then welds, flips and recomputes normals as per params (if not nil). Non-manifold edges are only reported.
+//	Returns the problems found in md, including faces that only became degenerate or duplicates by welding.
+func (_ MeshLib) Repair(md *u3d.MeshDescriptor, params *MeshRepairParams) (issues MeshIssues) {
+	if params == nil {
+		params = &MeshRepairParams{}
+	}
+	meshRepair(md, params, &issues)
+	return
+}
+
+//	Returns a provider of the mesh provided by provider, as fixed by Repair(). The problems found are logged via Diag.LogMeshes().
+func (me MeshLib) MeshRepaired(provider u3d.MeshProvider, params *MeshRepairParams) u3d.MeshProvider {
+	return func() (md *u3d.MeshDescriptor, err error) {
+		if md, err = provider(); err == nil && md != nil {
+			issues := me.Repair(md, params)
+			Diag.LogMeshes("MeshRepaired() found %v", &issues)
+		}
+		return
+	}
+}
+
+//	Returns the problems found in me, which must be loaded. Since Load() rejects bad indices,
+//	only the other problems can occur.
+func (me *Mesh) Validate() (issues MeshIssues) {
+	meshRepair(me.raw.meshDescriptor(), nil, &issues)
+	return
+}
+
+//	Fixes me, which must be loaded, via Core.Libs.Meshes.Repair() and reloads it. Its skinning data and morph
+//	targets are kept, but any vertex attributes must be re-set. For the repair to take effect on the GPU,
+//	call GpuUpload() subsequently.
+func (me *Mesh) Repair(params *MeshRepairParams) (issues MeshIssues, err error) {
+	if len(me.raw.vertSrc) == 0 {
+		err = errf("Cannot repair mesh '%v': mesh is not loaded", me.Name)
+		return
+	}
+	md, morphs := me.raw.meshDescriptor(), me.raw.morphs
+	issues = Core.Libs.Meshes.Repair(md, params)
+	if len(md.Faces) == 0 {
+		err = errf("Cannot repair mesh '%v': no valid faces left", me.Name)
+	} else if err = me.load(md); err == nil {
+		//	the tex-coord indices of md (and thus of the reloaded vertSrc) are the previous final vertices
+		for i := 0; i < len(morphs); i++ {
+			me.raw.morphs = append(me.raw.morphs, morphs[i].remap(me.raw.vertSrc, params != nil && params.FlipWinding))
+			me.raw.expandMorphBounds(&me.raw.morphs[i])
+		}
+	}
+	return
+}
+
+//	Returns a u3d.MeshDescriptor of me with the original position indices, and tex-coords and normals per final vertex.
+func (me *meshRaw) meshDescriptor() (md *u3d.MeshDescriptor) {
+	var numPos uint32
+	for _, src := range me.vertSrc {
+		if src.PosIndex >= numPos {
+			numPos = src.PosIndex + 1
+		}
+	}
+	md = &u3d.MeshDescriptor{Positions: make([]u3d.MeshDescVA3, numPos), TexCoords: make([]u3d.MeshDescVA2, len(me.vertSrc)), Normals: make([]u3d.MeshDescVA3, len(me.vertSrc))}
+	for v, src := range me.vertSrc {
+		f := me.verts[v*meshVertexBaseFloats:]
+		md.Positions[src.PosIndex] = u3d.MeshDescVA3{f[0], f[1], f[2]}
+		md.TexCoords[v], md.Normals[v] = u3d.MeshDescVA2{f[3], f[4]}, u3d.MeshDescVA3{f[5], f[6], f[7]}
+	}
+	md.Faces = make([]u3d.MeshDescF3, len(me.faces))
+	for fi := 0; fi < len(me.faces); fi++ {
+		md.Faces[fi].MeshFaceBase = me.faces[fi].base
+		for ei, entry := range me.faces[fi].entries {
+			v := me.indices[entry]
+			md.Faces[fi].V[ei] = u3d.MeshDescF3V{PosIndex: me.vertSrc[v].PosIndex, TexCoordIndex: v, NormalIndex: v}
+		}
+	}
+	return
+}
+
+//	Returns true if face refers to vertex data that doesn't exist in md.
+func meshBadFace(md *u3d.MeshDescriptor, face *u3d.MeshDescF3) bool {
+	for _, v := range face.V {
+		if int(v.PosIndex) >= len(md.Positions) || int(v.TexCoordIndex) >= len(md.TexCoords) || int(v.NormalIndex) >= len(md.Normals) {
+			return true
+		}
+	}
+	return false
+}
+
+//	Sets n to the area-weighted (that is, unnormalized) normal of face in md, and returns its length
+//	relative to the squared length of the longest edge: 0 for degenerate faces.
+func meshFaceNormal(md *u3d.MeshDescriptor, face *u3d.MeshDescF3, n *unum.Vec3) float64 {
+	var p [3]unum.Vec3
+	for i := 0; i < 3; i++ {
+		md.Positions[face.V[i].PosIndex].ToVec3(&p[i])
+	}
+	e1, e2, e3 := unum.Vec3{p[1].X - p[0].X, p[1].Y - p[0].Y, p[1].Z - p[0].Z}, unum.Vec3{p[2].X - p[0].X, p[2].Y - p[0].Y, p[2].Z - p[0].Z}, unum.Vec3{p[2].X - p[1].X, p[2].Y - p[1].Y, p[2].Z - p[1].Z}
+	n.SetFromCrossOf(&e1, &e2)
+	longest := math.Max(e1.X*e1.X+e1.Y*e1.Y+e1.Z*e1.Z, math.Max(e2.X*e2.X+e2.Y*e2.Y+e2.Z*e2.Z, e3.X*e3.X+e3.Y*e3.Y+e3.Z*e3.Z))
+	if longest == 0 {
+		return 0
+	}
+	return n.Magnitude() / longest
+}
+
+//	Finds (and if params is not nil, fixes) the problems in md, as described for MeshLib.Repair().
+func meshRepair(md *u3d.MeshDescriptor, params *MeshRepairParams, issues *MeshIssues) {
+	const degenerate = 1e-9
+	var (
+		n    unum.Vec3
+		face *u3d.MeshDescF3
+	)
+	nanPos := make([]bool, len(md.Positions))
+	for pi, pos := range md.Positions {
+		for _, f := range pos {
+			if math.IsNaN(float64(f)) || math.IsInf(float64(f), 0) {
+				nanPos[pi] = true
+			}
+		}
+		if nanPos[pi] {
+			issues.NaNPositions++
+		}
+	}
+	if params != nil && params.WeldEpsilon > 0 {
+		issues.Welded = meshWeld(md, nanPos, params.WeldEpsilon)
+	}
+	faces, seen := make([]u3d.MeshDescF3, 0, len(md.Faces)), map[[3]uint32]bool{}
+	for fi := 0; fi < len(md.Faces); fi++ {
+		face = &md.Faces[fi]
+		if meshBadFace(md, face) {
+			issues.BadIndices++
+			continue
+		}
+		if nanPos[face.V[0].PosIndex] || nanPos[face.V[1].PosIndex] || nanPos[face.V[2].PosIndex] {
+			continue
+		}
+		if meshFaceNormal(md, face, &n) < degenerate {
+			issues.Degenerate++
+			continue
+		}
+		//	rotate the smallest position index first, retaining the winding order
+		key := [3]uint32{face.V[0].PosIndex, face.V[1].PosIndex, face.V[2].PosIndex}
+		for key[0] > key[1] || key[0] > key[2] {
+			key[0], key[1], key[2] = key[1], key[2], key[0]
+		}
+		if seen[key] {
+			issues.Duplicate++
+			continue
+		}
+		seen[key], faces = true, append(faces, *face)
+	}
+	edges := map[[2]uint32]int{}
+	for fi := 0; fi < len(faces); fi++ {
+		for ei := 0; ei < 3; ei++ {
+			key := [2]uint32{faces[fi].V[ei].PosIndex, faces[fi].V[(ei+1)%3].PosIndex}
+			if key[0] > key[1] {
+				key[0], key[1] = key[1], key[0]
+			}
+			if edges[key]++; edges[key] == 3 {
+				issues.NonManifoldEdges++
+			}
+		}
+	}
+	if params == nil {
+		return
+	}
+	md.Faces = faces
+	if params.FlipWinding {
+		for fi := 0; fi < len(md.Faces); fi++ {
+			md.Faces[fi].V[1], md.Faces[fi].V[2] = md.Faces[fi].V[2], md.Faces[fi].V[1]
+		}
+		if params.Normals == MeshNormalsKeep {
+			for i := 0; i < len(md.Normals); i++ {
+				md.Normals[i] = u3d.MeshDescVA3{-md.Normals[i][0], -md.Normals[i][1], -md.Normals[i][2]}
+			}
+		}
+	}
+	switch params.Normals {
+	case MeshNormalsFlat:
+		meshRecomputeNormals(md, true, 1)
+	case MeshNormalsSmooth:
+		if params.SmoothAngleDeg > 0 && params.SmoothAngleDeg < 180 {
+			meshRecomputeNormals(md, false, math.Cos(params.SmoothAngleDeg*math.Pi/180))
+		} else {
+			meshRecomputeNormals(md, false, -1)
+		}
+	}
+}
+
+//	Merges all positions in md closer than eps to an earlier one into that one, and returns how many were merged.
+//	The merged positions remain in md.Positions, just no longer referred to by any face.
+func meshWeld(md *u3d.MeshDescriptor, skip []bool, eps float64) (numWelded int) {
+	var (
+		p, q unum.Vec3
+		cell [3]int64
+	)
+	remap, cells := make([]uint32, len(md.Positions)), map[[3]int64][]uint32{}
+	for pi := 0; pi < len(md.Positions); pi++ {
+		if remap[pi] = uint32(pi); skip[pi] {
+			continue
+		}
+		md.Positions[pi].ToVec3(&p)
+		home := [3]int64{int64(math.Floor(p.X / eps)), int64(math.Floor(p.Y / eps)), int64(math.Floor(p.Z / eps))}
+	search:
+		for x := int64(-1); x <= 1; x++ {
+			for y := int64(-1); y <= 1; y++ {
+				for z := int64(-1); z <= 1; z++ {
+					cell = [3]int64{home[0] + x, home[1] + y, home[2] + z}
+					for _, other := range cells[cell] {
+						md.Positions[other].ToVec3(&q)
+						if dx, dy, dz := q.X-p.X, q.Y-p.Y, q.Z-p.Z; dx*dx+dy*dy+dz*dz < eps*eps {
+							remap[pi] = other
+							break search
+						}
+					}
+				}
+			}
+		}
+		if remap[pi] == uint32(pi) {
+			cells[home] = append(cells[home], uint32(pi))
+		} else {
+			numWelded++
+		}
+	}
+	for fi := 0; fi < len(md.Faces); fi++ {
+		for ei := 0; ei < 3; ei++ {
+			if v := &md.Faces[fi].V[ei]; int(v.PosIndex) < len(remap) {
+				v.PosIndex = remap[v.PosIndex]
+			}
+		}
+	}
+	return
+}
+
+//	Replaces all normals in md: if flat, with the normal of each face. Otherwise, each face corner gets the
+//	normalized sum of the area-weighted normals of all faces at its position whose normal is within the cosine
+//	minDot of its own face's normal, so -1 smoothes all edges.
+func meshRecomputeNormals(md *u3d.MeshDescriptor, flat bool, minDot float64) {
+	var n unum.Vec3
+	faceNormals, unitNormals := make([]unum.Vec3, len(md.Faces)), make([]unum.Vec3, len(md.Faces))
+	posFaces := make([][]int, len(md.Positions))
+	for fi := 0; fi < len(md.Faces); fi++ {
+		meshFaceNormal(md, &md.Faces[fi], &faceNormals[fi])
+		unitNormals[fi] = faceNormals[fi]
+		if l := unitNormals[fi].Magnitude(); l > 0 {
+			unitNormals[fi].Set(unitNormals[fi].X/l, unitNormals[fi].Y/l, unitNormals[fi].Z/l)
+		}
+		if flat {
+			continue
+		}
+		for _, v := range md.Faces[fi].V {
+			posFaces[v.PosIndex] = append(posFaces[v.PosIndex], fi)
+		}
+	}
+	normals, known := make([]u3d.MeshDescVA3, 0, len(md.Normals)), map[u3d.MeshDescVA3]uint32{}
+	for fi := 0; fi < len(md.Faces); fi++ {
+		for ei := 0; ei < 3; ei++ {
+			v := &md.Faces[fi].V[ei]
+			if flat {
+				n = unitNormals[fi]
+			} else {
+				n = unum.Vec3{}
+				for _, other := range posFaces[v.PosIndex] {
+					if u, o := &unitNormals[fi], &unitNormals[other]; other == fi || u.X*o.X+u.Y*o.Y+u.Z*o.Z >= minDot {
+						n.Add(&faceNormals[other])
+					}
+				}
+			}
+			if l := n.Magnitude(); l > 0 {
+				n.Set(n.X/l, n.Y/l, n.Z/l)
+			}
+			va := u3d.MeshDescVA3{float32(n.X), float32(n.Y), float32(n.Z)}
+			index, ok := known[va]
+			if !ok {
+				index, known[va], normals = uint32(len(normals)), uint32(len(normals)), append(normals, va)
+			}
+			v.NormalIndex = index
+		}
+	}
+	md.Normals = normals
+}
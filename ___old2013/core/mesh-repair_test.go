@@ -0,0 +1,143 @@
+package core
+
+import (
+	"math"
+	"testing"
+
+	u3d "github.com/metaleap/go-util-3d"
+)
+
+//	Returns a unit quad in the XY plane facing +Z, as faces (0, 1, 2) and (0, 2, 3), with extra positions
+//	appended and extra faces (by position indices, all with tex-coord and normal 0) added.
+func meshTestQuad(extraPos []u3d.MeshDescVA3, extraFaces ...[3]uint32) *u3d.MeshDescriptor {
+	md := &u3d.MeshDescriptor{
+		Positions: append([]u3d.MeshDescVA3{{0, 0, 0}, {1, 0, 0}, {1, 1, 0}, {0, 1, 0}}, extraPos...),
+		TexCoords: []u3d.MeshDescVA2{{0, 0}},
+		Normals:   []u3d.MeshDescVA3{{0, 0, 1}},
+	}
+	for _, f := range append([][3]uint32{{0, 1, 2}, {0, 2, 3}}, extraFaces...) {
+		md.Faces = append(md.Faces, u3d.MeshDescF3{V: [3]u3d.MeshDescF3V{{PosIndex: f[0]}, {PosIndex: f[1]}, {PosIndex: f[2]}}})
+	}
+	return md
+}
+
+func TestMeshRepair(t *testing.T) {
+	nan, inf := float32(math.NaN()), float32(math.Inf(1))
+	for _, test := range []struct {
+		name      string
+		md        *u3d.MeshDescriptor
+		params    MeshRepairParams
+		issues    MeshIssues
+		numFaces  int
+		positions [][3]uint32
+	}{
+		{name: "clean", md: meshTestQuad(nil),
+			numFaces: 2, positions: [][3]uint32{{0, 1, 2}, {0, 2, 3}}},
+		{name: "bad position index", md: meshTestQuad(nil, [3]uint32{0, 2, 9}),
+			issues: MeshIssues{BadIndices: 1}, numFaces: 2},
+		{name: "bad tex-coord and normal indices", md: func() *u3d.MeshDescriptor {
+			md := meshTestQuad(nil, [3]uint32{0, 1, 3}, [3]uint32{1, 2, 3})
+			md.Faces[2].V[1].TexCoordIndex, md.Faces[3].V[2].NormalIndex = 1, 1
+			return md
+		}(), issues: MeshIssues{BadIndices: 2}, numFaces: 2},
+		{name: "NaN positions", md: meshTestQuad([]u3d.MeshDescVA3{{nan, 0, 0}, {0, inf, 0}}, [3]uint32{0, 1, 4}, [3]uint32{5, 2, 3}),
+			issues: MeshIssues{NaNPositions: 2}, numFaces: 2},
+		{name: "degenerate", md: meshTestQuad([]u3d.MeshDescVA3{{0.5, 0, 0}}, [3]uint32{0, 0, 1}, [3]uint32{0, 4, 1}, [3]uint32{2, 2, 2}),
+			issues: MeshIssues{Degenerate: 3}, numFaces: 2},
+		{name: "duplicate", md: meshTestQuad(nil, [3]uint32{1, 2, 0}, [3]uint32{3, 0, 2}),
+			issues: MeshIssues{Duplicate: 2}, numFaces: 2, positions: [][3]uint32{{0, 1, 2}, {0, 2, 3}}},
+		{name: "opposite winding is no duplicate", md: meshTestQuad(nil, [3]uint32{0, 2, 1}),
+			issues: MeshIssues{NonManifoldEdges: 1}, numFaces: 3},
+		{name: "welding", md: meshTestQuad([]u3d.MeshDescVA3{{1e-6, 0, 0}, {1, 1 + 1e-6, 0}}, [3]uint32{4, 5, 3}),
+			params: MeshRepairParams{WeldEpsilon: 1e-4},
+			issues: MeshIssues{Welded: 2, Duplicate: 1}, numFaces: 2, positions: [][3]uint32{{0, 1, 2}, {0, 2, 3}}},
+		{name: "degenerate by welding", md: meshTestQuad([]u3d.MeshDescVA3{{0, 1e-6, 0}}, [3]uint32{0, 1, 4}),
+			params: MeshRepairParams{WeldEpsilon: 1e-4},
+			issues: MeshIssues{Welded: 1, Degenerate: 1}, numFaces: 2},
+		{name: "welding keeps distant positions", md: meshTestQuad([]u3d.MeshDescVA3{{0, 0, 0.01}}, [3]uint32{4, 2, 3}),
+			params:   MeshRepairParams{WeldEpsilon: 1e-4},
+			numFaces: 3, positions: [][3]uint32{{0, 1, 2}, {0, 2, 3}, {4, 2, 3}}},
+		{name: "flip winding", md: meshTestQuad(nil),
+			params:   MeshRepairParams{FlipWinding: true},
+			numFaces: 2, positions: [][3]uint32{{0, 2, 1}, {0, 3, 2}}},
+	} {
+		validated := Core.Libs.Meshes.Validate(test.md)
+		issues := Core.Libs.Meshes.Repair(test.md, &test.params)
+		if issues != test.issues {
+			t.Errorf("%v: Repair() found %v, want %v", test.name, &issues, &test.issues)
+		}
+		//	Validate() doesn't weld, so it can only find what Repair() found without welding
+		if test.params.WeldEpsilon == 0 && validated != test.issues {
+			t.Errorf("%v: Validate() found %v, want %v", test.name, &validated, &test.issues)
+		}
+		if len(test.md.Faces) != test.numFaces {
+			t.Errorf("%v: %v faces left, want %v", test.name, len(test.md.Faces), test.numFaces)
+		}
+		if after := Core.Libs.Meshes.Validate(test.md); after.BadIndices+after.Degenerate+after.Duplicate != 0 {
+			t.Errorf("%v: still found %v after Repair()", test.name, &after)
+		}
+		for fi, want := range test.positions {
+			if fi < len(test.md.Faces) {
+				if v := &test.md.Faces[fi].V; [3]uint32{v[0].PosIndex, v[1].PosIndex, v[2].PosIndex} != want {
+					t.Errorf("%v: face %v has positions %v, want %v", test.name, fi, [3]uint32{v[0].PosIndex, v[1].PosIndex, v[2].PosIndex}, want)
+				}
+			}
+		}
+	}
+}
+
+func TestMeshRepairNormals(t *testing.T) {
+	for _, test := range []struct {
+		name   string
+		params MeshRepairParams
+		normal u3d.MeshDescVA3
+	}{
+		{"keep", MeshRepairParams{}, u3d.MeshDescVA3{0, 0, 1}},
+		{"flip", MeshRepairParams{FlipWinding: true}, u3d.MeshDescVA3{0, 0, -1}},
+		{"flat", MeshRepairParams{Normals: MeshNormalsFlat}, u3d.MeshDescVA3{0, 0, 1}},
+		{"flip flat", MeshRepairParams{FlipWinding: true, Normals: MeshNormalsFlat}, u3d.MeshDescVA3{0, 0, -1}},
+		{"flip smooth", MeshRepairParams{FlipWinding: true, Normals: MeshNormalsSmooth}, u3d.MeshDescVA3{0, 0, -1}},
+	} {
+		md := meshTestQuad(nil)
+		Core.Libs.Meshes.Repair(md, &test.params)
+		for fi := 0; fi < len(md.Faces); fi++ {
+			for _, v := range md.Faces[fi].V {
+				if n := md.Normals[v.NormalIndex]; n != test.normal {
+					t.Errorf("%v: face %v has normal %v, want %v", test.name, fi, n, test.normal)
+				}
+			}
+		}
+	}
+}
+
+func TestMeshRepairMorphs(t *testing.T) {
+	mesh := meshTestLoad(t, "quad", meshTestQuad(nil), true)
+	target := &MeshMorphTarget{Name: "bulge",
+		PosDeltas:    []u3d.MeshDescVA3{{0, 0, 0}, {0, 0, 0}, {0, 0, 0.5}, {0, 0, 0}},
+		NormalDeltas: []u3d.MeshDescVA3{{0.25, 0, 0}}}
+	if _, err := mesh.AddMorphTarget(target); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := mesh.Repair(&MeshRepairParams{FlipWinding: true}); err != nil {
+		t.Fatal(err)
+	}
+	if mesh.NumMorphTargets() != 1 || mesh.MorphTargetIndex("bulge") != 0 {
+		t.Fatalf("got %v morph targets after Repair(), want 1", mesh.NumMorphTargets())
+	}
+	deltas := mesh.raw.morphs[0].deltas
+	if len(deltas) != 8*len(mesh.raw.vertSrc) {
+		t.Fatalf("got %v morph deltas for %v vertices", len(deltas), len(mesh.raw.vertSrc))
+	}
+	for v, src := range mesh.raw.vertSrc {
+		d := deltas[8*v:]
+		if pos := target.PosDeltas[src.PosIndex]; d[0] != pos[0] || d[1] != pos[1] || d[2] != pos[2] {
+			t.Errorf("vertex %v at position %v has position delta %v, want %v", v, src.PosIndex, d[0:3], pos)
+		}
+		if d[4] != -0.25 || d[5] != 0 || d[6] != 0 {
+			t.Errorf("vertex %v has normal delta %v, want it negated to [-0.25 0 0]", v, d[4:7])
+		}
+	}
+	if mesh.raw.bounding.AaBox.Max.Z < 0.5 {
+		t.Errorf("bounds reach up to Z = %v, want them to cover the morph target", mesh.raw.bounding.AaBox.Max.Z)
+	}
+}
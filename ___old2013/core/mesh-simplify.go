@@ -0,0 +1,404 @@
+package core
+
+import (
+	"container/heap"
+	"math"
+
+	"github.com/metaleap/go-util-num"
+	u3d "github.com/metaleap/go-util-3d"
+)
+
+//	How much more the border, seam and face-tag boundary constraints of a mesh weigh in the
+//	simplification error than its surface, keeping those boundaries in place until last.
+const meshSimplifyBorderWeight = 1000
+
+//	Controls MeshLib.AddNewSimplified().
+type MeshSimplifyParams struct {
+	//	The fraction of the source mesh's triangles to keep, such as 0.5. If 0, only MaxError applies.
+	TargetRatio float64
+
+	//	If greater than 0, simplification stops before any edge collapse that would exceed this
+	//	error: the sum of squared distances of the moved vertex to the planes of its original faces.
+	MaxError float64
+}
+
+//	Adds a new Mesh to me, simplified from the loaded source mesh by quadric-error-metric edge collapses
+//	until params are met. Its vertices are a subset of those of the source mesh, so UV seams, normals,
+//	face tags and IDs (and thus per-face effects), skinning data and morph targets are all preserved.
+//	Vertices are only ever moved along (not across) UV seams, mesh borders and boundaries between faces
+//	of different tags. The new mesh is not added to any MeshBuffer.
+func (me *MeshLib) AddNewSimplified(name string, srcMeshID int, params *MeshSimplifyParams) (meshID int, err error) {
+	meshID = -1
+	src := me.get(srcMeshID)
+	if src == nil || len(src.raw.vertSrc) == 0 {
+		err = errf("Cannot simplify mesh %v: invalid mesh ID or mesh not loaded", srcMeshID)
+		return
+	} else if params.TargetRatio <= 0 && params.MaxError <= 0 {
+		err = errf("Cannot simplify mesh '%v': neither TargetRatio nor MaxError specified", src.Name)
+		return
+	}
+	simp := newMeshSimplifier(&src.raw)
+	simp.run(params)
+	md, srcVerts, srcPositions := simp.meshDescriptor()
+	if meshID, err = me.AddNewAndLoad(name, func() (*u3d.MeshDescriptor, error) { return md, nil }); err == nil {
+		mesh, srcRaw := &(*me)[meshID], &me.get(srcMeshID).raw
+		if srcRaw.skin != nil {
+			skin := *srcRaw.skin
+			skin.vertAtts = make([][2 * meshSkinInfluencesPerVertex]float32, len(srcPositions))
+			for pi, spi := range srcPositions {
+				if int(spi) < len(srcRaw.skin.vertAtts) {
+					skin.vertAtts[pi] = srcRaw.skin.vertAtts[spi]
+				}
+			}
+			mesh.raw.skin = &skin
+		}
+		for _, srcMorph := range srcRaw.morphs {
+			morph := meshRawMorph{name: srcMorph.name, deltas: make([]float32, meshVertexBaseFloats*len(mesh.raw.vertSrc))}
+			for v, vs := range mesh.raw.vertSrc {
+				sv := srcVerts[vs.TexCoordIndex]
+				copy(morph.deltas[meshVertexBaseFloats*v:meshVertexBaseFloats*(v+1)], srcMorph.deltas[meshVertexBaseFloats*sv:meshVertexBaseFloats*(sv+1)])
+			}
+			mesh.raw.morphs = append(mesh.raw.morphs, morph)
+			mesh.raw.expandMorphBounds(&mesh.raw.morphs[len(mesh.raw.morphs)-1])
+		}
+		Diag.LogMeshes("mesh{%v} simplified from %v to %v faces", name, len(srcRaw.faces), len(mesh.raw.faces))
+	}
+	return
+}
+
+//	A symmetric 4x4 matrix of plane equations, see "Surface Simplification Using Quadric Error Metrics"
+//	(Garland, Heckbert 1997), stored as its upper triangle.
+type meshQuadric [10]float64
+
+//	Adds the weighted quadric of the plane with normal n (normalized) through p.
+func (me *meshQuadric) addPlane(n, p *unum.Vec3, weight float64) {
+	a, b, c := n.X, n.Y, n.Z
+	d := -(a*p.X + b*p.Y + c*p.Z)
+	me[0] += weight * a * a
+	me[1] += weight * a * b
+	me[2] += weight * a * c
+	me[3] += weight * a * d
+	me[4] += weight * b * b
+	me[5] += weight * b * c
+	me[6] += weight * b * d
+	me[7] += weight * c * c
+	me[8] += weight * c * d
+	me[9] += weight * d * d
+}
+
+//	Returns the error of placing a vertex with both quadrics me and q at v.
+func (me *meshQuadric) eval(q *meshQuadric, v *unum.Vec3) float64 {
+	var s meshQuadric
+	for i := 0; i < len(s); i++ {
+		s[i] = me[i] + q[i]
+	}
+	x, y, z := v.X, v.Y, v.Z
+	return s[0]*x*x + 2*s[1]*x*y + 2*s[2]*x*z + 2*s[3]*x + s[4]*y*y + 2*s[5]*y*z + 2*s[6]*y + s[7]*z*z + 2*s[8]*z + s[9]
+}
+
+//	A candidate collapse of node from into node to, see meshSimplifier.
+type meshCollapse struct {
+	cost                         float64
+	from, to, stampFrom, stampTo int
+}
+
+type meshCollapseHeap []meshCollapse
+
+func (me meshCollapseHeap) Len() int {
+	return len(me)
+}
+
+func (me meshCollapseHeap) Less(i, j int) bool {
+	return me[i].cost < me[j].cost
+}
+
+func (me meshCollapseHeap) Swap(i, j int) {
+	me[i], me[j] = me[j], me[i]
+}
+
+func (me *meshCollapseHeap) Push(x interface{}) {
+	*me = append(*me, x.(meshCollapse))
+}
+
+func (me *meshCollapseHeap) Pop() (x interface{}) {
+	x, *me = (*me)[len(*me)-1], (*me)[:len(*me)-1]
+	return
+}
+
+//	Simplifies a meshRaw by half-edge collapses: a "node" is a distinct source position, shared by
+//	all final vertices (seam copies) at that position, and collapsing node a into node b moves each
+//	final vertex of a onto its counterpart of b across the collapsed edge.
+type meshSimplifier struct {
+	raw *meshRaw
+
+	//	Per node
+	pos       []unum.Vec3
+	quadrics  []meshQuadric
+	faces     [][]int
+	stamps    []int
+	srcPosIdx []uint32
+
+	//	Per final vertex
+	vertNode []int
+
+	//	Per face: final vertices, and whether not yet collapsed
+	tris     [][3]int
+	triAlive []bool
+	numTris  int
+
+	queue meshCollapseHeap
+}
+
+func newMeshSimplifier(raw *meshRaw) (me *meshSimplifier) {
+	me = &meshSimplifier{raw: raw, numTris: len(raw.faces)}
+	nodes := map[uint32]int{}
+	me.vertNode = make([]int, len(raw.vertSrc))
+	for v, src := range raw.vertSrc {
+		node, ok := nodes[src.PosIndex]
+		if !ok {
+			node, nodes[src.PosIndex] = len(me.pos), len(me.pos)
+			f := raw.verts[v*meshVertexBaseFloats:]
+			me.pos = append(me.pos, unum.Vec3{float64(f[0]), float64(f[1]), float64(f[2])})
+			me.srcPosIdx = append(me.srcPosIdx, src.PosIndex)
+		}
+		me.vertNode[v] = node
+	}
+	me.quadrics, me.faces, me.stamps = make([]meshQuadric, len(me.pos)), make([][]int, len(me.pos)), make([]int, len(me.pos))
+	me.tris, me.triAlive = make([][3]int, len(raw.faces)), make([]bool, len(raw.faces))
+	type edgeUse struct {
+		face int
+		v0   int
+		v1   int
+	}
+	edges := map[[2]int][]edgeUse{}
+	var n unum.Vec3
+	for f := 0; f < len(raw.faces); f++ {
+		for ei := 0; ei < 3; ei++ {
+			me.tris[f][ei] = int(raw.indices[raw.faces[f].entries[ei]])
+		}
+		me.triAlive[f] = true
+		area := me.triNormal(f, -1, nil, &n)
+		for ei := 0; ei < 3; ei++ {
+			node := me.vertNode[me.tris[f][ei]]
+			me.faces[node] = append(me.faces[node], f)
+			me.quadrics[node].addPlane(&n, &me.pos[node], area)
+			v0, v1 := me.tris[f][ei], me.tris[f][(ei+1)%3]
+			key := [2]int{me.vertNode[v0], me.vertNode[v1]}
+			if key[0] > key[1] {
+				key[0], key[1], v0, v1 = key[1], key[0], v1, v0
+			}
+			edges[key] = append(edges[key], edgeUse{face: f, v0: v0, v1: v1})
+		}
+	}
+	//	constrain borders, UV seams and face-tag boundaries to their current course
+	for key, uses := range edges {
+		border := len(uses) != 2 || uses[0].v0 != uses[1].v0 || uses[0].v1 != uses[1].v1 || !meshFaceTagsEqual(&raw.faces[uses[0].face].base, &raw.faces[uses[1].face].base)
+		if border {
+			for _, use := range uses {
+				me.addBorderQuadric(key[0], key[1], use.face)
+			}
+		}
+	}
+	for key := range edges {
+		me.pushCollapse(key[0], key[1])
+		me.pushCollapse(key[1], key[0])
+	}
+	heap.Init(&me.queue)
+	return
+}
+
+//	Adds to both nodes of edge a-b of face f the quadric of the plane through the edge, perpendicular to f.
+func (me *meshSimplifier) addBorderQuadric(a, b, f int) {
+	var n, e, m unum.Vec3
+	me.triNormal(f, -1, nil, &n)
+	e.Set(me.pos[b].X-me.pos[a].X, me.pos[b].Y-me.pos[a].Y, me.pos[b].Z-me.pos[a].Z)
+	m.Set(e.Y*n.Z-e.Z*n.Y, e.Z*n.X-e.X*n.Z, e.X*n.Y-e.Y*n.X)
+	if l := m.Magnitude(); l > 0 {
+		m.Set(m.X/l, m.Y/l, m.Z/l)
+		weight := meshSimplifyBorderWeight * (e.X*e.X + e.Y*e.Y + e.Z*e.Z)
+		me.quadrics[a].addPlane(&m, &me.pos[a], weight)
+		me.quadrics[b].addPlane(&m, &me.pos[b], weight)
+	}
+}
+
+//	Returns true if a and b have the same ID-independent tags.
+func meshFaceTagsEqual(a, b *u3d.MeshFaceBase) bool {
+	if len(a.Tags) != len(b.Tags) {
+		return false
+	}
+	for i := 0; i < len(a.Tags); i++ {
+		if a.Tags[i] != b.Tags[i] {
+			return false
+		}
+	}
+	return true
+}
+
+//	Sets n to the unit normal of face f, with node moved (if not -1) to pos, and returns the face's area.
+func (me *meshSimplifier) triNormal(f, node int, pos *unum.Vec3, n *unum.Vec3) float64 {
+	var p [3]*unum.Vec3
+	for i := 0; i < 3; i++ {
+		if p[i] = &me.pos[me.vertNode[me.tris[f][i]]]; node >= 0 && me.vertNode[me.tris[f][i]] == node {
+			p[i] = pos
+		}
+	}
+	e1x, e1y, e1z := p[1].X-p[0].X, p[1].Y-p[0].Y, p[1].Z-p[0].Z
+	e2x, e2y, e2z := p[2].X-p[0].X, p[2].Y-p[0].Y, p[2].Z-p[0].Z
+	n.Set(e1y*e2z-e1z*e2y, e1z*e2x-e1x*e2z, e1x*e2y-e1y*e2x)
+	l := n.Magnitude()
+	if l > 0 {
+		n.Set(n.X/l, n.Y/l, n.Z/l)
+	}
+	return l / 2
+}
+
+func (me *meshSimplifier) pushCollapse(from, to int) {
+	me.queue = append(me.queue, meshCollapse{cost: me.quadrics[from].eval(&me.quadrics[to], &me.pos[to]), from: from, to: to, stampFrom: me.stamps[from], stampTo: me.stamps[to]})
+}
+
+func (me *meshSimplifier) run(params *MeshSimplifyParams) {
+	target := int(math.Ceil(params.TargetRatio * float64(me.numTris)))
+	for me.queue.Len() > 0 && me.numTris > target {
+		c := heap.Pop(&me.queue).(meshCollapse)
+		if c.stampFrom != me.stamps[c.from] || c.stampTo != me.stamps[c.to] {
+			continue
+		}
+		if params.MaxError > 0 && c.cost > params.MaxError {
+			break
+		}
+		me.collapse(c.from, c.to)
+	}
+}
+
+//	Collapses node a into node b, unless this would tear a UV seam, flip a face or make the mesh non-manifold.
+func (me *meshSimplifier) collapse(a, b int) (ok bool) {
+	var (
+		ei, ai, bi int
+		n0, n1     unum.Vec3
+	)
+	vertMap, shared, neighborsA, neighborsB := map[int]int{}, 0, map[int]bool{}, map[int]bool{}
+	for _, f := range me.faces[b] {
+		if me.triAlive[f] {
+			for ei = 0; ei < 3; ei++ {
+				neighborsB[me.vertNode[me.tris[f][ei]]] = true
+			}
+		}
+	}
+	for _, f := range me.faces[a] {
+		if !me.triAlive[f] {
+			continue
+		}
+		ai, bi = -1, -1
+		for ei = 0; ei < 3; ei++ {
+			switch node := me.vertNode[me.tris[f][ei]]; node {
+			case a:
+				ai = ei
+			case b:
+				bi = ei
+			default:
+				neighborsA[node] = true
+			}
+		}
+		if bi >= 0 {
+			shared++
+			va, vb := me.tris[f][ai], me.tris[f][bi]
+			if mapped, exists := vertMap[va]; exists && mapped != vb {
+				return
+			}
+			vertMap[va] = vb
+		}
+	}
+	//	link condition: a and b may only share the neighbors opposite their common edge
+	numCommon := 0
+	for node := range neighborsA {
+		if neighborsB[node] {
+			numCommon++
+		}
+	}
+	if shared == 0 || numCommon > shared {
+		return
+	}
+	for _, f := range me.faces[a] {
+		if me.triAlive[f] && !me.triHasNode(f, b) {
+			for ei = 0; ei < 3; ei++ {
+				if v := me.tris[f][ei]; me.vertNode[v] == a {
+					if _, exists := vertMap[v]; !exists {
+						//	a final vertex of a not adjacent to b: would tear a seam
+						return
+					}
+				}
+			}
+			if me.triNormal(f, -1, nil, &n0) > 0 && (me.triNormal(f, a, &me.pos[b], &n1) == 0 || n0.X*n1.X+n0.Y*n1.Y+n0.Z*n1.Z < 0.2) {
+				return
+			}
+		}
+	}
+	for _, f := range me.faces[a] {
+		if !me.triAlive[f] {
+			continue
+		}
+		if me.triHasNode(f, b) {
+			me.triAlive[f], me.numTris = false, me.numTris-1
+			continue
+		}
+		for ei = 0; ei < 3; ei++ {
+			if v := me.tris[f][ei]; me.vertNode[v] == a {
+				me.tris[f][ei] = vertMap[v]
+			}
+		}
+		me.faces[b] = append(me.faces[b], f)
+	}
+	for i := 0; i < len(me.quadrics[b]); i++ {
+		me.quadrics[b][i] += me.quadrics[a][i]
+	}
+	me.faces[a] = nil
+	me.stamps[a]++
+	me.stamps[b]++
+	for node := range neighborsA {
+		neighborsB[node] = true
+	}
+	delete(neighborsB, a)
+	delete(neighborsB, b)
+	for node := range neighborsB {
+		heap.Push(&me.queue, meshCollapse{cost: me.quadrics[node].eval(&me.quadrics[b], &me.pos[b]), from: node, to: b, stampFrom: me.stamps[node], stampTo: me.stamps[b]})
+		heap.Push(&me.queue, meshCollapse{cost: me.quadrics[b].eval(&me.quadrics[node], &me.pos[node]), from: b, to: node, stampFrom: me.stamps[b], stampTo: me.stamps[node]})
+	}
+	return true
+}
+
+func (me *meshSimplifier) triHasNode(f, node int) bool {
+	return me.vertNode[me.tris[f][0]] == node || me.vertNode[me.tris[f][1]] == node || me.vertNode[me.tris[f][2]] == node
+}
+
+//	Returns the remaining faces as a u3d.MeshDescriptor whose TexCoords and Normals are indexed per source
+//	final vertex (as per srcVerts) and whose Positions correspond to the source positions in srcPositions.
+func (me *meshSimplifier) meshDescriptor() (md *u3d.MeshDescriptor, srcVerts []int, srcPositions []uint32) {
+	md = &u3d.MeshDescriptor{}
+	posMap, vertMap := map[int]uint32{}, map[int]uint32{}
+	for f := 0; f < len(me.tris); f++ {
+		if !me.triAlive[f] {
+			continue
+		}
+		face := u3d.MeshDescF3{MeshFaceBase: me.raw.faces[f].base}
+		for ei, v := range me.tris[f] {
+			node := me.vertNode[v]
+			pi, ok := posMap[node]
+			if !ok {
+				pi, posMap[node] = uint32(len(md.Positions)), uint32(len(md.Positions))
+				md.Positions = append(md.Positions, u3d.MeshDescVA3{float32(me.pos[node].X), float32(me.pos[node].Y), float32(me.pos[node].Z)})
+				srcPositions = append(srcPositions, me.srcPosIdx[node])
+			}
+			vi, ok := vertMap[v]
+			if !ok {
+				vi, vertMap[v] = uint32(len(md.TexCoords)), uint32(len(md.TexCoords))
+				fl := me.raw.verts[v*meshVertexBaseFloats:]
+				md.TexCoords = append(md.TexCoords, u3d.MeshDescVA2{fl[3], fl[4]})
+				md.Normals = append(md.Normals, u3d.MeshDescVA3{fl[5], fl[6], fl[7]})
+				srcVerts = append(srcVerts, v)
+			}
+			face.V[ei] = u3d.MeshDescF3V{PosIndex: pi, TexCoordIndex: vi, NormalIndex: vi}
+		}
+		md.Faces = append(md.Faces, face)
+	}
+	return
+}
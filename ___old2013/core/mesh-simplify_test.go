@@ -0,0 +1,97 @@
+package core
+
+import (
+	"math"
+	"testing"
+
+	u3d "github.com/metaleap/go-util-3d"
+)
+
+//	Simplifies a loaded 1x1 grid of cols x rows quads as per params.
+func meshTestSimplifyGrid(t *testing.T, cols, rows int, params *MeshSimplifyParams) (simp *meshSimplifier, md *u3d.MeshDescriptor) {
+	mesh := meshTestLoad(t, "grid", meshTestGrid(t, cols, rows, false), true)
+	simp = newMeshSimplifier(&mesh.raw)
+	simp.run(params)
+	md, _, _ = simp.meshDescriptor()
+	return
+}
+
+func TestMeshSimplifyTarget(t *testing.T) {
+	for _, test := range []struct {
+		cols, rows int
+		ratio      float64
+	}{
+		{8, 8, 0.5},
+		{16, 16, 0.25},
+		{16, 16, 0.1},
+		{30, 20, 0.3},
+	} {
+		simp, md := meshTestSimplifyGrid(t, test.cols, test.rows, &MeshSimplifyParams{TargetRatio: test.ratio})
+		//	each collapse removes the 1 or 2 faces along its edge, so it may overshoot the target by 1
+		numFaces, target := 2*test.cols*test.rows, int(math.Ceil(test.ratio*float64(2*test.cols*test.rows)))
+		if len(md.Faces) != simp.numTris {
+			t.Errorf("grid %vx%v: %v faces left, but counted %v", test.cols, test.rows, len(md.Faces), simp.numTris)
+		}
+		if len(md.Faces) > target || len(md.Faces) < target-1 {
+			t.Errorf("grid %vx%v: simplified from %v to %v faces, want %v", test.cols, test.rows, numFaces, len(md.Faces), target)
+		}
+	}
+}
+
+func TestMeshSimplifyMaxError(t *testing.T) {
+	//	all collapses on a flat grid are free, but none of them on a curved one
+	_, md := meshTestSimplifyGrid(t, 8, 8, &MeshSimplifyParams{MaxError: 1e-9})
+	if len(md.Faces) >= 2*8*8 {
+		t.Errorf("flat grid: no faces collapsed at zero error")
+	}
+	mesh := meshTestLoad(t, "curved", meshTestGrid(t, 8, 8, false), true)
+	for v := 0; v < len(mesh.raw.vertSrc); v++ {
+		f := mesh.raw.verts[v*meshVertexBaseFloats:]
+		f[1] = 4 * (f[0]*f[0] + f[2]*f[2])
+	}
+	simp := newMeshSimplifier(&mesh.raw)
+	if simp.run(&MeshSimplifyParams{MaxError: 1e-9}); simp.numTris != 2*8*8 {
+		t.Errorf("curved grid: %v of %v faces left at zero error", simp.numTris, 2*8*8)
+	}
+}
+
+func TestMeshSimplifyBorders(t *testing.T) {
+	for _, ratio := range []float64{0.5, 0.2, 0.05} {
+		_, md := meshTestSimplifyGrid(t, 12, 12, &MeshSimplifyParams{TargetRatio: ratio})
+		//	count the uses of each undirected edge: those used once form the border
+		edges := map[[2]uint32]int{}
+		area := 0.0
+		for _, face := range md.Faces {
+			for ei := 0; ei < 3; ei++ {
+				a, b := face.V[ei].PosIndex, face.V[(ei+1)%3].PosIndex
+				if a > b {
+					a, b = b, a
+				}
+				edges[[2]uint32{a, b}]++
+			}
+			p0, p1, p2 := md.Positions[face.V[0].PosIndex], md.Positions[face.V[1].PosIndex], md.Positions[face.V[2].PosIndex]
+			//	signed area in the XZ plane: flipped faces would cancel out others
+			area -= 0.5 * float64((p1[2]-p0[2])*(p2[0]-p0[0])-(p1[0]-p0[0])*(p2[2]-p0[2]))
+		}
+		perimeter := 0.0
+		for edge, uses := range edges {
+			if uses == 1 {
+				a, b := md.Positions[edge[0]], md.Positions[edge[1]]
+				onX := math.Abs(math.Abs(float64(a[0]))-0.5) < 1e-6 && a[0] == b[0]
+				onZ := math.Abs(math.Abs(float64(a[2]))-0.5) < 1e-6 && a[2] == b[2]
+				if !(onX || onZ) {
+					t.Errorf("ratio %v: border edge from %v to %v is off the grid's outline", ratio, a, b)
+				}
+				perimeter += math.Hypot(float64(b[0]-a[0]), float64(b[2]-a[2]))
+			} else if uses != 2 {
+				t.Errorf("ratio %v: edge %v is used by %v faces", ratio, edge, uses)
+			}
+		}
+		if math.Abs(perimeter-4) > 1e-5 {
+			t.Errorf("ratio %v: border is %v long, want 4", ratio, perimeter)
+		}
+		if math.Abs(math.Abs(area)-1) > 1e-5 {
+			t.Errorf("ratio %v: faces cover an area of %v, want 1", ratio, math.Abs(area))
+		}
+	}
+}
@@ -0,0 +1,161 @@
+package core
+
+import (
+	"sort"
+
+	"github.com/metaleap/go-util-num"
+	u3d "github.com/metaleap/go-util-3d"
+)
+
+//	The number of joints that can influence a single vertex of a skinned Mesh.
+const meshSkinInfluencesPerVertex = 4
+
+//	Describes how the vertices of a Mesh are bound to the joints of a skeleton, see Mesh.LoadSkinned().
+//
+//	A skeleton is simply a sub-tree of SceneNodes: each joint is a node, looked up by its SceneNode.Name
+//	in the sub-tree of the skinned node's Render.Skeleton, and posed via its Transform (or by AnimTracks).
+type MeshSkin struct {
+	//	Applied to all vertex positions before skinning. If zero, the identity matrix is used.
+	BindShapeMatrix unum.Mat4
+
+	//	At most Options.Rendering.SkinMaxJoints.
+	Joints []MeshSkinJoint
+
+	//	The joint influences per position in the u3d.MeshDescriptor, indexed like its Positions.
+	//	Only the 4 strongest influences of each position are used, with their weights normalized.
+	//	Positions without influences are not skinned.
+	Influences [][]MeshSkinInfluence
+}
+
+//	A single joint in a MeshSkin.
+type MeshSkinJoint struct {
+	//	The SceneNode.Name of the node acting as this joint.
+	Name string
+
+	//	Transforms from the bind-pose space of the mesh into the local space of this joint.
+	InvBindMatrix unum.Mat4
+}
+
+//	The influence of a single joint on a vertex position.
+type MeshSkinInfluence struct {
+	//	The index of the joint in MeshSkin.Joints.
+	Joint int
+
+	Weight float64
+}
+
+type meshSkinInfluences []MeshSkinInfluence
+
+func (me meshSkinInfluences) Len() int {
+	return len(me)
+}
+
+func (me meshSkinInfluences) Less(i, j int) bool {
+	return me[i].Weight > me[j].Weight
+}
+
+func (me meshSkinInfluences) Swap(i, j int) {
+	me[i], me[j] = me[j], me[i]
+}
+
+//	The skinning data of a meshRaw, prepared from a MeshSkin.
+type meshRawSkin struct {
+	joints []MeshSkinJoint
+
+	//	BindShapeMatrix pre-multiplied with each joint's InvBindMatrix.
+	jointMats []unum.Mat4
+
+	//	Per joint, the bounding box (in the joint's local space) of all vertices it influences,
+	//	if any (as per jointBoxOk).
+	jointBoxes []u3d.AaBb
+	jointBoxOk []bool
+
+	//	Per position: joint indices followed by weights, meshSkinInfluencesPerVertex each.
+	vertAtts [][2 * meshSkinInfluencesPerVertex]float32
+}
+
+//	Writes the skinning vertex attributes of the specified position to dst.
+func (me *meshRawSkin) writeVertAtts(posIndex uint32, dst []float32) {
+	if int(posIndex) < len(me.vertAtts) {
+		copy(dst, me.vertAtts[posIndex][:])
+	} else {
+		for i := 0; i < len(dst); i++ {
+			dst[i] = 0
+		}
+	}
+}
+
+func newMeshRawSkin(skin *MeshSkin, meshData *u3d.MeshDescriptor) (me *meshRawSkin, err error) {
+	if len(skin.Joints) > Options.Rendering.SkinMaxJoints {
+		err = errf("Mesh skin has %v joints, but Options.Rendering.SkinMaxJoints is %v", len(skin.Joints), Options.Rendering.SkinMaxJoints)
+		return
+	}
+	me = &meshRawSkin{joints: append([]MeshSkinJoint(nil), skin.Joints...)}
+	me.jointMats, me.jointBoxes = make([]unum.Mat4, len(skin.Joints)), make([]u3d.AaBb, len(skin.Joints))
+	bindShape := skin.BindShapeMatrix
+	if bindShape == (unum.Mat4{}) {
+		bindShape.Identity()
+	}
+	for j := 0; j < len(skin.Joints); j++ {
+		me.jointMats[j].SetFromMult4(&skin.Joints[j].InvBindMatrix, &bindShape)
+	}
+	var (
+		pos, jpos unum.Vec3
+		infl      meshSkinInfluences
+		sum       float64
+		i         int
+	)
+	me.jointBoxOk = make([]bool, len(skin.Joints))
+	me.vertAtts = make([][2 * meshSkinInfluencesPerVertex]float32, len(meshData.Positions))
+	for pi := 0; pi < len(meshData.Positions) && pi < len(skin.Influences); pi++ {
+		infl = append(infl[:0], skin.Influences[pi]...)
+		sort.Sort(infl)
+		if len(infl) > meshSkinInfluencesPerVertex {
+			infl = infl[:meshSkinInfluencesPerVertex]
+		}
+		for sum, i = 0, 0; i < len(infl); i++ {
+			if infl[i].Joint < 0 || infl[i].Joint >= len(skin.Joints) {
+				me, err = nil, errf("Mesh skin influence of position %v refers to unknown joint %v", pi, infl[i].Joint)
+				return
+			}
+			sum += infl[i].Weight
+		}
+		if sum > 0 {
+			meshData.Positions[pi].ToVec3(&pos)
+			for i = 0; i < len(infl); i++ {
+				me.vertAtts[pi][i] = float32(infl[i].Joint)
+				me.vertAtts[pi][meshSkinInfluencesPerVertex+i] = float32(infl[i].Weight / sum)
+				if infl[i].Weight > 0 {
+					mat4MultPoint(&jpos, &me.jointMats[infl[i].Joint], &pos)
+					if me.jointBoxOk[infl[i].Joint] {
+						me.jointBoxes[infl[i].Joint].UpdateMinMax(&jpos)
+					} else {
+						me.jointBoxOk[infl[i].Joint] = true
+						me.jointBoxes[infl[i].Joint].Min, me.jointBoxes[infl[i].Joint].Max = jpos, jpos
+					}
+				}
+			}
+		}
+	}
+	for j := 0; j < len(me.jointBoxes); j++ {
+		me.jointBoxes[j].SetCenterExtent()
+	}
+	return
+}
+
+//	Loads the geometry provided by provider, just like Load(), along with the specified skinning data.
+//	Nodes rendering me are only skinned if their Render.Skeleton is set.
+func (me *Mesh) LoadSkinned(provider u3d.MeshProvider, skin *MeshSkin) (err error) {
+	var meshData *u3d.MeshDescriptor
+	if meshData, err = provider(); err == nil && meshData != nil {
+		if me.raw.skin, err = newMeshRawSkin(skin, meshData); err == nil {
+			err = me.load(meshData)
+		}
+	}
+	return
+}
+
+//	Returns true if me was loaded with skinning data via LoadSkinned().
+func (me *Mesh) Skinned() bool {
+	return me.raw.skin != nil
+}
@@ -0,0 +1,235 @@
+package core
+
+import (
+	"math"
+
+	gl "github.com/metaleap/go-opengl/core"
+	ugl "github.com/metaleap/go-opengl/util"
+)
+
+//	The number of floats of the position, tex-coord and normal that every vertex in a MeshBuffer has.
+const meshVertexBaseFloats = 3 + 2 + 3
+
+//	Describes which optional attributes the vertices in a MeshBuffer carry, in addition to
+//	the position, tex-coord and normal that all vertices have. Vertices are interleaved in this
+//	order: position, tex-coord, normal, tangent, color, second tex-coord, joint indices & weights.
+type MeshVertexLayout struct {
+	//	Per-vertex tangent (xyz) and bitangent sign (w). For meshes without tangents
+	//	set via Mesh.SetVertexAttribs(), they are generated from normals and tex-coords.
+	Tangents bool
+
+	//	Per-vertex RGBA color. Opaque white for meshes without colors set via Mesh.SetVertexAttribs().
+	Colors bool
+
+	//	A second set of tex-coords (such as for light maps). For meshes without a second set
+	//	specified via Mesh.SetVertexAttribs(), a copy of the first set.
+	TexCoords2 bool
+
+	//	Joint indices and weights for skinning. Zero for meshes not loaded via Mesh.LoadSkinned().
+	//	Meshes in a MeshBuffer without this are never rendered skinned.
+	Skin bool
+}
+
+//	Returns the number of floats of a single vertex in me.
+func (me *MeshVertexLayout) FloatsPerVertex() (num int32) {
+	num, _, _, _, _ = me.offsets()
+	return
+}
+
+//	Returns the size in bytes of a single vertex in me.
+func (me *MeshVertexLayout) MemSizePerVertex() int32 {
+	const sizePerFloat int32 = 4
+	return sizePerFloat * me.FloatsPerVertex()
+}
+
+//	Returns the float offsets inside a vertex of the optional attributes, each -1 if not in me,
+//	and the total number of floats per vertex.
+func (me *MeshVertexLayout) offsets() (num, tangent, color, tex2, skin int32) {
+	tangent, color, tex2, skin, num = -1, -1, -1, -1, meshVertexBaseFloats
+	if me.Tangents {
+		tangent, num = num, num+4
+	}
+	if me.Colors {
+		color, num = num, num+4
+	}
+	if me.TexCoords2 {
+		tex2, num = num, num+2
+	}
+	if me.Skin {
+		skin, num = num, num+2*meshSkinInfluencesPerVertex
+	}
+	return
+}
+
+//	Returns the attribute pointers for all vertex attributes in me except the joint indices & weights,
+//	which only programs of the "SceneSkin" technique variant use.
+func (me *MeshVertexLayout) vertexAttribPointers() (atts []ugl.VertexAttribPointer) {
+	num, tangent, color, tex2, _ := me.offsets()
+	stride := gl.Sizei(4 * num)
+	att := func(name string, size, offset int32) {
+		atts = append(atts, ugl.VertexAttribPointer{})
+		atts[len(atts)-1].Init(name, gl.Int(size), stride, gl.Util.PtrOffset(nil, uintptr(4*offset)))
+	}
+	att("att_vec3_Pos", 3, 0)
+	att("att_vec2_Tex2D", 2, 3)
+	att("att_vec3_Normal", 3, 5)
+	if tangent >= 0 {
+		att("att_vec4_Tangent", 4, tangent)
+	}
+	if color >= 0 {
+		att("att_vec4_Color", 4, color)
+	}
+	if tex2 >= 0 {
+		att("att_vec2_Tex2DUv2", 2, tex2)
+	}
+	return
+}
+
+//	Optional per-vertex attributes of a Mesh beyond what a u3d.MeshDescriptor provides, see Mesh.SetVertexAttribs().
+//	All are indexed like the Positions of the u3d.MeshDescriptor that the Mesh was loaded from,
+//	and each may be shorter than those (or empty), in which case the defaults described
+//	in MeshVertexLayout apply to the missing entries.
+type MeshVertexAttribs struct {
+	//	Tangents (xyz) with the bitangent sign (w, either 1 or -1).
+	Tangents [][4]float32
+
+	//	RGBA vertex colors.
+	Colors [][4]float32
+
+	//	The second set of tex-coords.
+	TexCoords2 [][2]float32
+}
+
+//	Sets the optional vertex attributes of me. me must be loaded (and not since Unload()ed).
+//	Only those attributes present in the MeshVertexLayout of me's MeshBuffer are uploaded.
+//	For these to take effect on the GPU, call GpuUpload() subsequently.
+func (me *Mesh) SetVertexAttribs(attribs *MeshVertexAttribs) (err error) {
+	if len(me.raw.vertSrc) == 0 {
+		err = errf("Cannot set vertex attributes of mesh '%v': mesh is not loaded", me.Name)
+	} else {
+		me.raw.attribs = *attribs
+		me.gpuSynced = false
+	}
+	return
+}
+
+//	Writes all vertices of me, interleaved as per layout, to verts (re-allocated if too small) and returns it.
+func (me *meshRaw) vertsFor(layout *MeshVertexLayout, verts []float32) []float32 {
+	var (
+		tangents [][4]float32
+		src      uint32
+		f        []float32
+	)
+	num, tangent, color, tex2, skin := layout.offsets()
+	numVerts := len(me.vertSrc)
+	if size := int(num) * numVerts; cap(verts) < size {
+		verts = make([]float32, size)
+	} else {
+		verts = verts[:size]
+	}
+	if tangent >= 0 {
+		tangents = me.tangents()
+	}
+	for v := 0; v < numVerts; v++ {
+		f, src = verts[v*int(num):(v+1)*int(num)], me.vertSrc[v].PosIndex
+		copy(f, me.verts[v*meshVertexBaseFloats:(v+1)*meshVertexBaseFloats])
+		if tangent >= 0 {
+			copy(f[tangent:tangent+4], tangents[v][:])
+		}
+		if color >= 0 {
+			if int(src) < len(me.attribs.Colors) {
+				copy(f[color:color+4], me.attribs.Colors[src][:])
+			} else {
+				f[color], f[color+1], f[color+2], f[color+3] = 1, 1, 1, 1
+			}
+		}
+		if tex2 >= 0 {
+			if int(src) < len(me.attribs.TexCoords2) {
+				copy(f[tex2:tex2+2], me.attribs.TexCoords2[src][:])
+			} else {
+				copy(f[tex2:tex2+2], f[3:5])
+			}
+		}
+		if skin >= 0 {
+			if me.skin != nil {
+				me.skin.writeVertAtts(src, f[skin:skin+2*meshSkinInfluencesPerVertex])
+			} else {
+				for i := skin; i < skin+2*meshSkinInfluencesPerVertex; i++ {
+					f[i] = 0
+				}
+			}
+		}
+	}
+	return verts
+}
+
+//	Returns the tangent of each final vertex: from attribs.Tangents where specified,
+//	else accumulated from the tex-coord derivatives of all adjacent faces and then
+//	Gram-Schmidt-orthogonalized against the vertex normal.
+func (me *meshRaw) tangents() (tangents [][4]float32) {
+	var (
+		i, v               int
+		p, t               [3][]float32
+		e1, e2, sdir, tdir [3]float64
+		du1, dv1, du2, dv2 float64
+		r, dot, mag        float64
+	)
+	numVerts := len(me.vertSrc)
+	tangents = make([][4]float32, numVerts)
+	sdirs, tdirs := make([][3]float64, numVerts), make([][3]float64, numVerts)
+	for f := 0; f+2 < len(me.indices); f += 3 {
+		for i = 0; i < 3; i++ {
+			v = int(me.indices[f+i]) * meshVertexBaseFloats
+			p[i], t[i] = me.verts[v:v+3], me.verts[v+3:v+5]
+		}
+		du1, dv1 = float64(t[1][0]-t[0][0]), float64(t[1][1]-t[0][1])
+		du2, dv2 = float64(t[2][0]-t[0][0]), float64(t[2][1]-t[0][1])
+		if r = du1*dv2 - du2*dv1; r == 0 {
+			continue
+		}
+		r = 1 / r
+		for i = 0; i < 3; i++ {
+			e1[i], e2[i] = float64(p[1][i]-p[0][i]), float64(p[2][i]-p[0][i])
+			sdir[i], tdir[i] = r*(dv2*e1[i]-dv1*e2[i]), r*(du1*e2[i]-du2*e1[i])
+		}
+		for i = 0; i < 3; i++ {
+			v = int(me.indices[f+i])
+			for j := 0; j < 3; j++ {
+				sdirs[v][j] += sdir[j]
+				tdirs[v][j] += tdir[j]
+			}
+		}
+	}
+	var n, tan [3]float64
+	for v = 0; v < numVerts; v++ {
+		if src := me.vertSrc[v].PosIndex; int(src) < len(me.attribs.Tangents) {
+			tangents[v] = me.attribs.Tangents[src]
+			continue
+		}
+		for i = 0; i < 3; i++ {
+			n[i] = float64(me.verts[v*meshVertexBaseFloats+5+i])
+		}
+		dot = n[0]*sdirs[v][0] + n[1]*sdirs[v][1] + n[2]*sdirs[v][2]
+		for i = 0; i < 3; i++ {
+			tan[i] = sdirs[v][i] - n[i]*dot
+		}
+		if mag = tan[0]*tan[0] + tan[1]*tan[1] + tan[2]*tan[2]; mag < 1e-12 {
+			//	no usable tex-coords: any unit vector perpendicular to the normal will do
+			if n[0]*n[0] < 0.81 {
+				tan = [3]float64{0, n[2], -n[1]}
+			} else {
+				tan = [3]float64{-n[2], 0, n[0]}
+			}
+			mag = tan[0]*tan[0] + tan[1]*tan[1] + tan[2]*tan[2]
+		}
+		if mag > 0 {
+			mag = 1 / math.Sqrt(mag)
+		}
+		tangents[v] = [4]float32{float32(tan[0] * mag), float32(tan[1] * mag), float32(tan[2] * mag), 1}
+		//	bitangent sign: negative where the tex-coords are mirrored
+		if (n[1]*tan[2]-n[2]*tan[1])*tdirs[v][0]+(n[2]*tan[0]-n[0]*tan[2])*tdirs[v][1]+(n[0]*tan[1]-n[1]*tan[0])*tdirs[v][2] < 0 {
+			tangents[v][3] = -1
+		}
+	}
+	return
+}
@@ -10,7 +10,7 @@ import (
 type meshRaw struct {
 	lastNumIndices gl.Sizei
 
-	//	Raw vertices
+	//	Positions, tex-coords and normals of all final vertices, meshVertexBaseFloats each
 	verts []float32
 
 	//	Vertex indices
@@ -19,6 +19,18 @@ type meshRaw struct {
 	//	Raw face definitions
 	faces []meshRawFace
 
+	//	Skinning data, if loaded via Mesh.LoadSkinned()
+	skin *meshRawSkin
+
+	//	Morph targets, see Mesh.AddMorphTarget()
+	morphs []meshRawMorph
+
+	//	The source position, tex-coord and normal indices of each final vertex
+	vertSrc []u3d.MeshDescF3V
+
+	//	Optional vertex attributes, see Mesh.SetVertexAttribs()
+	attribs MeshVertexAttribs
+
 	bounding u3d.Bounds
 }
 
@@ -28,22 +40,41 @@ type meshRawFace struct {
 	entries [3]uint32
 	center  unum.Vec3
 
+	//	Positions of the triangle corners, kept for picking even after Mesh.Unload()
+	pos [3]unum.Vec3
+
 	base u3d.MeshFaceBase
 }
 
 type Mesh struct {
-	ID             int
+	ID int
+
+	//	The ID of this Mesh's default Model in Core.Libs.Models, or -1. Kept up to date by the library:
+	//	Core.Libs.Models.Compact() remaps it, and removing the Model resets it to -1.
 	DefaultModelID int
-	Name           string
+
+	Name string
 
 	meshBufOffsetBaseIndex, meshBufOffsetIndices, meshBufOffsetVerts int32
+	meshBufNumVerts, meshBufNumIndices                               int32
+	meshBufIndexSize                                                 int32
+	meshBufIndexType                                                 gl.Enum
 	gpuSynced                                                        bool
+	libGen                                                           uint64
 	raw                                                              meshRaw
 	meshBuffer                                                       *MeshBuffer
+	morphGpu                                                         meshMorphGpu
+	dyn                                                              *meshDynamic
 }
 
 func (me *Mesh) dispose() {
+	//	leaves no buffer membership (or allocated buffer space) behind for AddNew() to recycle along with this slot
+	if me.meshBuffer != nil {
+		me.meshBuffer.Remove(me.ID)
+	}
 	me.GpuDelete()
+	me.meshBuffer, me.dyn, me.raw = nil, nil, meshRaw{}
+	me.meshBufNumVerts, me.meshBufNumIndices = 0, 0
 }
 
 func (me *Mesh) init() {
@@ -53,33 +84,57 @@ func (me *Mesh) init() {
 func (me *Mesh) GpuDelete() {
 	if me.gpuSynced {
 		me.gpuSynced = false
+		if me.meshBuffer != nil {
+			me.meshBuffer.release(me)
+		}
 	}
+	me.morphGpu.dispose()
 }
 
 func (me *Mesh) GpuUpload() (err error) {
-	sizeVerts, sizeIndices := gl.Sizeiptr(4*len(me.raw.verts)), gl.Sizeiptr(4*len(me.raw.indices))
+	if me.dyn != nil {
+		meshEdits.Lock()
+		if me.dyn.stagedOk {
+			me.dyn.stagedOk = false
+			me.applyVertEdits(me.dyn.staged)
+		}
+		if me.dyn.dirty {
+			me.dyn.dirty = false
+			me.applyVertEdits(me.dyn.verts)
+		}
+		meshEdits.Unlock()
+	}
+	var indices interface{}
+	verts := me.raw.vertsFor(&me.meshBuffer.Layout, nil)
+	numVerts, numIndices := int32(len(me.raw.vertSrc)), int32(len(me.raw.indices))
 	me.GpuDelete()
-	if sizeVerts > gl.Sizeiptr(me.meshBuffer.memSizeVertices) {
-		err = errf("Cannot upload mesh '%v': vertex size (%vB) exceeds mesh buffer's available vertex memory (%vB)", me.Name, sizeVerts, me.meshBuffer.memSizeVertices)
-	} else if sizeIndices > gl.Sizeiptr(me.meshBuffer.memSizeIndices) {
-		err = errf("Cannot upload mesh '%v': index size (%vB) exceeds mesh buffer's available index memory (%vB)", me.Name, sizeIndices, me.meshBuffer.memSizeIndices)
+	if Options.Meshes.Optimize.Indices16 && numVerts < 65536 {
+		indices16 := make([]uint16, numIndices)
+		for i, index := range me.raw.indices {
+			indices16[i] = uint16(index)
+		}
+		indices, me.meshBufIndexSize, me.meshBufIndexType = &indices16[0], 2, gl.UNSIGNED_SHORT
 	} else {
-		me.meshBufOffsetBaseIndex, me.meshBufOffsetIndices, me.meshBufOffsetVerts = me.meshBuffer.offsetBaseIndex, me.meshBuffer.offsetIndices, me.meshBuffer.offsetVerts
+		indices, me.meshBufIndexSize, me.meshBufIndexType = &me.raw.indices[0], 4, gl.UNSIGNED_INT
+	}
+	//	index memory is allocated in units of Core.Mesh.Buffers.MemSizePerIndex()
+	isize := me.meshBufIndexSize * numIndices
+	if err = me.meshBuffer.alloc(me, numVerts, (isize+Core.Mesh.Buffers.MemSizePerIndex()-1)/Core.Mesh.Buffers.MemSizePerIndex()); err == nil {
 		Diag.LogMeshes("Upload %v at voff=%v ioff=%v boff=%v", me.Name, me.meshBufOffsetVerts, me.meshBufOffsetIndices, me.meshBufOffsetBaseIndex)
 		me.meshBuffer.glIbo.Bind()
 		defer me.meshBuffer.glIbo.Unbind()
 		me.meshBuffer.glVbo.Bind()
 		defer me.meshBuffer.glVbo.Unbind()
-		if err = me.meshBuffer.glVbo.SubData(gl.Intptr(me.meshBufOffsetVerts), sizeVerts, gl.Ptr(&me.raw.verts[0])); err == nil {
-			me.meshBuffer.offsetVerts += int32(sizeVerts)
-			if err = me.meshBuffer.glIbo.SubData(gl.Intptr(me.meshBufOffsetIndices), sizeIndices, gl.Ptr(&me.raw.indices[0])); err == nil {
-				me.meshBuffer.offsetIndices += int32(sizeIndices)
-				me.meshBuffer.offsetBaseIndex += int32(len(me.raw.indices))
-				if err == nil {
-					me.gpuSynced = true
-				}
+		if err = me.meshBuffer.uploadVerts(me.meshBufOffsetVerts, verts); err == nil {
+			if err = me.meshBuffer.glIbo.SubData(gl.Intptr(me.meshBufOffsetIndices), gl.Sizeiptr(isize), gl.Ptr(indices)); err == nil {
+				err = me.morphGpu.upload(me.raw.morphs)
 			}
 		}
+		if err == nil {
+			me.gpuSynced = true
+		} else {
+			me.meshBuffer.release(me)
+		}
 	}
 	return
 }
@@ -91,6 +146,7 @@ func (me *Mesh) GpuUploaded() bool {
 func (me *Mesh) Load(provider u3d.MeshProvider) (err error) {
 	var meshData *u3d.MeshDescriptor
 	if meshData, err = provider(); err == nil && meshData != nil {
+		me.raw.skin = nil
 		err = me.load(meshData)
 	}
 	return
@@ -105,14 +161,20 @@ func (me *Mesh) load(meshData *u3d.MeshDescriptor) (err error) {
 		ventry                                         u3d.MeshDescF3V
 		tvp                                            [3]unum.Vec3
 	)
+	for fi := 0; fi < len(meshData.Faces); fi++ {
+		if meshBadFace(meshData, &meshData.Faces[fi]) {
+			return errf("Cannot load mesh '%v': face %v refers to missing vertex data, see Core.Libs.Meshes.Repair()", me.Name, fi)
+		}
+	}
 	numVerts := 3 * int32(len(meshData.Faces))
 	vertsMap := make(map[u3d.MeshDescF3V]uint32, numVerts)
-	me.gpuSynced = false
+	me.gpuSynced, me.dyn = false, nil
 	me.raw.bounding.Reset()
-	me.raw.verts = make([]float32, Core.Mesh.Buffers.FloatsPerVertex()*numVerts)
+	me.raw.verts = make([]float32, meshVertexBaseFloats*numVerts)
 	me.raw.indices = make([]uint32, numVerts)
 	me.raw.lastNumIndices = gl.Sizei(numVerts)
 	me.raw.faces = make([]meshRawFace, len(meshData.Faces))
+	me.raw.vertSrc, me.raw.morphs, me.raw.attribs = make([]u3d.MeshDescF3V, 0, numVerts), nil, MeshVertexAttribs{}
 	for fi := 0; fi < len(meshData.Faces); fi++ {
 		me.raw.faces[offsetFace].base = meshData.Faces[fi].MeshFaceBase
 		meshData.Positions[meshData.Faces[fi].V[0].PosIndex].ToVec3(&tvp[0])
@@ -124,12 +186,14 @@ func (me *Mesh) load(meshData *u3d.MeshDescriptor) (err error) {
 			}
 			me.raw.bounding.AaBox.UpdateMinMax(&tvp[tvi])
 		}
+		me.raw.faces[offsetFace].pos = tvp
 		me.raw.faces[offsetFace].center.X = (tvp[0].X + tvp[1].X + tvp[2].X) / 3
 		me.raw.faces[offsetFace].center.Y = (tvp[0].Y + tvp[1].Y + tvp[2].Y) / 3
 		me.raw.faces[offsetFace].center.Z = (tvp[0].Z + tvp[1].Z + tvp[2].Z) / 3
 		for ei, ventry = range meshData.Faces[fi].V {
 			if vindex, vexists = vertsMap[ventry]; !vexists {
 				vindex, vertsMap[ventry] = offsetVertex, offsetVertex
+				me.raw.vertSrc = append(me.raw.vertSrc, ventry)
 				copy(me.raw.verts[offsetFloat:(offsetFloat+3)], meshData.Positions[ventry.PosIndex][0:3])
 				offsetFloat += 3
 				copy(me.raw.verts[offsetFloat:(offsetFloat+2)], meshData.TexCoords[ventry.TexCoordIndex][0:2])
@@ -147,7 +211,9 @@ func (me *Mesh) load(meshData *u3d.MeshDescriptor) (err error) {
 		}
 		offsetFace++
 	}
+	me.raw.verts = me.raw.verts[:offsetFloat]
 	me.raw.bounding.AaBox.SetCenterExtent()
+	me.raw.optimize(me.Name)
 	Diag.LogMeshes("mesh{%v}.Load() gave %v faces, %v att floats for %v final verts (%v source verts), %v indices (%vx vertex reuse)", me.Name, len(me.raw.faces), len(me.raw.verts), numFinalVerts, numVerts, len(me.raw.indices), vreuse)
 	return
 }
@@ -157,7 +223,13 @@ func (me *Mesh) Loaded() bool {
 }
 
 func (me *Mesh) Unload() {
-	me.raw.verts, me.raw.indices = nil, nil
+	me.raw.verts, me.raw.indices, me.raw.vertSrc, me.raw.attribs = nil, nil, nil, MeshVertexAttribs{}
+	for i := 0; i < len(me.raw.morphs); i++ {
+		me.raw.morphs[i].deltas = nil
+	}
+	if me.raw.skin != nil {
+		me.raw.skin.vertAtts = nil
+	}
 }
 
 func (_ MeshLib) GpuSync() (err error) {
@@ -204,6 +276,24 @@ func (_ MeshLib) MeshTri() u3d.MeshProvider {
 
 //#begin-gt -gen-lib.gt T:Mesh L:Core.Libs.Meshes
 
+//	A generational handle to a Mesh in Core.Libs.Meshes.
+//	Unlike a plain ID, it is never silently resolved to another Mesh that
+//	later re-uses the same ID after the original was removed or moved by Compact().
+type MeshHandle struct {
+	id  int
+	gen uint64
+}
+
+//	Returns the ID that me referred to when last resolved. It may since have become invalid.
+func (me MeshHandle) ID() int {
+	return me.id
+}
+
+//	Returns true if me is the zero MeshHandle (or was detected to be stale), which never resolves to a Mesh.
+func (me MeshHandle) IsNil() bool {
+	return me.gen == 0
+}
+
 //	Only used for Core.Libs.Meshes
 type MeshLib []Mesh
 
@@ -224,7 +314,7 @@ func (me *MeshLib) AddNew() (id int) {
 		*me = append(*me, Mesh{})
 	}
 	ref := &(*me)[id]
-	ref.ID = id
+	ref.ID, ref.libGen = id, libGenNext()
 	ref.init()
 	return
 }
@@ -240,6 +330,8 @@ func (me *MeshLib) Compact() {
 		if (*me)[i].ID == -1 {
 			compact, before, after = true, (*me)[:i], (*me)[i+1:]
 			*me = append(before, after...)
+			//	re-check i, which now holds the next entry
+			i--
 		}
 	}
 	if compact {
@@ -248,6 +340,7 @@ func (me *MeshLib) Compact() {
 			if ref = &(*me)[i]; ref.ID != i {
 				oldID, ref.ID = ref.ID, i
 				changed[oldID] = i
+				libGenMove(ref.libGen, i)
 			}
 		}
 		if len(changed) > 0 {
@@ -272,6 +365,39 @@ func (me MeshLib) get(id int) (ref *Mesh) {
 	return
 }
 
+//	Returns the Mesh that h refers to, or nil if it has since been removed.
+//	If it was moved by Compact(), h is updated to its new ID. If it was removed, h becomes nil.
+func (me MeshLib) Deref(h *MeshHandle) (ref *Mesh) {
+	if ref = me.deref(*h); ref != nil {
+		h.id = ref.ID
+	} else if h.gen != 0 {
+		*h = MeshHandle{id: -1}
+	}
+	return
+}
+
+//	Like Deref(), but leaves h as is: safe to call on handles owned by another thread.
+func (me MeshLib) deref(h MeshHandle) (ref *Mesh) {
+	if h.gen != 0 {
+		id := h.id
+		if id < 0 || id >= len(me) || me[id].libGen != h.gen {
+			id = libGenMovedTo(h.gen)
+		}
+		if id > -1 && id < len(me) && me[id].ID == id && me[id].libGen == h.gen {
+			ref = &me[id]
+		}
+	}
+	return
+}
+
+//	Returns a MeshHandle to the Mesh with the specified ID, or a nil MeshHandle if id is invalid.
+func (me MeshLib) Handle(id int) (h MeshHandle) {
+	if h.id = -1; me.IsOk(id) {
+		h.id, h.gen = id, me[id].libGen
+	}
+	return
+}
+
 func (me MeshLib) IsOk(id int) (ok bool) {
 	if id > -1 && id < len(me) {
 		ok = me[id].ID == id
@@ -291,7 +417,8 @@ func (me MeshLib) Remove(fromID, num int) {
 		changed := make(map[int]int, num)
 		for id := fromID; id < fromID+num; id++ {
 			me[id].dispose()
-			changed[id], me[id].ID = -1, -1
+			libGenForget(me[id].libGen)
+			changed[id], me[id].ID, me[id].libGen = -1, -1, 0
 		}
 		me.onMeshIDsChanged(changed)
 	}
@@ -3,9 +3,18 @@ package core
 //	A Model is a parameterized instantiation of its parent Mesh geometry
 //	with unique appearance, material or other properties.
 type Model struct {
-	ID    int
+	ID int
+
+	//	The ID of this Model's FxMaterial in Core.Libs.Materials, or -1. Kept up to date by the library:
+	//	Core.Libs.Materials.Compact() remaps it, and removing the FxMaterial resets it to -1.
 	MatID int
-	Name  string
+
+	Name string
+
+	//	Used for all nodes rendering this Model that don't have their own Render.Lod.Levels.
+	Lod LodGroup
+
+	libGen uint64
 }
 
 func (me *Model) dispose() {
@@ -13,11 +22,30 @@ func (me *Model) dispose() {
 
 func (me *Model) init() {
 	me.MatID = -1
+	me.Lod.init()
 	return
 }
 
 //#begin-gt -gen-lib.gt T:Model L:Core.Libs.Models
 
+//	A generational handle to a Model in Core.Libs.Models.
+//	Unlike a plain ID, it is never silently resolved to another Model that
+//	later re-uses the same ID after the original was removed or moved by Compact().
+type ModelHandle struct {
+	id  int
+	gen uint64
+}
+
+//	Returns the ID that me referred to when last resolved. It may since have become invalid.
+func (me ModelHandle) ID() int {
+	return me.id
+}
+
+//	Returns true if me is the zero ModelHandle (or was detected to be stale), which never resolves to a Model.
+func (me ModelHandle) IsNil() bool {
+	return me.gen == 0
+}
+
 //	Only used for Core.Libs.Models
 type ModelLib []Model
 
@@ -38,7 +66,7 @@ func (me *ModelLib) AddNew() (id int) {
 		*me = append(*me, Model{})
 	}
 	ref := &(*me)[id]
-	ref.ID = id
+	ref.ID, ref.libGen = id, libGenNext()
 	ref.init()
 	return
 }
@@ -54,6 +82,8 @@ func (me *ModelLib) Compact() {
 		if (*me)[i].ID == -1 {
 			compact, before, after = true, (*me)[:i], (*me)[i+1:]
 			*me = append(before, after...)
+			//	re-check i, which now holds the next entry
+			i--
 		}
 	}
 	if compact {
@@ -62,6 +92,7 @@ func (me *ModelLib) Compact() {
 			if ref = &(*me)[i]; ref.ID != i {
 				oldID, ref.ID = ref.ID, i
 				changed[oldID] = i
+				libGenMove(ref.libGen, i)
 			}
 		}
 		if len(changed) > 0 {
@@ -86,6 +117,39 @@ func (me ModelLib) get(id int) (ref *Model) {
 	return
 }
 
+//	Returns the Model that h refers to, or nil if it has since been removed.
+//	If it was moved by Compact(), h is updated to its new ID. If it was removed, h becomes nil.
+func (me ModelLib) Deref(h *ModelHandle) (ref *Model) {
+	if ref = me.deref(*h); ref != nil {
+		h.id = ref.ID
+	} else if h.gen != 0 {
+		*h = ModelHandle{id: -1}
+	}
+	return
+}
+
+//	Like Deref(), but leaves h as is: safe to call on handles owned by another thread.
+func (me ModelLib) deref(h ModelHandle) (ref *Model) {
+	if h.gen != 0 {
+		id := h.id
+		if id < 0 || id >= len(me) || me[id].libGen != h.gen {
+			id = libGenMovedTo(h.gen)
+		}
+		if id > -1 && id < len(me) && me[id].ID == id && me[id].libGen == h.gen {
+			ref = &me[id]
+		}
+	}
+	return
+}
+
+//	Returns a ModelHandle to the Model with the specified ID, or a nil ModelHandle if id is invalid.
+func (me ModelLib) Handle(id int) (h ModelHandle) {
+	if h.id = -1; me.IsOk(id) {
+		h.id, h.gen = id, me[id].libGen
+	}
+	return
+}
+
 func (me ModelLib) IsOk(id int) (ok bool) {
 	if id > -1 && id < len(me) {
 		ok = me[id].ID == id
@@ -105,7 +169,8 @@ func (me ModelLib) Remove(fromID, num int) {
 		changed := make(map[int]int, num)
 		for id := fromID; id < fromID+num; id++ {
 			me[id].dispose()
-			changed[id], me[id].ID = -1, -1
+			libGenForget(me[id].libGen)
+			changed[id], me[id].ID, me[id].libGen = -1, -1, 0
 		}
 		me.onModelIDsChanged(changed)
 	}
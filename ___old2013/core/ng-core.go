@@ -1,6 +1,8 @@
 package core
 
 import (
+	"image"
+
 	u3d "github.com/metaleap/go-util-3d"
 	gl "github.com/metaleap/go-opengl/core"
 	ugl "github.com/metaleap/go-opengl/util"
@@ -19,6 +21,16 @@ type NgCore struct {
 		Buffers MeshBufferLib
 		Desc    struct {
 			Cube, Plane, Pyramid, Quad, Tri u3d.MeshProvider
+
+			//	Factories of procedural mesh providers, with their faces tagged by part (such as "top" or "side").
+			UvSphere  func(radius float64, slices, stacks int) u3d.MeshProvider
+			Icosphere func(radius float64, subdivisions int) u3d.MeshProvider
+			Cylinder  func(radius, height float64, slices, stacks int, caps bool) u3d.MeshProvider
+			Cone      func(radius, height float64, slices, stacks int) u3d.MeshProvider
+			Torus     func(radius, tubeRadius float64, rings, sides int) u3d.MeshProvider
+			Capsule   func(radius, height float64, slices, stacks int) u3d.MeshProvider
+			Grid      func(width, depth float64, cols, rows int) u3d.MeshProvider
+			Terrain   func(width, depth, height float64, heightmap image.Image, cols, rows int) u3d.MeshProvider
 		}
 	}
 	Render struct {
@@ -35,7 +47,8 @@ type NgCore struct {
 		}
 		KnownTechniques map[string]renderTechniqueProvider
 
-		states ugl.RenderStates
+		states       ugl.RenderStates
+		techVariants []string
 	}
 
 	isInit bool
@@ -52,6 +65,9 @@ func (_ *NgCore) dispose() {
 
 func (_ *NgCore) init() (err error) {
 	Core.Mesh.Desc.Cube, Core.Mesh.Desc.Plane, Core.Mesh.Desc.Pyramid, Core.Mesh.Desc.Quad, Core.Mesh.Desc.Tri = u3d.MeshDescriptorCube, u3d.MeshDescriptorPlane, u3d.MeshDescriptorPyramid, u3d.MeshDescriptorQuad, u3d.MeshDescriptorTri
+	desc := &Core.Mesh.Desc
+	desc.UvSphere, desc.Icosphere, desc.Cylinder, desc.Cone = meshGenUvSphere, meshGenIcosphere, meshGenCylinder, meshGenCone
+	desc.Torus, desc.Capsule, desc.Grid, desc.Terrain = meshGenTorus, meshGenCapsule, meshGenGrid, meshGenTerrain
 	Core.Libs.init()
 	Core.initRendering()
 	err = Core.showSplash()
@@ -65,6 +81,7 @@ func (_ *NgCore) initRendering() {
 		"Quad":  newRenderTechniqueQuad,
 		"Scene": newRenderTechniqueScene,
 	}
+	rend.techVariants = []string{"SceneInst", "SceneMorph", "SceneSkin"}
 	rend.Fx.KnownProcIDs = fxKnownProcIDs()
 	rend.Fx.procFuncs = make(map[string]string, len(rend.Fx.KnownProcIDs))
 	for _, shaderFunc := range rend.Fx.KnownProcIDs {
@@ -92,32 +109,25 @@ func (_ *NgCore) onResizeWindow(viewWidth, viewHeight int) {
 }
 
 func (_ *NgCore) GpuSyncImageLibs() (err error) {
-	type imgChan chan fxImage
-	imgLoad := func(ch imgChan, img fxImage) {
-		if !img.Loaded() {
-			if err := img.Load(); err != nil {
-				Diag.LogErr(err)
-			}
-		}
-		ch <- img
-	}
-
 	num := len(Core.Libs.Images.Tex2D) + len(Core.Libs.Images.TexCube)
-	ch, done := make(imgChan, num), 0
+	ch, done := make(chan fxImageLoaded, num), 0
 	Core.Libs.Images.Tex2D.Walk(func(t2d *FxImage2D) {
-		go imgLoad(ch, t2d)
+		go fxImageLoad(ch, t2d)
 	})
 	Core.Libs.Images.TexCube.Walk(func(tcm *FxImageCube) {
-		go imgLoad(ch, tcm)
+		go fxImageLoad(ch, tcm)
 	})
 
-	for img := range ch {
+	for loaded := range ch {
 		//	As soon as the first image is processed/loaded, it can be uploaded while others are still busy
 		if done++; done >= num {
 			close(ch)
 		}
-		if img.Loaded() {
-			if err = img.GpuSync(); err != nil {
+		if loaded.err != nil {
+			Diag.LogErr(loaded.err)
+		}
+		if loaded.img.Loaded() {
+			if err = loaded.img.GpuSync(); err != nil {
 				Diag.LogErr(err)
 			}
 		}
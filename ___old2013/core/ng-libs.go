@@ -1,5 +1,53 @@
 package core
 
+import (
+	"sync"
+	"sync/atomic"
+)
+
+var (
+	//	Incremented for every new lib element, see libGenNext().
+	libGens uint64
+
+	//	The current IDs of all lib elements (by generation) that Compact() has moved, see libGenMovedTo().
+	libGenMoved = map[uint64]int{}
+
+	libGenMovedMutex sync.RWMutex
+)
+
+//	Returns a new generation number, never 0, to identify a new lib element for
+//	the lifetime of the process: unlike its ID, it is never re-used or changed by Compact().
+func libGenNext() uint64 {
+	return atomic.AddUint64(&libGens, 1)
+}
+
+//	Returns the ID that the lib element of generation gen was moved to by Compact(), or -1.
+func libGenMovedTo(gen uint64) (id int) {
+	var ok bool
+	libGenMovedMutex.RLock()
+	if id, ok = libGenMoved[gen]; !ok {
+		id = -1
+	}
+	libGenMovedMutex.RUnlock()
+	return
+}
+
+//	Records that Compact() moved the lib element of generation gen to id.
+func libGenMove(gen uint64, id int) {
+	libGenMovedMutex.Lock()
+	libGenMoved[gen] = id
+	libGenMovedMutex.Unlock()
+}
+
+//	Forgets any move of the lib element of generation gen, which is about to be removed.
+func libGenForget(gen uint64) {
+	if gen != 0 {
+		libGenMovedMutex.Lock()
+		delete(libGenMoved, gen)
+		libGenMovedMutex.Unlock()
+	}
+}
+
 type LibElemIDChangedHandler func(oldNewIDs map[int]int)
 
 type LibElemIDChangedHandlers []LibElemIDChangedHandler
@@ -18,6 +66,7 @@ func (me LibElemIDChangedHandlers) callAll(oldNewIDs map[int]int) {
 
 //	Only used for Core.Libs.
 type NgLibs struct {
+	AnimClips AnimClipLib
 	Effects   FxEffectLib
 	Materials FxMaterialLib
 	Images    struct {
@@ -37,7 +86,7 @@ func (me *NgLibs) dispose() {
 		&Core.Render.Canvases, &Core.Mesh.Buffers,
 		&me.Models, &me.Materials, &me.Effects,
 		&me.Images.Tex2D, &me.Images.TexCube,
-		&me.Meshes, &me.Scenes,
+		&me.Meshes, &me.Scenes, &me.AnimClips,
 	} {
 		disp.dispose()
 	}
@@ -50,7 +99,7 @@ func (me *NgLibs) init() {
 		&Core.Render.Canvases, &Core.Mesh.Buffers,
 		&me.Models, &me.Materials, &me.Effects,
 		&me.Images.Tex2D, &me.Images.TexCube,
-		&me.Meshes, &me.Scenes,
+		&me.Meshes, &me.Scenes, &me.AnimClips,
 	} {
 		c.init()
 	}
@@ -113,6 +162,10 @@ func (_ *NgLibs) onFxImageIDsChanged(procID string, oldNewIDs map[int]int) {
 	}
 }
 
+func (_ AnimClipLib) onAnimClipIDsChanged(oldNewIDs map[int]int) {
+	Options.Libs.OnIDsChanged.AnimClips.callAll(oldNewIDs)
+}
+
 func (_ FxEffectLib) onFxEffectIDsChanged(oldNewIDs map[int]int) {
 	for mid := 0; mid < len(Core.Libs.Materials); mid++ {
 		if Core.Libs.Materials.Ok(mid) {
@@ -143,17 +196,7 @@ func (_ FxImageCubeLib) onFxImageCubeIDsChanged(oldNewIDs map[int]int) {
 }
 
 func (_ FxMaterialLib) onFxMaterialIDsChanged(oldNewIDs map[int]int) {
-	var id, v int
-	for id = 0; id < len(Core.Libs.Scenes); id++ {
-		if Core.Libs.Scenes.Ok(id) {
-			for v = 0; v < len(Core.Libs.Scenes[id].allNodes); v++ {
-				if Core.Libs.Scenes[id].allNodes.Ok(v) {
-					Core.Libs.UpdateIDRef(oldNewIDs, &Core.Libs.Scenes[id].allNodes[v].Render.MatID)
-				}
-			}
-		}
-	}
-	for id = 0; id < len(Core.Libs.Models); id++ {
+	for id := 0; id < len(Core.Libs.Models); id++ {
 		if Core.Libs.Models.Ok(id) {
 			Core.Libs.UpdateIDRef(oldNewIDs, &Core.Libs.Models[id].MatID)
 		}
@@ -162,36 +205,16 @@ func (_ FxMaterialLib) onFxMaterialIDsChanged(oldNewIDs map[int]int) {
 }
 
 func (_ MeshLib) onMeshIDsChanged(oldNewIDs map[int]int) {
-	var id, v int
 	for _, meshBuf := range Core.Mesh.Buffers {
-		for id, v = range meshBuf.meshIDs {
+		for id, v := range meshBuf.meshIDs {
 			meshBuf.meshIDs[id] = Core.Libs.UpdatedIDRef(oldNewIDs, v)
 		}
 	}
-	for id = 0; id < len(Core.Libs.Scenes); id++ {
-		if Core.Libs.Scenes.Ok(id) {
-			for v = 0; v < len(Core.Libs.Scenes[id].allNodes); v++ {
-				if Core.Libs.Scenes[id].allNodes.Ok(v) {
-					Core.Libs.UpdateIDRef(oldNewIDs, &Core.Libs.Scenes[id].allNodes[v].Render.meshID)
-				}
-			}
-		}
-	}
 	Options.Libs.OnIDsChanged.Meshes.callAll(oldNewIDs)
 }
 
 func (_ ModelLib) onModelIDsChanged(oldNewIDs map[int]int) {
-	var id, i int
-	for id = 0; id < len(Core.Libs.Scenes); id++ {
-		if Core.Libs.Scenes.Ok(id) {
-			for i = 0; i < len(Core.Libs.Scenes[id].allNodes); i++ {
-				if Core.Libs.Scenes[id].allNodes.Ok(i) {
-					Core.Libs.UpdateIDRef(oldNewIDs, &Core.Libs.Scenes[id].allNodes[i].Render.ModelID)
-				}
-			}
-		}
-	}
-	for id = 0; id < len(Core.Libs.Meshes); id++ {
+	for id := 0; id < len(Core.Libs.Meshes); id++ {
 		if Core.Libs.Meshes.Ok(id) {
 			Core.Libs.UpdateIDRef(oldNewIDs, &Core.Libs.Meshes[id].DefaultModelID)
 		}
@@ -205,7 +228,9 @@ func (_ SceneLib) onSceneIDsChanged(oldNewIDs map[int]int) {
 	for id = 0; id < len(Core.Render.Canvases); id++ {
 		for vid = 0; vid < len(Core.Render.Canvases[id].Views); vid++ {
 			if rts = Core.Render.Canvases[id].Views[vid].Technique_Scene(); rts != nil {
-				Core.Libs.UpdateIDRef(oldNewIDs, &rts.Camera.sceneID)
+				for _, layer := range rts.Camera.layers {
+					Core.Libs.UpdateIDRef(oldNewIDs, &layer.sceneID)
+				}
 			}
 		}
 	}
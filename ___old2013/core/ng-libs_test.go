@@ -0,0 +1,63 @@
+package core
+
+import (
+	"testing"
+)
+
+func TestLibsCompactIDRefs(t *testing.T) {
+	libs := &Core.Libs
+	//	three entries each, of which the first two get removed: so Compact() moves the third
+	var mats, models, meshes, scenes [3]int
+	for i := 0; i < 3; i++ {
+		mats[i], models[i], meshes[i], scenes[i] = libs.Materials.AddNew(), libs.Models.AddNew(), libs.Meshes.AddNew(), libs.Scenes.AddNew()
+		libs.Models[models[i]].Name, libs.Meshes[meshes[i]].Name = strf("model%d", i), strf("mesh%d", i)
+	}
+	libs.Models[models[2]].MatID, libs.Meshes[meshes[2]].DefaultModelID = mats[2], models[2]
+	matH, modelH, meshH, sceneH := libs.Materials.Handle(mats[2]), libs.Models.Handle(models[2]), libs.Meshes.Handle(meshes[2]), libs.Scenes.Handle(scenes[2])
+	rts := &RenderTechniqueScene{}
+	layer := &CameraLayer{sceneID: scenes[2]}
+	rts.Camera.layers = []*CameraLayer{layer}
+	defer func(canvases RenderCanvasLib) { Core.Render.Canvases = canvases }(Core.Render.Canvases)
+	Core.Render.Canvases = RenderCanvasLib{&RenderCanvas{Views: RenderViewLib{&RenderView{Technique: rts}}}}
+
+	for i := 0; i < 2; i++ {
+		libs.Materials.Remove(mats[i], 1)
+		libs.Models.Remove(models[i], 1)
+		libs.Meshes.Remove(meshes[i], 1)
+		libs.Scenes.Remove(scenes[i], 1)
+	}
+	libs.Materials.Compact()
+	libs.Models.Compact()
+	libs.Meshes.Compact()
+	libs.Scenes.Compact()
+	mat, model, mesh, scene := libs.Materials.Deref(&matH), libs.Models.Deref(&modelH), libs.Meshes.Deref(&meshH), libs.Scenes.Deref(&sceneH)
+	if mat == nil || model == nil || mesh == nil || scene == nil {
+		t.Fatalf("handles no longer resolve after Compact()")
+	}
+	if model.Name != "model2" || mesh.Name != "mesh2" {
+		t.Fatalf("handles resolve to %q and %q after Compact()", model.Name, mesh.Name)
+	}
+	for i := 0; i < len(libs.Meshes); i++ {
+		if name := libs.Meshes[i].Name; name == "mesh0" || name == "mesh1" {
+			t.Errorf("Compact() kept the removed %v at %v", name, i)
+		}
+	}
+	if model.MatID != mat.ID {
+		t.Errorf("Model.MatID is %v after Compact(), want %v", model.MatID, mat.ID)
+	}
+	if mesh.DefaultModelID != model.ID {
+		t.Errorf("Mesh.DefaultModelID is %v after Compact(), want %v", mesh.DefaultModelID, model.ID)
+	}
+	if layer.SceneID() != scene.ID {
+		t.Errorf("CameraLayer.SceneID() is %v after Compact(), want %v", layer.SceneID(), scene.ID)
+	}
+
+	//	removing the referenced entries resets the references
+	libs.Materials.Remove(mat.ID, 1)
+	libs.Models.Remove(model.ID, 1)
+	libs.Meshes.Remove(mesh.ID, 1)
+	libs.Scenes.Remove(scene.ID, 1)
+	if model.MatID != -1 || mesh.DefaultModelID != -1 || layer.SceneID() != -1 {
+		t.Errorf("got MatID %v, DefaultModelID %v and SceneID() %v after removal, want -1", model.MatID, mesh.DefaultModelID, layer.SceneID())
+	}
+}
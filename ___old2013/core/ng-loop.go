@@ -23,7 +23,7 @@ type NgLoop struct {
 
 	On struct {
 		//	While Loop.Run() is running, this callback is invoked (in its own "app thread")
-		//	every loop iteration (ie. once per frame).
+		//	every loop iteration (ie. once per frame), right after all AnimPlayers of all Scenes were evaluated.
 		//	This callback may run in parallel with On.EverySec(), but never with On.WinThread().
 		AppThread func()
 
@@ -73,7 +73,13 @@ func (_ *NgLoop) onSwap() {
 
 func (_ *NgLoop) onThreadApp() {
 	Stats.FrameAppThread.begin()
+	Core.Libs.Scenes.Walk(func(scene *Scene) {
+		scene.onAnimate(Loop.Tick.Delta)
+	})
 	Loop.On.AppThread()
+	Core.Libs.Scenes.Walk(func(scene *Scene) {
+		scene.onSkin()
+	})
 	Stats.FrameAppThread.end()
 	thrApp.Unlock()
 }
@@ -161,6 +167,12 @@ func (_ *NgLoop) Run() {
 			}
 			//	Wait for threads -- waits until both app and prep threads are done and copies stage states around
 			Loop.onWaitForThreads()
+			//	Apply vertex edits of dynamic meshes -- while neither app nor prep thread is running
+			Core.Libs.Meshes.onSyncEdits()
+			//	Stream world cells in and out -- while neither app nor prep thread is running
+			Core.Libs.Scenes.Walk(func(scene *Scene) {
+				scene.onStream()
+			})
 			//	Call On.WinThread() -- for main-thread user code (mostly input polling) without affecting On.AppThread
 			Loop.onThreadWin()
 
@@ -197,7 +209,7 @@ func (_ *NgLoop) Run() {
 		Diag.LogMisc("Exited loop.")
 		Diag.LogIfGlErr("ngine.PostLoop")
 		if false {
-			for rbi, rbe := range Core.Render.Canvases[1].Views[0].Technique_Scene().thrRend.batch.all {
+			for rbi, rbe := range Core.Render.Canvases[1].Views[0].Technique_Scene().Camera.layers[0].thrRend.batch.all {
 				println(strf("%d\t==>\tP:%v\tT:%v\tB:%v\tD:%v", rbi, rbe.prog, rbe.texes, Core.Libs.Meshes[rbe.mesh].meshBuffer.glIbo.GlHandle, rbe.dist))
 			}
 		}
@@ -42,12 +42,20 @@ type NgOptions struct {
 			BaseName       string
 			ShaderSources  string
 			CachedTextures string
+			CachedMeshes   string
 		}
 	}
 
 	Cameras struct {
 		DefaultControllerParams ControllerParams
-		PerspectiveDefaults     u3d.Perspective
+
+		//	The resolution of the CPU depth buffer used by cameras with Cull.Occlusion enabled.
+		//	Defaults to 256x128. Larger sizes cull more precisely but take longer in the prep thread.
+		OcclusionBufSize struct {
+			Width, Height int
+		}
+
+		PerspectiveDefaults u3d.Perspective
 	}
 
 	Initialization struct {
@@ -94,8 +102,9 @@ type NgOptions struct {
 		InitialCap   int
 		GrowCapBy    int
 		OnIDsChanged struct {
-			Effects LibElemIDChangedHandlers
-			Images  struct {
+			AnimClips LibElemIDChangedHandlers
+			Effects   LibElemIDChangedHandlers
+			Images    struct {
 				TexCube LibElemIDChangedHandlers
 				Tex2D   LibElemIDChangedHandlers
 			}
@@ -120,9 +129,53 @@ type NgOptions struct {
 		}
 	}
 
+	Meshes struct {
+		//	Used by Mesh.LoadCached() and the importers (such as Scene.ImportObj()) to skip the parsing and processing of unchanged mesh source files.
+		DiskCache struct {
+			Enabled      bool
+			Compressor   func(w io.WriteCloser) io.WriteCloser
+			Decompressor func(r io.ReadCloser) io.ReadCloser
+		}
+
+		//	Optional processing of meshes at load time (Mesh.Load() and Mesh.LoadSkinned()) and upload time.
+		//	The Diag.LogMeshes() output reports the average cache miss ratio (ACMR) before and after.
+		Optimize struct {
+			//	Reorders triangles for the post-transform vertex cache (Tipsify). Defaults to true.
+			VertexCache bool
+
+			//	Additionally sorts the clusters of the VertexCache order such that outward-facing
+			//	ones come first, reducing overdraw. Ignored unless VertexCache. Defaults to false.
+			Overdraw bool
+
+			//	Renumbers vertices in the order of their first use, for linear vertex fetches. Defaults to true.
+			VertexFetch bool
+
+			//	Uploads 16-bit rather than 32-bit indices for meshes with fewer than 65536 vertices. Defaults to true.
+			Indices16 bool
+		}
+	}
+
 	Rendering struct {
 		DefaultBatcher    RenderBatcher
 		DefaultClearColor ugl.GlVec4
+
+		//	The vertex layout of mesh buffers created via Core.Mesh.Buffers.AddNew().
+		//	Defaults to only the position, tex-coord and normal of all vertices: skinned meshes
+		//	go to a companion buffer with Skin instead, see MeshBuffer.SkinnedBuffer().
+		DefaultMeshVertexLayout MeshVertexLayout
+
+		//	The maximum number of joints in a MeshSkin, and the length of the joint-matrix
+		//	palette uniform in the shaders of skinned nodes. Defaults to 64.
+		//	Changes only affect meshes loaded and shaders built afterwards.
+		SkinMaxJoints int
+	}
+
+	Scenes struct {
+		SpatialIndex struct {
+			//	The edge length of the smallest cells that the spatial index of a Scene
+			//	subdivides into. Defaults to 4. Changes only affect nodes (re)inserted afterwards.
+			MinCellSize float64
+		}
 	}
 
 	Textures struct {
@@ -137,10 +190,16 @@ func init() {
 	o.Textures.Storage.DiskCache.Compressor = func(w io.WriteCloser) io.WriteCloser { return w }
 	o.Textures.Storage.DiskCache.Decompressor = func(r io.ReadCloser) io.ReadCloser { return r }
 	o.Cameras.DefaultControllerParams.initDefaults()
+	o.Cameras.OcclusionBufSize.Width, o.Cameras.OcclusionBufSize.Height = 256, 128
 	persp := &o.Cameras.PerspectiveDefaults
 	persp.FovY.Deg, persp.ZFar, persp.ZNear, persp.Enabled = 37.8493, 30000, 0.3, true
 	o.Loop.GcEvery.Sec = true
 	o.Libs.InitialCap, o.Libs.GrowCapBy = 16, 32
+	o.Scenes.SpatialIndex.MinCellSize = 4
+	o.Meshes.DiskCache.Enabled = true
+	o.Meshes.DiskCache.Compressor = func(w io.WriteCloser) io.WriteCloser { return w }
+	o.Meshes.DiskCache.Decompressor = func(r io.ReadCloser) io.ReadCloser { return r }
+	o.Meshes.Optimize.VertexCache, o.Meshes.Optimize.VertexFetch, o.Meshes.Optimize.Indices16 = true, true, true
 
 	//	Set all ID-changed handlers to empty funcs so we don't need to check for nil
 	init, isMac, initGl := &o.Initialization, runtime.GOOS == "darwin", &o.Initialization.GlContext
@@ -150,11 +209,12 @@ func init() {
 	buf.Color.R, buf.Color.G, buf.Color.B, buf.Depth = 8, 8, 8, 8
 
 	rend := &o.Rendering
-	rend.DefaultBatcher.Enabled = true
+	rend.DefaultBatcher.Enabled, rend.DefaultBatcher.Instancing = true, true
 	rend.DefaultBatcher.Priority[0] = BatchByProgram
 	rend.DefaultBatcher.Priority[1] = BatchByTexture
 	rend.DefaultBatcher.Priority[2] = BatchByBuffer
 	rend.DefaultClearColor = ugl.GlVec4{0, 0, 0, 1}
+	rend.SkinMaxJoints = 64
 
 	win := &UserIO.Window
 	win.OnCloseRequested = func() bool { return true }
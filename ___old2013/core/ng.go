@@ -31,7 +31,7 @@ func Init(fullscreen bool, ctx ngctx.CtxProvider) (err error) {
 	}
 	defer runtime.GC()
 	if len(Options.AppDir.Temp.BaseName) > 0 {
-		for _, diagTmpDirName := range []string{Options.AppDir.Temp.ShaderSources, Options.AppDir.Temp.CachedTextures} {
+		for _, diagTmpDirName := range []string{Options.AppDir.Temp.ShaderSources, Options.AppDir.Temp.CachedTextures, Options.AppDir.Temp.CachedMeshes} {
 			tmpDirPath = Core.fileIO.resolveLocalFilePath(filepath.Join(Options.AppDir.Temp.BaseName, diagTmpDirName))
 			if err = ufs.EnsureDirExists(tmpDirPath); err != nil {
 				return
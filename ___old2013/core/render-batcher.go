@@ -23,6 +23,10 @@ type renderBatchEntry struct {
 	dist                 float64
 	texes                []int
 	face                 int32
+
+	//	The number of entries drawn by this entry's draw call: 1 normally, more for the first entry
+	//	of an instanced run, 0 for the other entries in such a run.
+	instances, instOffset int
 }
 
 type renderBatchList struct {
@@ -31,6 +35,7 @@ type renderBatchList struct {
 	ki, kj    renderBatchDistStatsKey
 	n         int
 	prios     [numPrios]RenderBatchCriteria
+	byMeshFx  bool
 }
 
 func (me *renderBatchList) calcStats() {
@@ -98,6 +103,13 @@ func (me *renderBatchList) Less(i, j int) (less bool) {
 			}
 		}
 	}
+	if me.byMeshFx {
+		if me.all[i].mesh != me.all[j].mesh {
+			return me.all[i].mesh < me.all[j].mesh
+		} else if me.all[i].fx != me.all[j].fx {
+			return me.all[i].fx < me.all[j].fx
+		}
+	}
 	return me.all[i].dist < me.all[j].dist
 }
 
@@ -134,16 +146,20 @@ func (me renderBatchDistStats) add(dist float64, key renderBatchDistStatsKey) {
 type RenderBatcher struct {
 	Enabled  bool
 	Priority [numPrios]RenderBatchCriteria
+
+	//	If true, runs of batch entries sharing the same mesh and effect are drawn
+	//	with a single instanced draw call each. Defaults to true.
+	Instancing bool
 }
 
-func (me *RenderTechniqueScene) prepEntry(n, nid, fx int, fi int32) {
-	entry := &me.thrPrep.batch.all[n]
-	entry.node, entry.fx, entry.face, entry.mesh = nid, fx, fi, Core.Libs.Scenes[me.Camera.sceneID].allNodes[nid].Render.meshID
+func (me *RenderTechniqueScene) prepEntry(layer *CameraLayer, all SceneNodeLib, n, nid, fx int, fi int32) {
+	entry := &layer.thrPrep.batch.all[n]
+	entry.node, entry.fx, entry.face, entry.mesh, entry.instances = nid, fx, fi, layer.thrPrep.nodeMeshIDs[nid], 1
 	var distPos *unum.Vec3
 	if fi == -1 {
-		distPos = &Core.Libs.Scenes[me.Camera.sceneID].allNodes[nid].Transform.Pos
+		distPos = &all[nid].Transform.Pos
 	} else {
-		distPos = Core.Libs.Scenes[me.Camera.sceneID].allNodes[nid].Transform.Pos.Added(&Core.Libs.Meshes[entry.mesh].raw.faces[fi].center)
+		distPos = all[nid].Transform.Pos.Added(&Core.Libs.Meshes[entry.mesh].raw.faces[fi].center)
 	}
 	entry.dist = me.Camera.Controller.Pos.DistanceManhattan(distPos)
 	entry.prog = ogl.progs.Index(Core.Libs.Effects[fx].uberPnames[me.name()])
@@ -162,7 +178,8 @@ func (me *RenderTechniqueScene) prepEntry(n, nid, fx int, fi int32) {
 	me.thrPrep.Done()
 }
 
-func (me *RenderTechniqueScene) prepBatch(scene *Scene, size int) {
+//	Fills the batch of layer with the size entries to render for scene, returning the number of draw calls they take.
+func (me *RenderTechniqueScene) prepBatch(layer *CameraLayer, scene *Scene, size int) (numDrawCalls int) {
 	var (
 		mesh   *Mesh
 		mat    *FxMaterial
@@ -170,30 +187,41 @@ func (me *RenderTechniqueScene) prepBatch(scene *Scene, size int) {
 		fi, fl int32
 		nid    int
 	)
-	b := &me.thrPrep.batch
+	b := &layer.thrPrep.batch
 	b.n = 0
 	if len(b.all) < size {
 		b.all = make([]renderBatchEntry, size)
 	}
 	for nid = 1; nid < len(scene.allNodes); nid++ {
-		if scene.allNodes.Ok(nid) && me.Camera.thrPrep.nodeRender[nid] {
-			if mesh, mat = scene.allNodes[nid].meshMat(); mat.HasFaceEffects() {
-				for fi, fl = 0, int32(len(mesh.raw.faces)); fi < fl; fi++ {
-					if effect = mat.faceEffect(&mesh.raw.faces[fi]); effect != nil {
-						me.thrPrep.Add(1)
-						go me.prepEntry(b.n, nid, effect.ID, fi)
-						b.n++
-					}
+		if !(scene.allNodes.Ok(nid) && layer.thrPrep.nodeRender[nid]) {
+			continue
+		}
+		if mesh = Core.Libs.Meshes.get(layer.thrPrep.nodeMeshIDs[nid]); mesh == nil {
+			continue
+		}
+		if mat = scene.allNodes[nid].matFor(mesh); mat == nil {
+			continue
+		}
+		if mat.HasFaceEffects() {
+			for fi, fl = 0, int32(len(mesh.raw.faces)); fi < fl; fi++ {
+				if effect = mat.faceEffect(&mesh.raw.faces[fi]); effect != nil {
+					me.thrPrep.Add(1)
+					go me.prepEntry(layer, scene.allNodes, b.n, nid, effect.ID, fi)
+					b.n++
 				}
-			} else if effect = Core.Libs.Effects.get(mat.DefaultEffectID); effect != nil {
-				me.thrPrep.Add(1)
-				go me.prepEntry(b.n, nid, effect.ID, -1)
-				b.n++
 			}
+		} else if effect = Core.Libs.Effects.get(mat.DefaultEffectID); effect != nil {
+			me.thrPrep.Add(1)
+			go me.prepEntry(layer, scene.allNodes, b.n, nid, effect.ID, -1)
+			b.n++
 		}
 	}
-	b.prios = me.Batch.Priority
+	b.prios, b.byMeshFx = me.Batch.Priority, me.Batch.Instancing
 	me.thrPrep.Wait()
 	b.calcStats()
 	sort.Sort(b)
+	if numDrawCalls = size; me.Batch.Instancing {
+		numDrawCalls -= b.markInstanceRuns(scene.allNodes)
+	}
+	return
 }
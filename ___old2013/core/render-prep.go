@@ -21,32 +21,50 @@ func (me *RenderView) onPrep() {
 }
 
 func (me *RenderTechniqueScene) onPrep() {
-	if scene := me.Camera.Scene(); scene != nil {
-		me.Camera.thrPrep.matCamProj.SetFromMult4(&me.Camera.thrPrep.matProj, &me.Camera.Controller.thrPrep.mat)
-		// if me.Camera.Cull.Frustum {
-		// 	me.Camera.thrPrep.frustum.UpdatePlanes(&me.Camera.thrPrep.matCamProj, true)
-		// 	println(me.Camera.thrPrep.frustum.String())
-		// }
-		if !scene.thrPrep.done {
-			scene.thrPrep.done = true
-			scene.onPrep()
-		}
-		bc := 0
-		me.Camera.onPrep(scene.allNodes, 0, &bc)
-		me.numDrawCalls = bc
-		if me.Batch.Enabled {
-			me.prepBatch(scene, me.numDrawCalls)
+	me.Camera.thrPrep.matCamProj.SetFromMult4(&me.Camera.thrPrep.matProj, &me.Camera.Controller.thrPrep.mat)
+	// if me.Camera.Cull.Frustum {
+	// 	me.Camera.thrPrep.frustum.UpdatePlanes(&me.Camera.thrPrep.matCamProj, true)
+	// 	println(me.Camera.thrPrep.frustum.String())
+	// }
+	numDrawCalls := 0
+	for _, layer := range me.Camera.thrPrep.layers {
+		if scene := layer.Scene(); scene != nil && layer.thrPrep.enabled {
+			numDrawCalls += me.onPrepLayer(layer, scene)
+		} else {
+			layer.thrPrep.batch.n = 0
 		}
 	}
+	me.Camera.thrPrep.layer, me.numDrawCalls = nil, numDrawCalls
+}
+
+//	Prepares rendering scene in layer, returning the number of draw calls this will take.
+func (me *RenderTechniqueScene) onPrepLayer(layer *CameraLayer, scene *Scene) (numDrawCalls int) {
+	if !scene.thrPrep.done {
+		scene.thrPrep.done = true
+		scene.onPrep()
+	}
+	me.Camera.thrPrep.layer = layer
+	if me.Camera.Perspective.Enabled && me.Camera.Cull.Frustum {
+		me.Camera.onPrepSpatial(scene, &numDrawCalls)
+	} else {
+		me.Camera.onPrep(scene.allNodes, 0, &numDrawCalls)
+	}
+	if me.Camera.Cull.Occlusion && me.Camera.Perspective.Enabled {
+		me.Camera.onPrepOcclusion(scene.allNodes, &numDrawCalls)
+	}
+	if me.Batch.Enabled {
+		numDrawCalls = me.prepBatch(layer, scene, numDrawCalls)
+	}
+	return
 }
 
 func (me *Camera) onPrep(all SceneNodeLib, nodeID int, batchCounter *int) {
 	var mesh *Mesh
 	var mat *FxMaterial
-	prepChildren := all[nodeID].Render.Enabled && (all[nodeID].parentID < 1 || me.thrPrep.nodeRender[all[nodeID].parentID])
+	prepChildren := all[nodeID].Render.Enabled && (all[nodeID].parentID < 1 || me.thrPrep.layer.thrPrep.nodeRender[all[nodeID].parentID])
 	camNodeRender := prepChildren
 	if camNodeRender {
-		if mesh, mat = all[nodeID].meshMat(); mesh == nil || mat == nil {
+		if mesh, mat = all[nodeID].prepMeshMat(); mesh == nil || mat == nil {
 			prepChildren, camNodeRender = len(all[nodeID].childNodeIDs) > 0, false
 		}
 	}
@@ -60,28 +78,82 @@ func (me *Camera) onPrep(all SceneNodeLib, nodeID int, batchCounter *int) {
 		}
 	}
 	if camNodeRender {
-		if me.Perspective.Enabled {
-			if all[nodeID].Render.skyMode {
-				me.thrPrep.nodeProjMats[nodeID].SetFromMult4(&me.thrPrep.matCamProj, &me.thrPrep.matPos)
-			} else {
-				me.thrPrep.nodeProjMats[nodeID].SetFromMult4(&me.thrPrep.matCamProj, &all[nodeID].Transform.thrPrep.matModelView)
+		camNodeRender = me.prepNode(all, nodeID, mat, batchCounter)
+	}
+	me.thrPrep.layer.thrPrep.nodeRender[nodeID] = prepChildren
+	for i := 0; i < len(all[nodeID].childNodeIDs); i++ {
+		if all.IsOk(all[nodeID].childNodeIDs[i]) {
+			me.onPrep(all, all[nodeID].childNodeIDs[i], batchCounter)
+		}
+	}
+	me.thrPrep.layer.thrPrep.nodeRender[nodeID] = camNodeRender
+}
+
+//	Frustum-culls via the spatial index of scene, rather than by walking its whole node hierarchy.
+func (me *Camera) onPrepSpatial(scene *Scene, batchCounter *int) {
+	all := scene.allNodes
+	for i := 0; i < len(me.thrPrep.layer.thrPrep.nodeRender); i++ {
+		me.thrPrep.layer.thrPrep.nodeRender[i] = false
+	}
+	prep := func(nodeID int) {
+		if all.Ok(nodeID) && !me.thrPrep.layer.thrPrep.nodeRender[nodeID] && all.enabledInHierarchy(nodeID) {
+			if mesh, mat := all[nodeID].prepMeshMat(); mesh != nil && mat != nil {
+				me.thrPrep.layer.thrPrep.nodeRender[nodeID] = me.prepNode(all, nodeID, mat, batchCounter)
 			}
-		} else {
-			me.thrPrep.nodeProjMats[nodeID] = all[nodeID].Transform.thrPrep.matModelView
 		}
-		if mat.HasFaceEffects() {
-			*batchCounter = *batchCounter + len(mesh.raw.faces)
+	}
+	scene.thrPrep.spatial.walk(func(box *sceneOctreeBox) (inside, intersect bool) {
+		return me.frustumHasSphere(&box.center, box.radius)
+	}, func(nodeID int) {
+		if all[nodeID].Render.Cull.Frustum {
+			prep(nodeID)
+		}
+	})
+	for _, nodeID := range scene.thrPrep.unculled {
+		prep(nodeID)
+	}
+}
+
+//	Prepares rendering nodeID in this camera's next frame, unless its LodGroup has no level for its distance, in which case it returns false.
+func (me *Camera) prepNode(all SceneNodeLib, nodeID int, mat *FxMaterial, batchCounter *int) bool {
+	me.thrPrep.layer.thrPrep.nodeMeshIDs[nodeID] = me.prepNodeLod(all, nodeID)
+	mesh := Core.Libs.Meshes.get(me.thrPrep.layer.thrPrep.nodeMeshIDs[nodeID])
+	if mesh == nil {
+		me.thrPrep.layer.thrPrep.nodeMeshIDs[nodeID] = -1
+		return false
+	}
+	if mat = all[nodeID].matFor(mesh); mat == nil {
+		return false
+	}
+	if me.Perspective.Enabled {
+		if all[nodeID].Render.skyMode {
+			me.thrPrep.layer.thrPrep.nodeProjMats[nodeID].SetFromMult4(&me.thrPrep.matCamProj, &me.thrPrep.matPos)
 		} else {
-			*batchCounter = *batchCounter + 1
+			me.thrPrep.layer.thrPrep.nodeProjMats[nodeID].SetFromMult4(&me.thrPrep.matCamProj, &all[nodeID].Transform.thrPrep.matModelView)
 		}
+	} else {
+		me.thrPrep.layer.thrPrep.nodeProjMats[nodeID] = all[nodeID].Transform.thrPrep.matModelView
 	}
-	me.thrPrep.nodeRender[nodeID] = prepChildren
-	for i := 0; i < len(all[nodeID].childNodeIDs); i++ {
-		if all.IsOk(all[nodeID].childNodeIDs[i]) {
-			me.onPrep(all, all[nodeID].childNodeIDs[i], batchCounter)
+	*batchCounter = *batchCounter + nodeBatchCount(mesh, mat)
+	return true
+}
+
+//	Returns the number of renderBatchEntry items that prepBatch() adds for a node with the specified mesh and mat.
+func nodeBatchCount(mesh *Mesh, mat *FxMaterial) int {
+	if mat.HasFaceEffects() {
+		return len(mesh.raw.faces)
+	}
+	return 1
+}
+
+//	Returns true if the specified node and all its ancestors (other than the root node) are Render.Enabled.
+func (me SceneNodeLib) enabledInHierarchy(nodeID int) bool {
+	for id := nodeID; id > 0 || id == nodeID; id = me[id].parentID {
+		if !me[id].Render.Enabled {
+			return false
 		}
 	}
-	me.thrPrep.nodeRender[nodeID] = camNodeRender
+	return true
 }
 
 func (me *Scene) onPrep() {
@@ -0,0 +1,110 @@
+package core
+
+import (
+	gl "github.com/metaleap/go-opengl/core"
+	ugl "github.com/metaleap/go-opengl/util"
+)
+
+//	The texture unit that the per-instance matrices are bound to for instanced draw calls,
+//	the second one reserved above those of the FxProcs of an FxEffect.
+const renderInstMatsTexUnit = renderFxTexUnits + 1
+
+//	The "SceneInst" variant of the "Scene" technique: its programs use the vx_SceneInst_ vertex
+//	functions, reading each instance's matrix from a buffer texture rather than from uni_mat4_VertexMatrix.
+//	Only used by RenderTechniqueScene.renderBatched() for runs of batch entries sharing mesh and effect.
+type renderTechniqueSceneInst struct {
+	renderTechniqueBase
+	scene *RenderTechniqueScene
+}
+
+func (me *renderTechniqueSceneInst) render() {
+}
+
+func (me *renderTechniqueSceneInst) vertexAttribPointers(layout *MeshVertexLayout) []ugl.VertexAttribPointer {
+	return me.scene.vertexAttribPointers(layout)
+}
+
+//	Streams the per-instance matrices of all instanced draw calls of one frame into a buffer texture.
+type renderInstancer struct {
+	mats  []ugl.GlMat4
+	glBuf ugl.Buffer
+	glTex gl.Uint
+	cap   int
+}
+
+func (me *renderInstancer) dispose() {
+	me.glBuf.Dispose()
+	if me.glTex != 0 {
+		gl.DeleteTextures(1, &me.glTex)
+		me.glTex = 0
+	}
+	me.cap = 0
+}
+
+//	Uploads me.mats, (re)creating the buffer and its texture if they're too small.
+func (me *renderInstancer) upload() (err error) {
+	if len(me.mats) > 0 {
+		size := gl.Sizeiptr(len(me.mats) * len(me.mats[0]) * 4)
+		if len(me.mats) > me.cap {
+			me.cap = 2 * len(me.mats)
+			if err = me.glBuf.Recreate(gl.TEXTURE_BUFFER, gl.Sizeiptr(me.cap*len(me.mats[0])*4), ugl.PtrNil, gl.STREAM_DRAW); err != nil {
+				me.cap = 0
+				return
+			}
+			if me.glTex == 0 {
+				gl.GenTextures(1, &me.glTex)
+			}
+			gl.BindTexture(gl.TEXTURE_BUFFER, me.glTex)
+			gl.TexBuffer(gl.TEXTURE_BUFFER, gl.RGBA32F, me.glBuf.GlHandle)
+			gl.BindTexture(gl.TEXTURE_BUFFER, 0)
+		}
+		me.glBuf.Bind()
+		defer me.glBuf.Unbind()
+		err = me.glBuf.SubData(0, size, gl.Ptr(&me.mats[0]))
+	}
+	return
+}
+
+//	Marks all runs of consecutive (non-sky, non-skinned, non-morphing, non-per-face) entries sharing mesh and effect in the
+//	sorted batch, such that renderBatched() draws each run with a single instanced draw call.
+//	Returns the number of entries that no longer need their own draw call.
+func (me *renderBatchList) markInstanceRuns(all SceneNodeLib) (numSaved int) {
+	var i, j int
+	instanceable := func(e *renderBatchEntry) bool {
+		return e.face == -1 && !all[e.node].Render.skyMode && len(all[e.node].thrPrep.skinMats) == 0 && all[e.node].thrPrep.morphCount == 0
+	}
+	for i = 0; i < me.n; i = j {
+		me.all[i].instances = 1
+		for j = i + 1; j < me.n && instanceable(&me.all[i]) && me.all[j].mesh == me.all[i].mesh && me.all[j].fx == me.all[i].fx && instanceable(&me.all[j]); j++ {
+			me.all[i].instances, me.all[j].instances = me.all[i].instances+1, 0
+			numSaved++
+		}
+	}
+	return
+}
+
+func (me *RenderTechniqueScene) renderInstanced(mesh *Mesh, effect *FxEffect, entry *renderBatchEntry) {
+	thrRend.nextTech, thrRend.nextEffect = &me.inst, effect
+	Core.useTechFx()
+	mesh.meshBuffer.use()
+	ugl.Cache.BindTextureTo(renderInstMatsTexUnit, me.thrRend.instancer.glTex, gl.TEXTURE_BUFFER)
+	thrRend.curProg.Uniform1i("uni_samplerBuffer_InstMats", renderInstMatsTexUnit)
+	thrRend.curProg.Uniform1i("uni_int_InstOffset", gl.Int(entry.instOffset))
+	gl.DrawElementsInstancedBaseVertex(gl.TRIANGLES, mesh.raw.lastNumIndices, mesh.meshBufIndexType, gl.Util.PtrOffset(nil, uintptr(mesh.meshBufOffsetIndices)), gl.Sizei(entry.instances), gl.Int(mesh.meshBufOffsetBaseIndex))
+	thrRend.nextTech = me
+}
+
+//	Collects the matrices of all instanced runs in the current batch and uploads them in one go.
+func (me *RenderTechniqueScene) renderInstancedPrep() (err error) {
+	b, inst := &thrRend.curLayer.thrRend.batch, &me.thrRend.instancer
+	inst.mats = inst.mats[:0]
+	for i := 0; i < b.n; i++ {
+		if b.all[i].instances > 1 {
+			b.all[i].instOffset = len(inst.mats)
+			for j := i; j < i+b.all[i].instances; j++ {
+				inst.mats = append(inst.mats, thrRend.curLayer.thrRend.nodeProjMats[b.all[j].node])
+			}
+		}
+	}
+	return inst.upload()
+}
@@ -0,0 +1,42 @@
+package core
+
+import (
+	gl "github.com/metaleap/go-opengl/core"
+	ugl "github.com/metaleap/go-opengl/util"
+)
+
+//	The texture unit that the morph-target deltas of a Mesh are bound to,
+//	the first one reserved above those of the FxProcs of an FxEffect.
+const renderMorphTexUnit = renderFxTexUnits
+
+//	The "SceneMorph" variant of the "Scene" technique: its programs use the vx_SceneMorph_ vertex functions,
+//	which add the weighted morph-target deltas from a buffer texture to each vertex position.
+//	Used by RenderTechniqueScene for all non-skinned nodes with non-zero Render.MorphWeights.
+type renderTechniqueSceneMorph struct {
+	renderTechniqueBase
+	scene *RenderTechniqueScene
+}
+
+func (me *renderTechniqueSceneMorph) render() {
+}
+
+//	Uploads the morph weights of node, and binds the morph-target deltas of mesh, for the current program.
+func (me *renderTechniqueSceneMorph) useNode(node *SceneNode, mesh *Mesh) {
+	count := node.thrRend.morphCount
+	if mesh.morphGpu.glTex == 0 {
+		count = 0
+	} else if n := mesh.NumMorphTargets(); count > n {
+		count = n
+	}
+	if thrRend.curProg.Uniform1i("uni_int_MorphCount", gl.Int(count)); count > 0 {
+		ugl.Cache.BindTextureTo(renderMorphTexUnit, mesh.morphGpu.glTex, gl.TEXTURE_BUFFER)
+		thrRend.curProg.Uniform1i("uni_samplerBuffer_MorphDeltas", renderMorphTexUnit)
+		thrRend.curProg.Uniform1i("uni_int_MorphNumVerts", mesh.morphGpu.numVerts)
+		thrRend.curProg.Uniform1i("uni_int_MorphBaseVertex", gl.Int(mesh.meshBufOffsetBaseIndex))
+		thrRend.curProg.UniformMat4("uni_mat4_MorphWeights", &node.thrRend.morphWeights)
+	}
+}
+
+func (me *renderTechniqueSceneMorph) vertexAttribPointers(layout *MeshVertexLayout) []ugl.VertexAttribPointer {
+	return me.scene.vertexAttribPointers(layout)
+}
@@ -0,0 +1,34 @@
+package core
+
+import (
+	gl "github.com/metaleap/go-opengl/core"
+	ugl "github.com/metaleap/go-opengl/util"
+)
+
+//	The "SceneSkin" variant of the "Scene" technique: its programs use the vx_SceneSkin_ vertex functions,
+//	which blend each vertex by up to 4 joint matrices of the palette in uni_mat4_SkinJoints.
+//	Used by RenderTechniqueScene for all nodes skinned via Render.Skeleton. Also applies morph targets.
+type renderTechniqueSceneSkin struct {
+	renderTechniqueBase
+	scene *RenderTechniqueScene
+}
+
+func (me *renderTechniqueSceneSkin) render() {
+}
+
+//	Uploads the joint-matrix palette and morph weights of node to the current program.
+func (me *renderTechniqueSceneSkin) useNode(node *SceneNode, mesh *Mesh) {
+	thrRend.curProg.UniformMatrix4fv("uni_mat4_SkinJoints", gl.Sizei(len(node.thrRend.skinMats)), gl.FALSE, &node.thrRend.skinMats[0][0])
+	me.scene.morph.useNode(node, mesh)
+}
+
+func (me *renderTechniqueSceneSkin) vertexAttribPointers(layout *MeshVertexLayout) (atts []ugl.VertexAttribPointer) {
+	if atts = me.scene.vertexAttribPointers(layout); layout.Skin {
+		num, _, _, _, skin := layout.offsets()
+		stride := gl.Sizei(4 * num)
+		atts = append(atts, ugl.VertexAttribPointer{}, ugl.VertexAttribPointer{})
+		atts[len(atts)-2].Init("att_vec4_SkinJoints", meshSkinInfluencesPerVertex, stride, gl.Util.PtrOffset(nil, uintptr(4*skin)))
+		atts[len(atts)-1].Init("att_vec4_SkinWeights", meshSkinInfluencesPerVertex, stride, gl.Util.PtrOffset(nil, uintptr(4*(skin+meshSkinInfluencesPerVertex))))
+	}
+	return
+}
@@ -3,7 +3,6 @@ package core
 import (
 	"sync"
 
-	gl "github.com/metaleap/go-opengl/core"
 	ugl "github.com/metaleap/go-opengl/util"
 )
 
@@ -14,12 +13,15 @@ type RenderTechniqueScene struct {
 	Batch  RenderBatcher
 	Camera Camera
 
+	inst  renderTechniqueSceneInst
+	morph renderTechniqueSceneMorph
+	skin  renderTechniqueSceneSkin
+
 	thrPrep struct {
 		sync.WaitGroup
-		batch renderBatchList
 	}
 	thrRend struct {
-		batch renderBatchList
+		instancer renderInstancer
 	}
 }
 
@@ -27,12 +29,21 @@ func newRenderTechniqueScene(view *RenderView) RenderTechnique {
 	me := &RenderTechniqueScene{}
 	view.RenderStates.DepthTest = true
 	me.init("Scene", view)
+	me.inst.init("SceneInst", view)
+	me.morph.init("SceneMorph", view)
+	me.skin.init("SceneSkin", view)
+	me.inst.scene, me.morph.scene, me.skin.scene = me, me, me
 	me.Batch = Options.Rendering.DefaultBatcher
 	me.Camera.init()
 	me.ApplyCameraPerspective()
 	return me
 }
 
+func (me *RenderTechniqueScene) dispose() {
+	me.thrRend.instancer.dispose()
+	me.renderTechniqueBase.dispose()
+}
+
 //	Applies changes made to the Enabled, FovY, ZNear and/or ZFar parameters in me.Camera.Perspective.
 func (me *RenderTechniqueScene) ApplyCameraPerspective() {
 	me.Camera.viewportAspectRatio = me.view.Port.aspect
@@ -47,9 +58,26 @@ func (me *RenderTechniqueScene) ToggleBatching() {
 	me.Batch.Enabled = !me.Batch.Enabled
 }
 
-func (me *RenderTechniqueScene) vertexAttribPointers() (atts []ugl.VertexAttribPointer) {
-	atts = make([]ugl.VertexAttribPointer, 2)
-	atts[0].Init("att_vec3_Pos", 3, 8*4, ugl.PtrNil)
-	atts[1].Init("att_vec2_Tex2D", 2, 8*4, gl.Util.PtrOffset(nil, 3*4))
-	return
+//	Returns the technique whose program is to render node with mesh: a variant for skinned or morphing nodes, else me.
+func (me *RenderTechniqueScene) nodeTech(node *SceneNode, mesh *Mesh) RenderTechnique {
+	if len(node.thrRend.skinMats) > 0 && mesh.meshBuffer != nil && mesh.meshBuffer.Layout.Skin {
+		return &me.skin
+	} else if node.thrRend.morphCount > 0 && mesh.morphGpu.glTex != 0 {
+		return &me.morph
+	}
+	return me
+}
+
+//	Sets the per-node uniforms of the current program if it belongs to a variant technique returned by nodeTech().
+func (me *RenderTechniqueScene) useNodeTech(node *SceneNode, mesh *Mesh) {
+	switch thrRend.curTech {
+	case &me.skin:
+		me.skin.useNode(node, mesh)
+	case &me.morph:
+		me.morph.useNode(node, mesh)
+	}
+}
+
+func (me *RenderTechniqueScene) vertexAttribPointers(layout *MeshVertexLayout) []ugl.VertexAttribPointer {
+	return layout.vertexAttribPointers()
 }
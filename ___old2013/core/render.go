@@ -57,13 +57,19 @@ func (me *RenderTechniqueQuad) render() {
 func (me *RenderTechniqueScene) render() {
 	thrRend.nextTech = me
 	thrRend.curCam = &me.Camera
-	if scene := me.Camera.Scene(); scene != nil {
-		if me.Batch.Enabled {
-			me.renderBatched(scene)
-		} else {
-			scene.render()
+	for _, layer := range me.Camera.thrRend.layers {
+		if scene := layer.Scene(); scene != nil && layer.thrRend.enabled {
+			if thrRend.curLayer = layer; layer.thrRend.clearDepth {
+				gl.Clear(gl.DEPTH_BUFFER_BIT)
+			}
+			if me.Batch.Enabled {
+				me.renderBatched(scene)
+			} else {
+				scene.render()
+			}
 		}
 	}
+	thrRend.curLayer = nil
 }
 
 func (me *RenderTechniqueScene) renderBatched(scene *Scene) {
@@ -72,23 +78,38 @@ func (me *RenderTechniqueScene) renderBatched(scene *Scene) {
 		node   *SceneNode
 		effect *FxEffect
 	)
-	b := &me.thrRend.batch
+	b := &thrRend.curLayer.thrRend.batch
+	instancing := me.Batch.Instancing
+	if instancing {
+		if err := me.renderInstancedPrep(); err != nil {
+			Diag.LogErr(err)
+			instancing = false
+		}
+	}
 	for i := 0; i < b.n; i++ {
-		if node, mesh, effect = scene.allNodes.get(b.all[i].node), Core.Libs.Meshes.get(b.all[i].mesh), Core.Libs.Effects.get(b.all[i].fx); effect != nil && mesh != nil && node != nil && thrRend.curCam.thrRend.nodeRender[node.ID] {
-			thrRend.nextEffect = effect
+		if b.all[i].instances == 0 && instancing {
+			continue
+		}
+		if node, mesh, effect = scene.allNodes.get(b.all[i].node), Core.Libs.Meshes.get(b.all[i].mesh), Core.Libs.Effects.get(b.all[i].fx); effect != nil && mesh != nil && node != nil && thrRend.curLayer.thrRend.nodeRender[node.ID] {
+			if b.all[i].instances > 1 && instancing {
+				me.renderInstanced(mesh, effect, &b.all[i])
+				continue
+			}
+			thrRend.nextTech, thrRend.nextEffect = me.nodeTech(node, mesh), effect
 			Core.useTechFx()
+			me.useNodeTech(node, mesh)
 			mesh.meshBuffer.use()
 			if node.Render.skyMode {
 				Core.Render.states.DisableFaceCulling()
 				gl.DepthFunc(gl.LEQUAL)
 				thrRend.curProg.Uniform1i("uni_int_Sky", 1)
 			}
-			thrRend.curProg.UniformMat4("uni_mat4_VertexMatrix", &thrRend.curCam.thrRend.nodeProjMats[node.ID])
-			// thrRend.curProg.UniformMatrix4fv("uni_mat4_VertexMatrix", 1, gl.FALSE, &thrRend.curCam.thrRend.nodeProjMats[me.ID][0])
+			thrRend.curProg.UniformMat4("uni_mat4_VertexMatrix", &thrRend.curLayer.thrRend.nodeProjMats[node.ID])
+			// thrRend.curProg.UniformMatrix4fv("uni_mat4_VertexMatrix", 1, gl.FALSE, &thrRend.curLayer.thrRend.nodeProjMats[me.ID][0])
 			if b.all[i].face == -1 {
-				gl.DrawElementsBaseVertex(gl.TRIANGLES, mesh.raw.lastNumIndices, gl.UNSIGNED_INT, gl.Util.PtrOffset(nil, uintptr(mesh.meshBufOffsetIndices)), gl.Int(mesh.meshBufOffsetBaseIndex))
+				gl.DrawElementsBaseVertex(gl.TRIANGLES, mesh.raw.lastNumIndices, mesh.meshBufIndexType, gl.Util.PtrOffset(nil, uintptr(mesh.meshBufOffsetIndices)), gl.Int(mesh.meshBufOffsetBaseIndex))
 			} else {
-				gl.DrawElementsBaseVertex(gl.TRIANGLES, 3, gl.UNSIGNED_INT, gl.Util.PtrOffset(nil, uintptr(mesh.meshBufOffsetIndices+(b.all[i].face*3*Core.Mesh.Buffers.MemSizePerIndex()))), gl.Int(mesh.meshBufOffsetBaseIndex))
+				gl.DrawElementsBaseVertex(gl.TRIANGLES, 3, mesh.meshBufIndexType, gl.Util.PtrOffset(nil, uintptr(mesh.meshBufOffsetIndices+(b.all[i].face*3*mesh.meshBufIndexSize))), gl.Int(mesh.meshBufOffsetBaseIndex))
 			}
 			if node.Render.skyMode {
 				thrRend.curProg.Uniform1i("uni_int_Sky", 0)
@@ -96,42 +117,53 @@ func (me *RenderTechniqueScene) renderBatched(scene *Scene) {
 			}
 		}
 	}
-	if thrRend.curCam.thrRend.nodeRender[0] {
+	if thrRend.curLayer.thrRend.nodeRender[0] {
 		scene.allNodes[0].render()
 	}
 }
 
 func (me *Scene) render() {
 	for id := len(me.allNodes) - 1; id > -1; id-- {
-		if me.allNodes.Ok(id) && thrRend.curCam.thrRend.nodeRender[id] {
+		if me.allNodes.Ok(id) && thrRend.curLayer.thrRend.nodeRender[id] {
 			me.allNodes[id].render()
 		}
 	}
 }
 
 func (me *SceneNode) render() {
-	mesh, mat := me.meshMat()
+	mesh := Core.Libs.Meshes.get(thrRend.curLayer.thrRend.nodeMeshIDs[me.ID])
+	if mesh == nil {
+		return
+	}
+	mat := me.matFor(mesh)
+	if mat == nil {
+		return
+	}
+	tech := thrRend.curView.Technique_Scene()
+	thrRend.nextTech = tech.nodeTech(me, mesh)
 	if mat.HasFaceEffects() {
 		for i, l := int32(0), int32(len(mesh.raw.faces)); i < l; i++ {
 			thrRend.nextEffect = mat.faceEffect(&mesh.raw.faces[i])
 			Core.useTechFx()
+			tech.useNodeTech(me, mesh)
 			mesh.meshBuffer.use()
-			thrRend.curProg.UniformMat4("uni_mat4_VertexMatrix", &thrRend.curCam.thrRend.nodeProjMats[me.ID])
-			// thrRend.curProg.UniformMatrix4fv("uni_mat4_VertexMatrix", 1, gl.FALSE, &thrRend.curCam.thrRend.nodeProjMats[me.ID][0])
-			gl.DrawElementsBaseVertex(gl.TRIANGLES, 3, gl.UNSIGNED_INT, gl.Util.PtrOffset(nil, uintptr(mesh.meshBufOffsetIndices+(i*3*Core.Mesh.Buffers.MemSizePerIndex()))), gl.Int(mesh.meshBufOffsetBaseIndex))
+			thrRend.curProg.UniformMat4("uni_mat4_VertexMatrix", &thrRend.curLayer.thrRend.nodeProjMats[me.ID])
+			// thrRend.curProg.UniformMatrix4fv("uni_mat4_VertexMatrix", 1, gl.FALSE, &thrRend.curLayer.thrRend.nodeProjMats[me.ID][0])
+			gl.DrawElementsBaseVertex(gl.TRIANGLES, 3, mesh.meshBufIndexType, gl.Util.PtrOffset(nil, uintptr(mesh.meshBufOffsetIndices+(i*3*mesh.meshBufIndexSize))), gl.Int(mesh.meshBufOffsetBaseIndex))
 		}
 	} else {
 		thrRend.nextEffect = Core.Libs.Effects.get(mat.DefaultEffectID)
 		Core.useTechFx()
+		tech.useNodeTech(me, mesh)
 		mesh.meshBuffer.use()
 		if me.Render.skyMode {
 			Core.Render.states.DisableFaceCulling()
 			gl.DepthFunc(gl.LEQUAL)
 			thrRend.curProg.Uniform1i("uni_int_Sky", 1)
 		}
-		thrRend.curProg.UniformMat4("uni_mat4_VertexMatrix", &thrRend.curCam.thrRend.nodeProjMats[me.ID])
-		// thrRend.curProg.UniformMatrix4fv("uni_mat4_VertexMatrix", 1, gl.FALSE, &thrRend.curCam.thrRend.nodeProjMats[me.ID][0])
-		gl.DrawElementsBaseVertex(gl.TRIANGLES, mesh.raw.lastNumIndices, gl.UNSIGNED_INT, gl.Util.PtrOffset(nil, uintptr(mesh.meshBufOffsetIndices)), gl.Int(mesh.meshBufOffsetBaseIndex))
+		thrRend.curProg.UniformMat4("uni_mat4_VertexMatrix", &thrRend.curLayer.thrRend.nodeProjMats[me.ID])
+		// thrRend.curProg.UniformMatrix4fv("uni_mat4_VertexMatrix", 1, gl.FALSE, &thrRend.curLayer.thrRend.nodeProjMats[me.ID][0])
+		gl.DrawElementsBaseVertex(gl.TRIANGLES, mesh.raw.lastNumIndices, mesh.meshBufIndexType, gl.Util.PtrOffset(nil, uintptr(mesh.meshBufOffsetIndices)), gl.Int(mesh.meshBufOffsetBaseIndex))
 		if me.Render.skyMode {
 			thrRend.curProg.Uniform1i("uni_int_Sky", 0)
 			gl.DepthFunc(gl.LESS)
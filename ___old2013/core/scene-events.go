@@ -0,0 +1,58 @@
+package core
+
+//	Handles a structural change to the node with the specified nodeID in scene.
+type SceneNodeHandler func(scene *Scene, nodeID int)
+
+type SceneNodeHandlers []SceneNodeHandler
+
+func (me *SceneNodeHandlers) Add(fn SceneNodeHandler) {
+	if fn != nil {
+		*me = append(*me, fn)
+	}
+}
+
+func (me SceneNodeHandlers) callAll(scene *Scene, nodeID int) {
+	for _, fn := range me {
+		fn(scene, nodeID)
+	}
+}
+
+//	Handles the move of the node with the specified nodeID in scene from oldParentID to its new parent.
+type SceneNodeReparentedHandler func(scene *Scene, nodeID, oldParentID int)
+
+type SceneNodeReparentedHandlers []SceneNodeReparentedHandler
+
+func (me *SceneNodeReparentedHandlers) Add(fn SceneNodeReparentedHandler) {
+	if fn != nil {
+		*me = append(*me, fn)
+	}
+}
+
+func (me SceneNodeReparentedHandlers) callAll(scene *Scene, nodeID, oldParentID int) {
+	for _, fn := range me {
+		fn(scene, nodeID, oldParentID)
+	}
+}
+
+//	Observer hooks for structural changes to a Scene, see Scene.On.
+//	All handlers are called synchronously, on the thread making the change (usually the app thread).
+type SceneEvents struct {
+	//	Called by Scene.AddNewChildNode(), Scene.CloneSubtree() and Scene.InstantiatePrefab() for each new node
+	//	(parents before their child-nodes), once all new nodes are fully set up: with their final Name, Render
+	//	and Transform, and their transformations and bounds applied.
+	NodeAdded SceneNodeHandlers
+
+	//	Called by Scene.RemoveNode() for each removed node (child-nodes first), right before its removal.
+	NodeRemoved SceneNodeHandlers
+
+	//	Called by Scene.SetNodeMeshID() after the node's mesh was changed.
+	NodeMeshChanged SceneNodeHandlers
+
+	//	Called by Scene.SetParent() after the node was moved to its new parent.
+	NodeReparented SceneNodeReparentedHandlers
+
+	//	Called by Scene.ApplyNodeTransforms() for the node and each of its child-nodes whose local or
+	//	world transformation actually changed, once the node's new transformation and bounds are in place.
+	//	Also called for each new node, right after NodeAdded (and just once, regardless of that node's Transform).
+	NodeTransformed SceneNodeHandlers
+}
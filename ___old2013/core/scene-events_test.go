@@ -0,0 +1,79 @@
+package core
+
+import (
+	"testing"
+
+	"github.com/metaleap/go-util-num"
+)
+
+//	Records all NodeAdded and NodeTransformed events of scene into log, along with the state of each node at that time.
+func sceneTestObserve(scene *Scene, meshID int, log *[]string) {
+	record := func(event string) SceneNodeHandler {
+		return func(scene *Scene, nodeID int) {
+			node, mat := &scene.allNodes[nodeID], &scene.allNodes[nodeID].Transform.thrApp.matModelView
+			*log = append(*log, strf("%s %q mesh:%v frustum:%v pos:%v world:%v", event, node.Name, node.meshID() == meshID,
+				node.Render.Cull.Frustum, node.Transform.Pos, unum.Vec3{mat[12], mat[13], mat[14]}))
+		}
+	}
+	scene.On.NodeAdded.Add(record("added"))
+	scene.On.NodeTransformed.Add(record("transformed"))
+}
+
+func TestSceneEventsCloned(t *testing.T) {
+	meshID := sceneTestMesh(t, "leaf", 2)
+	defer Core.Libs.Meshes.Remove(meshID, 1)
+	srcID := Core.Libs.Scenes.AddNew()
+	defer Core.Libs.Scenes.Remove(srcID, 1)
+	dstID := Core.Libs.Scenes.AddNew()
+	defer Core.Libs.Scenes.Remove(dstID, 1)
+	src, dst := &Core.Libs.Scenes[srcID], &Core.Libs.Scenes[dstID]
+
+	//	a parent at (1, 0, 0) with a leaf 2 units above it
+	parentID := src.AddNewChildNode(0, -1)
+	src.Node(parentID).Name = "parent"
+	src.Node(parentID).Transform.SetPos(1, 0, 0)
+	leafID := src.AddNewChildNode(parentID, meshID)
+	src.Node(leafID).Name, src.Node(leafID).Render.Cull.Frustum = "leaf", false
+	src.Node(leafID).Transform.SetPos(0, 2, 0)
+	src.ApplyNodeTransforms(parentID)
+	prefabID := Core.Libs.Scenes.AddNewPrefab(srcID, parentID)
+	defer Core.Libs.Scenes.Remove(prefabID, 1)
+	src, dst = &Core.Libs.Scenes[srcID], &Core.Libs.Scenes[dstID]
+
+	var log []string
+	sceneTestObserve(src, meshID, &log)
+	if src.CloneSubtree(parentID, 0) < 0 {
+		t.Fatalf("CloneSubtree() failed")
+	}
+	for i, want := range []string{
+		`added "parent" mesh:false frustum:true pos:{1 0 0} world:{1 0 0}`,
+		`transformed "parent" mesh:false frustum:true pos:{1 0 0} world:{1 0 0}`,
+		`added "leaf" mesh:true frustum:false pos:{0 2 0} world:{1 2 0}`,
+		`transformed "leaf" mesh:true frustum:false pos:{0 2 0} world:{1 2 0}`,
+	} {
+		if i >= len(log) || log[i] != want {
+			t.Errorf("CloneSubtree(): got events %q, want %q at %v", log, want, i)
+			break
+		}
+	}
+
+	//	the prefab's copy of the parent is at its origin
+	log = log[:0]
+	sceneTestObserve(dst, meshID, &log)
+	if dst.InstantiatePrefab(prefabID, 0) < 0 {
+		t.Fatalf("InstantiatePrefab() failed")
+	}
+	for i, want := range []string{
+		`added "" mesh:false frustum:true pos:{0 0 0} world:{0 0 0}`,
+		`transformed "" mesh:false frustum:true pos:{0 0 0} world:{0 0 0}`,
+		`added "parent" mesh:false frustum:true pos:{0 0 0} world:{0 0 0}`,
+		`transformed "parent" mesh:false frustum:true pos:{0 0 0} world:{0 0 0}`,
+		`added "leaf" mesh:true frustum:false pos:{0 2 0} world:{0 2 0}`,
+		`transformed "leaf" mesh:true frustum:false pos:{0 2 0} world:{0 2 0}`,
+	} {
+		if i >= len(log) || log[i] != want {
+			t.Errorf("InstantiatePrefab(): got events %q, want %q at %v", log, want, i)
+			break
+		}
+	}
+}
@@ -0,0 +1,157 @@
+package core
+
+import (
+	"math"
+
+	u3d "github.com/metaleap/go-util-3d"
+)
+
+//	Describes alternative meshes of decreasing detail for a SceneNode or Model.
+//	Each camera picks the level to render per node in its prep stage.
+type LodGroup struct {
+	//	Ordered from the most to the least detailed level. If empty, the node's own mesh is always rendered.
+	//	If no level fits the current distance or screen size, the node is not rendered at all.
+	Levels []LodLevel
+
+	//	To avoid popping back and forth near a threshold, a level is only switched
+	//	once the threshold has been passed by this fraction of it. Defaults to 0.1.
+	Hysteresis float64
+}
+
+func (me *LodGroup) init() {
+	me.Levels, me.Hysteresis = nil, 0.1
+}
+
+//	Returns true if level lvl is suitable for the specified camera distance and screen size,
+//	with its thresholds relaxed (if scale > 1) or tightened (if scale < 1) by scale.
+func (me *LodGroup) fits(lvl int, dist, size, scale float64) bool {
+	l := &me.Levels[lvl]
+	return (l.MaxDist <= 0 || dist <= l.MaxDist*scale) && (l.MinScreenSize <= 0 || size >= l.MinScreenSize/scale)
+}
+
+//	Returns the level to use, given the previously used level cur (or -1), or -1 if no level fits.
+func (me *LodGroup) pick(cur int, dist, size float64) (lvl int) {
+	if lvl = -1; cur > -1 && cur < len(me.Levels) {
+		for i := 0; i < cur; i++ {
+			if me.fits(i, dist, size, 1-me.Hysteresis) {
+				return i
+			}
+		}
+		if me.fits(cur, dist, size, 1+me.Hysteresis) {
+			return cur
+		}
+		for i := cur + 1; i < len(me.Levels); i++ {
+			if me.fits(i, dist, size, 1) {
+				return i
+			}
+		}
+	} else {
+		for i := 0; i < len(me.Levels); i++ {
+			if me.fits(i, dist, size, 1) {
+				return i
+			}
+		}
+	}
+	return
+}
+
+//	A single level in a LodGroup.
+type LodLevel struct {
+	//	The Mesh in Core.Libs.Meshes to render at this level, see MeshLib.Handle().
+	//	The node's material is used regardless, as are its bounds (based on its own mesh or, for a node
+	//	without one, on the mesh of its first level).
+	Mesh MeshHandle
+
+	//	If greater than 0, this level is only used up to this distance from the camera.
+	MaxDist float64
+
+	//	If greater than 0, this level is only used as long as the node's bounding sphere
+	//	covers at least this fraction of the viewport height.
+	MinScreenSize float64
+}
+
+//	Returns the LodGroup of me if it has any Levels, else that of its Model, if any.
+func (me *SceneNode) lodGroup() *LodGroup {
+	if len(me.Render.Lod.Levels) > 0 {
+		return &me.Render.Lod
+	}
+	model := Core.Libs.Models.deref(me.Render.Model)
+	if mesh := me.mesh(); model == nil && mesh != nil {
+		model = Core.Libs.Models.get(mesh.DefaultModelID)
+	}
+	if model != nil && len(model.Lod.Levels) > 0 {
+		return &model.Lod
+	}
+	return nil
+}
+
+//	Returns the mesh whose bounds the bounds of me are based on: its own mesh, or else the first level of its lodGroup().
+func (me *SceneNode) boundsMesh() (mesh *Mesh) {
+	if mesh = me.mesh(); mesh == nil {
+		if lod := me.lodGroup(); lod != nil {
+			mesh = Core.Libs.Meshes.deref(lod.Levels[0].Mesh)
+		}
+	}
+	return
+}
+
+//	Returns the mesh bounds that the bounds of me are based on, see boundsMesh().
+func (me *SceneNode) boundsSrc() *u3d.Bounds {
+	if mesh := me.boundsMesh(); mesh != nil {
+		return &mesh.raw.bounding
+	}
+	return nil
+}
+
+//	Returns true if me renders any of the specified meshes, as its own or as a level of its lodGroup().
+func (me *SceneNode) rendersAnyMesh(meshIDs map[int]bool) bool {
+	if meshIDs[me.meshID()] {
+		return true
+	}
+	if lod := me.lodGroup(); lod != nil {
+		for i := 0; i < len(lod.Levels); i++ {
+			if mesh := Core.Libs.Meshes.deref(lod.Levels[i].Mesh); mesh != nil && meshIDs[mesh.ID] {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+//	Snapshots the lodGroup() of me, along with the current IDs of its level meshes, for the prep thread:
+//	the app thread may meanwhile modify the Levels of both me and its Model.
+func (me *SceneNode) copyLodAppToPrep() {
+	prep := &me.thrPrep
+	prep.lod.Levels, prep.lodMeshIDs = prep.lod.Levels[:0], prep.lodMeshIDs[:0]
+	if lod := me.lodGroup(); lod != nil {
+		prep.lod.Hysteresis, prep.lod.Levels = lod.Hysteresis, append(prep.lod.Levels, lod.Levels...)
+		for i := 0; i < len(lod.Levels); i++ {
+			meshID := -1
+			if mesh := Core.Libs.Meshes.deref(lod.Levels[i].Mesh); mesh != nil {
+				meshID = mesh.ID
+			}
+			prep.lodMeshIDs = append(prep.lodMeshIDs, meshID)
+		}
+	}
+}
+
+//	Picks the mesh to render for nodeID in this camera's next frame. Returns -1 if the node's LodGroup has no level for the current distance.
+func (me *Camera) prepNodeLod(all SceneNodeLib, nodeID int) (meshID int) {
+	if meshID = all[nodeID].meshID(); !all[nodeID].Render.skyMode {
+		if lod := &all[nodeID].thrPrep.lod; len(lod.Levels) > 0 {
+			mat := &all[nodeID].Transform.thrPrep.matModelView
+			pos := &me.Controller.thrPrep.pos
+			dx, dy, dz := mat[12]-pos.X, mat[13]-pos.Y, mat[14]-pos.Z
+			dist, size := math.Sqrt(dx*dx+dy*dy+dz*dz), 1.0
+			if me.Perspective.Enabled && dist > 0 {
+				size = all[nodeID].thrPrep.bounding.self.Sphere / (dist * math.Tan(me.Perspective.FovY.Deg*math.Pi/360))
+			}
+			if me.thrPrep.layer.thrPrep.nodeLod[nodeID] = lod.pick(me.thrPrep.layer.thrPrep.nodeLod[nodeID], dist, size); me.thrPrep.layer.thrPrep.nodeLod[nodeID] < 0 {
+				meshID = -1
+			} else if lvlMeshID := all[nodeID].thrPrep.lodMeshIDs[me.thrPrep.layer.thrPrep.nodeLod[nodeID]]; lvlMeshID > -1 {
+				meshID = lvlMeshID
+			}
+		}
+	}
+	return
+}
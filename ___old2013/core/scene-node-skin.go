@@ -0,0 +1,93 @@
+package core
+
+import (
+	"github.com/metaleap/go-util-num"
+	u3d "github.com/metaleap/go-util-3d"
+)
+
+//	The app-thread skinning state of a SceneNode.
+type sceneNodeSkin struct {
+	bound   bool
+	boundTo SceneNodeHandle
+	joints  []SceneNodeHandle
+	mats    []unum.Mat4
+}
+
+//	Makes me look up its joint nodes by name again before the next frame.
+//	Only necessary after renaming, adding or removing nodes in the sub-tree of me.Render.Skeleton.
+func (me *SceneNode) RebindSkin() {
+	me.thrApp.skin.bound = false
+}
+
+//	Computes the joint-matrix palettes and skinned bounds of all skinned nodes in me.
+//	Called on the app thread, after Loop.On.AppThread().
+func (me *Scene) onSkin() {
+	for id := 1; id < len(me.allNodes); id++ {
+		if me.allNodes.Ok(id) {
+			if me.allNodes[id].thrApp.skin.mats = me.allNodes[id].thrApp.skin.mats[:0]; !me.allNodes[id].Render.Skeleton.IsNil() {
+				me.skinNode(id)
+			}
+		}
+	}
+}
+
+func (me *Scene) skinNode(nodeID int) {
+	node := &me.allNodes[nodeID]
+	mesh, skin := node.mesh(), &node.thrApp.skin
+	skel := me.allNodes.Deref(&node.Render.Skeleton)
+	if mesh == nil || mesh.raw.skin == nil || skel == nil {
+		return
+	}
+	raw := mesh.raw.skin
+	if !skin.bound || skin.boundTo != node.Render.Skeleton || len(skin.joints) != len(raw.joints) {
+		skin.bound, skin.boundTo, skin.joints = true, node.Render.Skeleton, skin.joints[:0]
+		for j := 0; j < len(raw.joints); j++ {
+			skin.joints = append(skin.joints, me.allNodes.Handle(me.NodeByName(skel.ID, raw.joints[j].Name)))
+		}
+	}
+	var (
+		matInv, matTmp unum.Mat4
+		box, jointBox  u3d.AaBb
+		boxOk, missing bool
+		joint          *SceneNode
+	)
+	mat4InvertAffine(&matInv, &node.Transform.thrApp.matModelView)
+	for j := 0; j < len(raw.joints); j++ {
+		skin.mats = append(skin.mats, unum.Mat4{})
+		if joint = me.allNodes.Deref(&skin.joints[j]); joint == nil {
+			//	a missing joint keeps its vertices in the bind pose
+			skin.mats[j].Identity()
+			missing = true
+			continue
+		}
+		matTmp.SetFromMult4(&joint.Transform.thrApp.matModelView, &raw.jointMats[j])
+		skin.mats[j].SetFromMult4(&matInv, &matTmp)
+		if raw.jointBoxOk[j] {
+			jointBox = raw.jointBoxes[j]
+			jointBox.Transform(&joint.Transform.thrApp.matModelView)
+			if boxOk {
+				box.UpdateMinMaxFrom(&jointBox)
+			} else {
+				box, boxOk = jointBox, true
+			}
+		}
+	}
+	if missing || !boxOk {
+		jointBox = mesh.raw.bounding.AaBox
+		jointBox.Transform(&node.Transform.thrApp.matModelView)
+		if boxOk {
+			box.UpdateMinMaxFrom(&jointBox)
+		} else {
+			box = jointBox
+		}
+	}
+	box.SetCenterExtent()
+	//	only propagate the bounds if the pose moved them since the last frame (or since ApplyNodeTransforms() reset them)
+	if box != node.thrApp.bounding.self.AaBox {
+		node.thrApp.bounding.self.AaBox = box
+		node.thrApp.bounding.self.Sphere = box.BoundingSphere(&node.Transform.Pos)
+		me.applyBounds(nodeID, nil)
+		me.applyAncestorBounds(nodeID)
+		me.spatialUpdate(nodeID)
+	}
+}
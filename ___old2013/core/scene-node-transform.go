@@ -1,6 +1,8 @@
 package core
 
 import (
+	"math"
+
 	"github.com/metaleap/go-util-num"
 	u3d "github.com/metaleap/go-util-3d"
 )
@@ -29,7 +31,7 @@ type SceneNodeTransform struct {
 	// Other unum.Mat4
 
 	thrApp struct {
-		matModelView unum.Mat4
+		matLocal, matModelView unum.Mat4
 	}
 	thrPrep struct {
 		matModelView unum.Mat4
@@ -38,7 +40,7 @@ type SceneNodeTransform struct {
 
 func (me *SceneNodeTransform) init() {
 	me.Scale.X, me.Scale.Y, me.Scale.Z = 1, 1, 1
-	me.thrApp.matModelView.Identity()
+	unum.Mat4Identities(&me.thrApp.matLocal, &me.thrApp.matModelView)
 }
 
 func (me *SceneNodeTransform) AddRot(rot *unum.Vec3) {
@@ -73,31 +75,81 @@ func (me *SceneNodeTransform) StepDelta(deltaPerSecond float64) float64 {
 //	Updates the internal 4x4 transformation matrix for all transformations of the specified
 //	node and child-nodes. It is only this matrix that is used by the rendering runtime.
 func (me *Scene) ApplyNodeTransforms(nodeID int) {
+	me.applyNodeTransforms(nodeID, true)
+}
+
+//	Implements ApplyNodeTransforms(), only calling On.NodeTransformed (for nodes whose
+//	local or world matrix changed) if notify is set.
+func (me *Scene) applyNodeTransforms(nodeID int, notify bool) {
 	if me.allNodes.IsOk(nodeID) {
 		//	this node
-		var matParent, matTrans, matScale, matRotX, matRotY, matRotZ unum.Mat4
-		matScale.Scaling(&me.allNodes[nodeID].Transform.Scale)
-		matTrans.Translation(&me.allNodes[nodeID].Transform.Pos)
-		matRotX.RotationX(me.allNodes[nodeID].Transform.Rot.X)
-		matRotY.RotationY(me.allNodes[nodeID].Transform.Rot.Y)
-		matRotZ.RotationZ(me.allNodes[nodeID].Transform.Rot.Z)
+		var matParent, matLocal, matWorld unum.Mat4
+		tr := &me.allNodes[nodeID].Transform
+		tr.localMatrix(&matLocal)
 		if me.allNodes[nodeID].parentID < 0 {
 			matParent.Identity()
 		} else {
 			matParent.CopyFrom(&me.allNodes[me.allNodes[nodeID].parentID].Transform.thrApp.matModelView)
 		}
-		me.allNodes[nodeID].Transform.thrApp.matModelView.SetFromMultN(&matParent, &matTrans /*me.Other,*/, &matScale, &matRotX, &matRotY, &matRotZ)
+		matWorld.SetFromMult4(&matParent, &matLocal)
+		changed := matLocal != tr.thrApp.matLocal || matWorld != tr.thrApp.matModelView
+		tr.thrApp.matLocal, tr.thrApp.matModelView = matLocal, matWorld
 		//	child-nodes
 		for i := 0; i < len(me.allNodes[nodeID].childNodeIDs); i++ {
-			me.ApplyNodeTransforms(me.allNodes[nodeID].childNodeIDs[i])
+			me.applyNodeTransforms(me.allNodes[nodeID].childNodeIDs[i], notify)
 		}
 		me.allNodes[nodeID].thrApp.bounding.full.Clear()
 		me.allNodes[nodeID].thrApp.bounding.self.Clear()
-		if Core.Libs.Meshes.IsOk(me.allNodes[nodeID].Render.meshID) {
-			me.applyBounds(nodeID, &Core.Libs.Meshes[me.allNodes[nodeID].Render.meshID].raw.bounding)
-		} else {
-			//	this node has no geometry of its own but its child-nodes might
-			me.applyBounds(nodeID, nil)
+		//	if this node has no geometry of its own, its child-nodes might
+		me.applySelfBounds(nodeID)
+		me.spatialUpdate(nodeID)
+		if notify && changed {
+			me.On.NodeTransformed.callAll(me, nodeID)
+		}
+	}
+}
+
+//	Recomputes the full bounds of all ancestors of nodeID, but not those of nodeID itself.
+func (me *Scene) applyAncestorBounds(nodeID int) {
+	if me.allNodes.IsOk(nodeID) {
+		for id := me.allNodes[nodeID].parentID; me.allNodes.IsOk(id); id = me.allNodes[id].parentID {
+			me.applyBounds(id, nil)
+		}
+	}
+}
+
+//	Applies the bounds of the boundsMesh() of node n (if any) as its own, and remembers that mesh for applyBoundsMeshChanges().
+func (me *Scene) applySelfBounds(n int) {
+	var src *u3d.Bounds
+	node := &me.allNodes[n]
+	if mesh := node.boundsMesh(); mesh != nil {
+		src, node.thrApp.boundsMesh = &mesh.raw.bounding, Core.Libs.Meshes.Handle(mesh.ID)
+	} else {
+		node.thrApp.boundsMesh = MeshHandle{}
+	}
+	me.applyBounds(n, src)
+}
+
+//	Re-applies the bounds (and spatial index entries) of all nodes whose boundsMesh() changed since their bounds were
+//	last applied, such as by a change to their LodGroup or that of their Model. Skinned nodes are left to skinNode().
+//	Called while neither app nor prep thread is running.
+func (me *Scene) applyBoundsMeshChanges() {
+	var (
+		node *SceneNode
+		mesh *Mesh
+		cur  MeshHandle
+	)
+	for n := 0; n < len(me.allNodes); n++ {
+		if node = &me.allNodes[n]; me.allNodes.Ok(n) && len(node.thrApp.skin.mats) == 0 {
+			if cur, mesh = (MeshHandle{}), node.boundsMesh(); mesh != nil {
+				cur = Core.Libs.Meshes.Handle(mesh.ID)
+			}
+			if cur != node.thrApp.boundsMesh {
+				node.thrApp.bounding.self.Clear()
+				me.applySelfBounds(n)
+				me.applyAncestorBounds(n)
+				me.spatialUpdate(n)
+			}
 		}
 	}
 }
@@ -114,3 +166,92 @@ func (me *Scene) applyBounds(n int, src *u3d.Bounds) {
 	}
 	me.allNodes[n].thrApp.bounding.full.Sphere = me.allNodes[n].thrApp.bounding.full.AaBox.BoundingSphere(&me.allNodes[n].Transform.Pos)
 }
+
+//	Computes the world transformation of the specified node freshly from the Transforms of it and all its ancestors,
+//	rather than relying on the last ApplyNodeTransforms() call to be up-to-date.
+func (me *Scene) nodeWorldMatrix(nodeID int, mat *unum.Mat4) {
+	var matLocal, matTmp unum.Mat4
+	mat.Identity()
+	for id := nodeID; me.allNodes.IsOk(id); id = me.allNodes[id].parentID {
+		me.allNodes[id].Transform.localMatrix(&matLocal)
+		matTmp.SetFromMult4(&matLocal, mat)
+		*mat = matTmp
+	}
+}
+
+func (me *SceneNodeTransform) localMatrix(mat *unum.Mat4) {
+	var matTrans, matScale, matRotX, matRotY, matRotZ unum.Mat4
+	matScale.Scaling(&me.Scale)
+	matTrans.Translation(&me.Pos)
+	matRotX.RotationX(me.Rot.X)
+	matRotY.RotationY(me.Rot.Y)
+	matRotZ.RotationZ(me.Rot.Z)
+	mat.SetFromMultN(&matTrans /*me.Other,*/, &matScale, &matRotX, &matRotY, &matRotZ)
+}
+
+//	Sets Pos, Rot and Scale such that localMatrix() would reproduce mat, as far as possible.
+//	mat must be an affine transformation, any shearing or projection in it is lost: in that case
+//	(such as for a non-uniform scaling of a rotation), exact is false.
+func (me *SceneNodeTransform) setFromMatrix(mat *unum.Mat4) (exact bool) {
+	//	localMatrix() composes T * S * Rx * Ry * Rz: so each row of the upper-left 3x3 is a row of the rotation, scaled.
+	var rot [3][3]float64
+	me.Pos.Set(mat[12], mat[13], mat[14])
+	for r := 0; r < 3; r++ {
+		rot[r][0], rot[r][1], rot[r][2] = mat[r], mat[4+r], mat[8+r]
+	}
+	me.Scale.Set(
+		math.Sqrt(rot[0][0]*rot[0][0]+rot[0][1]*rot[0][1]+rot[0][2]*rot[0][2]),
+		math.Sqrt(rot[1][0]*rot[1][0]+rot[1][1]*rot[1][1]+rot[1][2]*rot[1][2]),
+		math.Sqrt(rot[2][0]*rot[2][0]+rot[2][1]*rot[2][1]+rot[2][2]*rot[2][2]))
+	if mat3Det(&rot) < 0 {
+		me.Scale.X = -me.Scale.X
+	}
+	for c, s := range [3]float64{me.Scale.X, me.Scale.Y, me.Scale.Z} {
+		if s != 0 {
+			rot[c][0], rot[c][1], rot[c][2] = rot[c][0]/s, rot[c][1]/s, rot[c][2]/s
+		}
+	}
+	//	without shearing, the rows of rot are orthonormal
+	exact = true
+	for r := 0; r < 3 && exact; r++ {
+		a, b := &rot[r], &rot[(r+1)%3]
+		exact = math.Abs(a[0]*b[0]+a[1]*b[1]+a[2]*b[2]) < 1e-5
+	}
+	//	rot = Rx * Ry * Rz, so rot[0][2] = sin(Rot.Y)
+	me.Rot.Y = math.Asin(math.Max(-1, math.Min(1, rot[0][2])))
+	if math.Abs(rot[0][2]) < 0.9999999 {
+		me.Rot.X, me.Rot.Z = math.Atan2(-rot[1][2], rot[2][2]), math.Atan2(-rot[0][1], rot[0][0])
+	} else {
+		//	gimbal lock: only the sum (or difference) of X and Z is defined
+		me.Rot.X, me.Rot.Z = math.Atan2(rot[2][1], rot[1][1]), 0
+	}
+	return
+}
+
+func mat3Det(m *[3][3]float64) float64 {
+	return m[0][0]*(m[1][1]*m[2][2]-m[1][2]*m[2][1]) - m[0][1]*(m[1][0]*m[2][2]-m[1][2]*m[2][0]) + m[0][2]*(m[1][0]*m[2][1]-m[1][1]*m[2][0])
+}
+
+//	Sets dst to the inverse of the affine (column-major) transformation src.
+//	If src isn't invertible, dst is set to the identity matrix.
+func mat4InvertAffine(dst, src *unum.Mat4) {
+	var inv [3][3]float64
+	a, b, c := src[0], src[4], src[8]
+	d, e, f := src[1], src[5], src[9]
+	g, h, i := src[2], src[6], src[10]
+	det := a*(e*i-f*h) - b*(d*i-f*g) + c*(d*h-e*g)
+	if det == 0 {
+		dst.Identity()
+		return
+	}
+	inv[0][0], inv[0][1], inv[0][2] = (e*i-f*h)/det, (c*h-b*i)/det, (b*f-c*e)/det
+	inv[1][0], inv[1][1], inv[1][2] = (f*g-d*i)/det, (a*i-c*g)/det, (c*d-a*f)/det
+	inv[2][0], inv[2][1], inv[2][2] = (d*h-e*g)/det, (b*g-a*h)/det, (a*e-b*d)/det
+	tx, ty, tz := src[12], src[13], src[14]
+	for r := 0; r < 3; r++ {
+		dst[r], dst[4+r], dst[8+r] = inv[r][0], inv[r][1], inv[r][2]
+		dst[12+r] = -(inv[r][0]*tx + inv[r][1]*ty + inv[r][2]*tz)
+		dst[3+4*r] = 0
+	}
+	dst[15] = 1
+}
@@ -1,29 +1,77 @@
 package core
 
+import (
+	"github.com/metaleap/go-util-num"
+	ugl "github.com/metaleap/go-opengl/util"
+)
+
 type SceneNode struct {
 	ID        int
 	Transform SceneNodeTransform
 
+	//	Optional, need not be unique. Used by AnimTrack.NodeName and Scene.NodeByName().
+	Name string
+
 	Render struct {
 		Cull struct {
 			Frustum bool
+
+			//	If true (the default), this node may be occlusion-culled by cameras with Cull.Occlusion enabled.
+			Occlusion bool
 		}
 		Enabled bool
-		MatID   int
-		ModelID int
 
-		meshID  int
+		//	If it has any Levels, cameras render the mesh of its level appropriate for their distance to
+		//	this node, rather than the node's own mesh. Otherwise, the LodGroup of the node's Model is used.
+		Lod LodGroup
+
+		//	The FxMaterial to render this node with. If nil (the default) or removed, the material of the Model
+		//	(or else of the mesh's DefaultModelID) is used. Also see SceneNode.SetMatID().
+		Mat FxMaterialHandle
+
+		//	The Model to render this node's mesh with, if any. Also see SceneNode.SetModelID().
+		Model ModelHandle
+
+		//	If true, this node's geometry hides nodes behind it from cameras with Cull.Occlusion enabled.
+		//	Best used for few, large and simple meshes such as walls, floors or terrain. Defaults to false.
+		Occluder bool
+
+		//	The weights of the morph targets of the node's mesh (see Mesh.AddMorphTarget()), by their index.
+		//	Also see SceneNode.SetMorphWeight().
+		MorphWeights []float64
+
+		//	If a node of this Scene and the node's mesh was loaded via Mesh.LoadSkinned(), this node is skinned:
+		//	the joints of the MeshSkin are looked up by name in the sub-tree of Skeleton. Defaults to nil.
+		//	Also see Scene.SetNodeSkeletonID().
+		Skeleton SceneNodeHandle
+
+		mesh    MeshHandle
 		skyMode bool
 	}
 
+	libGen       uint64
 	parentID     int
 	childNodeIDs []int
 
 	thrApp struct {
-		bounding nodeBounds
+		animDirty    bool
+		bounding     nodeBounds
+		boundsMesh   MeshHandle
+		skin         sceneNodeSkin
+		spatialDirty bool
 	}
 	thrPrep struct {
-		bounding nodeBounds
+		bounding     nodeBounds
+		lod          LodGroup
+		lodMeshIDs   []int
+		morphCount   int
+		morphWeights ugl.GlMat4
+		skinMats     []unum.Mat4
+	}
+	thrRend struct {
+		morphCount   int
+		morphWeights ugl.GlMat4
+		skinMats     []ugl.GlMat4
 	}
 }
 
@@ -31,26 +79,69 @@ func (me *SceneNode) dispose() {
 }
 
 func (me *SceneNode) init() {
-	me.Render.Enabled, me.Render.Cull.Frustum = true, true
-	me.Render.MatID, me.Render.meshID, me.Render.ModelID = -1, -1, -1
+	me.Render.Enabled, me.Render.Cull.Frustum, me.Render.Cull.Occlusion = true, true, true
+	me.Name, me.Render.Mat, me.Render.mesh, me.Render.Model, me.Render.Skeleton = "", FxMaterialHandle{id: -1}, MeshHandle{id: -1}, ModelHandle{id: -1}, SceneNodeHandle{id: -1}
+	me.Render.Lod.init()
 	me.Transform.init()
 }
 
 func (me *SceneNode) mesh() *Mesh {
-	return Core.Libs.Meshes.get(me.Render.meshID)
+	return Core.Libs.Meshes.deref(me.Render.mesh)
+}
+
+//	Returns the ID of the mesh rendered by me, or -1 if it has none or it was removed.
+func (me *SceneNode) meshID() int {
+	if mesh := me.mesh(); mesh != nil {
+		return mesh.ID
+	}
+	return -1
+}
+
+//	Sets me.Render.Mat to the FxMaterial with the specified ID in Core.Libs.Materials, or to nil if matID is invalid.
+func (me *SceneNode) SetMatID(matID int) {
+	me.Render.Mat = Core.Libs.Materials.Handle(matID)
+}
+
+//	Sets me.Render.Model to the Model with the specified ID in Core.Libs.Models, or to nil if modelID is invalid.
+func (me *SceneNode) SetModelID(modelID int) {
+	me.Render.Model = Core.Libs.Models.Handle(modelID)
 }
 
 func (me *SceneNode) meshMat() (mesh *Mesh, mat *FxMaterial) {
 	if mesh = me.mesh(); mesh != nil {
-		if mat = Core.Libs.Materials.get(me.Render.MatID); mat == nil {
-			model := Core.Libs.Models.get(me.Render.ModelID)
-			if model == nil {
-				model = Core.Libs.Models.get(mesh.DefaultModelID)
-			}
-			if model != nil {
-				mat = Core.Libs.Materials.get(model.MatID)
+		mat = me.matFor(mesh)
+	}
+	return
+}
+
+//	Returns the FxMaterial to render me with when rendering mesh (its own or that of a LOD level): that of Render.Mat,
+//	or else of Render.Model, or else of the DefaultModelID of its own mesh or, if it has none, of mesh.
+func (me *SceneNode) matFor(mesh *Mesh) (mat *FxMaterial) {
+	if mat = Core.Libs.Materials.deref(me.Render.Mat); mat == nil {
+		model := Core.Libs.Models.deref(me.Render.Model)
+		if model == nil {
+			if own := me.mesh(); own != nil {
+				mesh = own
 			}
+			model = Core.Libs.Models.get(mesh.DefaultModelID)
 		}
+		if model != nil {
+			mat = Core.Libs.Materials.get(model.MatID)
+		}
+	}
+	return
+}
+
+//	Like meshMat(), but for the prep thread, and also for nodes that have no mesh of their own but LOD levels:
+//	for those, mesh is that of their first level that has a valid one.
+func (me *SceneNode) prepMeshMat() (mesh *Mesh, mat *FxMaterial) {
+	if mesh = me.mesh(); mesh == nil {
+		for i := 0; i < len(me.thrPrep.lodMeshIDs) && mesh == nil; i++ {
+			mesh = Core.Libs.Meshes.get(me.thrPrep.lodMeshIDs[i])
+		}
+	}
+	if mesh != nil {
+		mat = me.matFor(mesh)
 	}
 	return
 }
@@ -4,6 +4,24 @@ type sceneChildNodes []int
 
 //#begin-gt -gen-lib.gt T:SceneNode L:Core.Scenes[id].allNodes
 
+//	A generational handle to a SceneNode in Core.Scenes[id].allNodes.
+//	Unlike a plain ID, it is never silently resolved to another SceneNode that
+//	later re-uses the same ID after the original was removed or moved by Compact().
+type SceneNodeHandle struct {
+	id  int
+	gen uint64
+}
+
+//	Returns the ID that me referred to when last resolved. It may since have become invalid.
+func (me SceneNodeHandle) ID() int {
+	return me.id
+}
+
+//	Returns true if me is the zero SceneNodeHandle (or was detected to be stale), which never resolves to a SceneNode.
+func (me SceneNodeHandle) IsNil() bool {
+	return me.gen == 0
+}
+
 //	Only used for Core.Scenes[id].allNodes
 type SceneNodeLib []SceneNode
 
@@ -24,7 +42,7 @@ func (me *SceneNodeLib) AddNew() (id int) {
 		*me = append(*me, SceneNode{})
 	}
 	ref := &(*me)[id]
-	ref.ID = id
+	ref.ID, ref.libGen = id, libGenNext()
 	ref.init()
 	return
 }
@@ -40,6 +58,8 @@ func (me *SceneNodeLib) Compact() {
 		if (*me)[i].ID == -1 {
 			compact, before, after = true, (*me)[:i], (*me)[i+1:]
 			*me = append(before, after...)
+			//	re-check i, which now holds the next entry
+			i--
 		}
 	}
 	if compact {
@@ -48,6 +68,7 @@ func (me *SceneNodeLib) Compact() {
 			if ref = &(*me)[i]; ref.ID != i {
 				oldID, ref.ID = ref.ID, i
 				changed[oldID] = i
+				libGenMove(ref.libGen, i)
 			}
 		}
 		if len(changed) > 0 {
@@ -72,6 +93,39 @@ func (me SceneNodeLib) get(id int) (ref *SceneNode) {
 	return
 }
 
+//	Returns the SceneNode that h refers to, or nil if it has since been removed.
+//	If it was moved by Compact(), h is updated to its new ID. If it was removed, h becomes nil.
+func (me SceneNodeLib) Deref(h *SceneNodeHandle) (ref *SceneNode) {
+	if ref = me.deref(*h); ref != nil {
+		h.id = ref.ID
+	} else if h.gen != 0 {
+		*h = SceneNodeHandle{id: -1}
+	}
+	return
+}
+
+//	Like Deref(), but leaves h as is: safe to call on handles owned by another thread.
+func (me SceneNodeLib) deref(h SceneNodeHandle) (ref *SceneNode) {
+	if h.gen != 0 {
+		id := h.id
+		if id < 0 || id >= len(me) || me[id].libGen != h.gen {
+			id = libGenMovedTo(h.gen)
+		}
+		if id > -1 && id < len(me) && me[id].ID == id && me[id].libGen == h.gen {
+			ref = &me[id]
+		}
+	}
+	return
+}
+
+//	Returns a SceneNodeHandle to the SceneNode with the specified ID, or a nil SceneNodeHandle if id is invalid.
+func (me SceneNodeLib) Handle(id int) (h SceneNodeHandle) {
+	if h.id = -1; me.IsOk(id) {
+		h.id, h.gen = id, me[id].libGen
+	}
+	return
+}
+
 func (me SceneNodeLib) IsOk(id int) (ok bool) {
 	if id > -1 && id < len(me) {
 		ok = me[id].ID == id
@@ -91,7 +145,8 @@ func (me SceneNodeLib) Remove(fromID, num int) {
 		changed := make(map[int]int, num)
 		for id := fromID; id < fromID+num; id++ {
 			me[id].dispose()
-			changed[id], me[id].ID = -1, -1
+			libGenForget(me[id].libGen)
+			changed[id], me[id].ID, me[id].libGen = -1, -1, 0
 		}
 		me.onSceneNodeIDsChanged(changed)
 	}
@@ -0,0 +1,209 @@
+package core
+
+import (
+	"math"
+	"sort"
+
+	"github.com/metaleap/go-util-num"
+	u3d "github.com/metaleap/go-util-3d"
+)
+
+//	Describes the closest intersection of a ray with the geometry of a Scene, as found by Scene.Raycast().
+type SceneRayHit struct {
+	//	The ID of the node whose geometry was hit.
+	NodeID int
+
+	//	The index of the hit face in its Mesh.
+	FaceIndex int
+
+	//	The ID and tags of the hit face.
+	Face u3d.MeshFaceBase
+
+	//	The world-space hit position.
+	Point unum.Vec3
+
+	//	The weights of the hit face's 3 corners that sum up to the hit position.
+	Barycentric unum.Vec3
+
+	//	The distance from the ray's origin to Point.
+	Dist float64
+}
+
+type sceneRayCandidate struct {
+	nodeID int
+	dist   float64
+}
+
+type sceneRayCandidates []sceneRayCandidate
+
+func (me sceneRayCandidates) Len() int {
+	return len(me)
+}
+
+func (me sceneRayCandidates) Less(i, j int) bool {
+	return me[i].dist < me[j].dist
+}
+
+func (me sceneRayCandidates) Swap(i, j int) {
+	me[i], me[j] = me[j], me[i]
+}
+
+//	Returns the distance along the ray at which it enters me, or hit = false if it misses me entirely.
+func (me *sceneOctreeBox) rayDist(origin, dir *unum.Vec3) (dist float64, hit bool) {
+	o, d := [3]float64{origin.X, origin.Y, origin.Z}, [3]float64{dir.X, dir.Y, dir.Z}
+	min, max := [3]float64{me.min.X, me.min.Y, me.min.Z}, [3]float64{me.max.X, me.max.Y, me.max.Z}
+	tNear, tFar := 0.0, math.Inf(1)
+	for i := 0; i < 3; i++ {
+		if d[i] == 0 {
+			if o[i] < min[i] || o[i] > max[i] {
+				return
+			}
+		} else {
+			t1, t2 := (min[i]-o[i])/d[i], (max[i]-o[i])/d[i]
+			if t1 > t2 {
+				t1, t2 = t2, t1
+			}
+			if tNear, tFar = math.Max(tNear, t1), math.Min(tFar, t2); tNear > tFar {
+				return
+			}
+		}
+	}
+	dist, hit = tNear, true
+	return
+}
+
+//	Casts a ray from origin into direction dir against the geometry of all nodes in me, and returns the closest hit if any.
+//	Uses the node transformations and bounds as of the last ApplyNodeTransforms() call affecting them.
+//	Nodes found in the spatial index are only tested if the ray hits their bounds, all others always are.
+//	Nodes that are not Render.Enabled, or have an ancestor that isn't, are ignored.
+//	This works even for meshes that were Mesh.Unload()ed.
+func (me *Scene) Raycast(origin, dir *unum.Vec3) (hit SceneRayHit, ok bool) {
+	var candidates sceneRayCandidates
+	if dir.X == 0 && dir.Y == 0 && dir.Z == 0 {
+		return
+	}
+	rayDir := *dir
+	rayDir.Normalize()
+	me.thrApp.spatial.walk(func(box *sceneOctreeBox) (inside, intersect bool) {
+		_, intersect = box.rayDist(origin, &rayDir)
+		return
+	}, func(nodeID int) {
+		if dist, x := me.thrApp.spatial.itemBox(nodeID).rayDist(origin, &rayDir); x {
+			candidates = append(candidates, sceneRayCandidate{nodeID: nodeID, dist: dist})
+		}
+	})
+	//	nodes left out of the index (such as for non-finite bounds) are tested regardless
+	for nodeID := 1; nodeID < len(me.allNodes); nodeID++ {
+		if me.spatialIndexed(nodeID) && !me.thrApp.spatial.indexed(nodeID) {
+			candidates = append(candidates, sceneRayCandidate{nodeID: nodeID})
+		}
+	}
+	sort.Sort(candidates)
+	hit.Dist = math.Inf(1)
+	for _, c := range candidates {
+		if c.dist > hit.Dist {
+			break
+		}
+		if me.allNodes.enabledInHierarchy(c.nodeID) && me.raycastNode(c.nodeID, origin, &rayDir, &hit) {
+			ok = true
+		}
+	}
+	if ok {
+		hit.Point.SetFromAddScaled(origin, &rayDir, hit.Dist)
+	} else {
+		hit.Dist = 0
+	}
+	return
+}
+
+//	Tests the ray (with normalized dir) against all faces of the mesh of nodeID and
+//	updates hit with the closest intersection that is closer than hit.Dist, if any.
+func (me *Scene) raycastNode(nodeID int, origin, dir *unum.Vec3, hit *SceneRayHit) (ok bool) {
+	mesh := me.allNodes[nodeID].mesh()
+	if mesh == nil {
+		return
+	}
+	//	transform the ray into the node's local space: as dir isn't re-normalized, distances along it remain world-space distances
+	var matInv unum.Mat4
+	var lo, ld unum.Vec3
+	mat4InvertAffine(&matInv, &me.allNodes[nodeID].Transform.thrApp.matModelView)
+	mat4MultPoint(&lo, &matInv, origin)
+	mat4MultDir(&ld, &matInv, dir)
+	var t, u, v float64
+	var x bool
+	for fi := 0; fi < len(mesh.raw.faces); fi++ {
+		if t, u, v, x = rayTriangle(&lo, &ld, &mesh.raw.faces[fi].pos); x && t < hit.Dist {
+			ok, hit.Dist, hit.NodeID, hit.FaceIndex, hit.Face = true, t, nodeID, fi, mesh.raw.faces[fi].base
+			hit.Barycentric.Set(1-u-v, u, v)
+		}
+	}
+	return
+}
+
+//	Moeller-Trumbore ray-triangle intersection, for both front and back faces.
+func rayTriangle(origin, dir *unum.Vec3, tri *[3]unum.Vec3) (t, u, v float64, hit bool) {
+	const epsilon = 1e-12
+	e1 := unum.Vec3{tri[1].X - tri[0].X, tri[1].Y - tri[0].Y, tri[1].Z - tri[0].Z}
+	e2 := unum.Vec3{tri[2].X - tri[0].X, tri[2].Y - tri[0].Y, tri[2].Z - tri[0].Z}
+	var p, q unum.Vec3
+	p.SetFromCrossOf(dir, &e2)
+	det := vec3Dot(&e1, &p)
+	if math.Abs(det) < epsilon {
+		return
+	}
+	s := unum.Vec3{origin.X - tri[0].X, origin.Y - tri[0].Y, origin.Z - tri[0].Z}
+	if u = vec3Dot(&s, &p) / det; u < 0 || u > 1 {
+		return
+	}
+	q.SetFromCrossOf(&s, &e1)
+	if v = vec3Dot(dir, &q) / det; v < 0 || u+v > 1 {
+		return
+	}
+	t = vec3Dot(&e2, &q) / det
+	hit = t >= 0
+	return
+}
+
+func vec3Dot(a, b *unum.Vec3) float64 {
+	return a.X*b.X + a.Y*b.Y + a.Z*b.Z
+}
+
+//	Sets dst to the point p transformed by the (column-major) matrix m.
+func mat4MultPoint(dst *unum.Vec3, m *unum.Mat4, p *unum.Vec3) {
+	dst.Set(m[0]*p.X+m[4]*p.Y+m[8]*p.Z+m[12], m[1]*p.X+m[5]*p.Y+m[9]*p.Z+m[13], m[2]*p.X+m[6]*p.Y+m[10]*p.Z+m[14])
+}
+
+//	Sets dst to the direction d transformed by the (column-major) matrix m, ignoring its translation.
+func mat4MultDir(dst *unum.Vec3, m *unum.Mat4, d *unum.Vec3) {
+	dst.Set(m[0]*d.X+m[4]*d.Y+m[8]*d.Z, m[1]*d.X+m[5]*d.Y+m[9]*d.Z, m[2]*d.X+m[6]*d.Y+m[10]*d.Z)
+}
+
+//	Returns a world-space ray from this camera through the specified point on its viewport, for use with Scene.Raycast().
+//	x and y are relative to the viewport of the camera's RenderView: from 0 (left / top) to 1 (right / bottom).
+//	The returned dir is normalized.
+func (me *Camera) ScreenPointToRay(x, y float64) (origin, dir unum.Vec3) {
+	ndcX, ndcY := 2*x-1, 1-2*y
+	if me.Perspective.Enabled {
+		var right unum.Vec3
+		ctl := &me.Controller
+		right.SetFromCrossOf(&ctl.UpAxis, &ctl.dir)
+		right.Normalize()
+		tanHalf := math.Tan(me.Perspective.FovY.Deg * math.Pi / 360)
+		sx, sy := ndcX*tanHalf*me.viewportAspectRatio, ndcY*tanHalf
+		origin = ctl.Pos
+		dir.Set(-ctl.dir.X+right.X*sx+ctl.UpAxis.X*sy, -ctl.dir.Y+right.Y*sx+ctl.UpAxis.Y*sy, -ctl.dir.Z+right.Z*sx+ctl.UpAxis.Z*sy)
+		dir.Normalize()
+	} else {
+		//	without perspective, the camera's projection is affine: so the ray runs between the
+		//	unprojected points on the near and far planes of clip space
+		var matCamProj, matInv unum.Mat4
+		var far unum.Vec3
+		matCamProj.SetFromMult4(&me.thrApp.matProj, &me.Controller.thrApp.mat)
+		mat4InvertAffine(&matInv, &matCamProj)
+		mat4MultPoint(&origin, &matInv, &unum.Vec3{ndcX, ndcY, -1})
+		mat4MultPoint(&far, &matInv, &unum.Vec3{ndcX, ndcY, 1})
+		dir.Set(far.X-origin.X, far.Y-origin.Y, far.Z-origin.Z)
+		dir.Normalize()
+	}
+	return
+}
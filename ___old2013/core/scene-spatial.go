@@ -0,0 +1,366 @@
+package core
+
+import (
+	"math"
+
+	"github.com/metaleap/go-util-num"
+	u3d "github.com/metaleap/go-util-3d"
+)
+
+//	An axis-aligned box with its bounding sphere, in world space.
+type sceneOctreeBox struct {
+	min, max, center unum.Vec3
+	ext, radius      float64
+}
+
+func (me *sceneOctreeBox) setFromMinMax(min, max *unum.Vec3) {
+	me.min, me.max = *min, *max
+	me.center.Set((min.X+max.X)*0.5, (min.Y+max.Y)*0.5, (min.Z+max.Z)*0.5)
+	hx, hy, hz := (max.X-min.X)*0.5, (max.Y-min.Y)*0.5, (max.Z-min.Z)*0.5
+	me.ext, me.radius = math.Max(hx, math.Max(hy, hz)), math.Sqrt(hx*hx+hy*hy+hz*hz)
+}
+
+//	Returns false if any coordinate of me is NaN or infinite, such as for nodes with a zero scale.
+func (me *sceneOctreeBox) finite() bool {
+	for _, f := range [...]float64{me.min.X, me.min.Y, me.min.Z, me.max.X, me.max.Y, me.max.Z} {
+		if math.IsNaN(f) || math.IsInf(f, 0) {
+			return false
+		}
+	}
+	return true
+}
+
+func (me *sceneOctreeBox) setFromCenterHalf(center *unum.Vec3, half float64) {
+	me.center, me.ext, me.radius = *center, half, half*math.Sqrt(3)
+	me.min.Set(center.X-half, center.Y-half, center.Z-half)
+	me.max.Set(center.X+half, center.Y+half, center.Z+half)
+}
+
+//	Returns the smallest and the greatest distance from pos to any point in me.
+func (me *sceneOctreeBox) dists(pos *unum.Vec3) (near, far float64) {
+	var dn, df float64
+	for i, p := range [3]float64{pos.X, pos.Y, pos.Z} {
+		min, max := [3]float64{me.min.X, me.min.Y, me.min.Z}[i], [3]float64{me.max.X, me.max.Y, me.max.Z}[i]
+		if dn = 0; p < min {
+			dn = min - p
+		} else if p > max {
+			dn = p - max
+		}
+		df = math.Max(p-min, max-p)
+		near, far = near+dn*dn, far+df*df
+	}
+	near, far = math.Sqrt(near), math.Sqrt(far)
+	return
+}
+
+type sceneOctreeCell struct {
+	//	The cell's non-loose bounds are center +/- half. Its loose bounds, which
+	//	all its items' boxes fit into, extend twice as far: center +/- 2*half.
+	center   unum.Vec3
+	half     float64
+	parent   int
+	children [8]int
+	items    []sceneOctreeItem
+}
+
+type sceneOctreeItem struct {
+	sceneOctreeBox
+	nodeID int
+}
+
+//	A loose octree over the world-space bounding boxes of the nodes with geometry in a Scene.
+//	Each item is stored in the smallest cell that contains its center and is at least as large as the item,
+//	so updating an item only touches its old and new cell. The root grows on demand to fit far-away items.
+type sceneOctree struct {
+	cells     []sceneOctreeCell
+	freeCells []int
+	nodeCells []int // for each SceneNode ID, the index of the cell containing it, or -1
+	root      int
+}
+
+func (me *sceneOctree) init() {
+	me.cells, me.freeCells, me.nodeCells, me.root = nil, nil, nil, -1
+}
+
+//	Returns true if the specified box fits into the loose bounds of the specified cell.
+func (me *sceneOctree) cellFits(ci int, box *sceneOctreeBox) bool {
+	cell := &me.cells[ci]
+	return box.ext <= cell.half && math.Abs(box.center.X-cell.center.X) <= cell.half &&
+		math.Abs(box.center.Y-cell.center.Y) <= cell.half && math.Abs(box.center.Z-cell.center.Z) <= cell.half
+}
+
+//	Returns the index into the children of the cell at center of the octant containing pos.
+func (me *sceneOctree) octant(center, pos *unum.Vec3) (oct int) {
+	if pos.X >= center.X {
+		oct |= 1
+	}
+	if pos.Y >= center.Y {
+		oct |= 2
+	}
+	if pos.Z >= center.Z {
+		oct |= 4
+	}
+	return
+}
+
+func octantSign(oct, axisBit int) float64 {
+	if oct&axisBit != 0 {
+		return 1
+	}
+	return -1
+}
+
+//	The maximum number of times the root cell of a sceneOctree doubles in size to fit a single item.
+//	Items further out than this (2^64 times the root's size) are not indexed.
+const sceneOctreeMaxGrowth = 64
+
+//	Grows the root of me until box fits into it, and returns false if it can't within sceneOctreeMaxGrowth steps.
+func (me *sceneOctree) growToFit(box *sceneOctreeBox) bool {
+	if me.root < 0 {
+		half := Options.Scenes.SpatialIndex.MinCellSize * 0.5
+		for step := 0; half < box.ext; step++ {
+			if step == sceneOctreeMaxGrowth {
+				return false
+			}
+			half *= 2
+		}
+		me.root = me.newCell(-1, &box.center, half)
+	}
+	var center unum.Vec3
+	for step := 0; !me.cellFits(me.root, box); step++ {
+		if step == sceneOctreeMaxGrowth {
+			return false
+		}
+		old := me.root
+		half := me.cells[old].half
+		center = me.cells[old].center
+		if box.center.X < center.X {
+			center.X -= half
+		} else {
+			center.X += half
+		}
+		if box.center.Y < center.Y {
+			center.Y -= half
+		} else {
+			center.Y += half
+		}
+		if box.center.Z < center.Z {
+			center.Z -= half
+		} else {
+			center.Z += half
+		}
+		me.root = me.newCell(-1, &center, half*2)
+		me.cells[me.root].children[me.octant(&center, &me.cells[old].center)] = old
+		me.cells[old].parent = me.root
+	}
+	return true
+}
+
+func (me *sceneOctree) insert(nodeID int, box *sceneOctreeBox) {
+	if !me.growToFit(box) {
+		return
+	}
+	var oct, child int
+	var center unum.Vec3
+	minHalf := Options.Scenes.SpatialIndex.MinCellSize * 0.5
+	ci := me.root
+	for half := me.cells[ci].half * 0.5; half >= minHalf && box.ext <= half; half *= 0.5 {
+		oct = me.octant(&me.cells[ci].center, &box.center)
+		if child = me.cells[ci].children[oct]; child < 0 {
+			center = me.cells[ci].center
+			center.Add3(octantSign(oct, 1)*half, octantSign(oct, 2)*half, octantSign(oct, 4)*half)
+			child = me.newCell(ci, &center, half)
+			me.cells[ci].children[oct] = child
+		}
+		ci = child
+	}
+	me.cells[ci].items = append(me.cells[ci].items, sceneOctreeItem{sceneOctreeBox: *box, nodeID: nodeID})
+	me.nodeCells[nodeID] = ci
+}
+
+func (me *sceneOctree) newCell(parent int, center *unum.Vec3, half float64) (ci int) {
+	if l := len(me.freeCells); l > 0 {
+		ci, me.freeCells = me.freeCells[l-1], me.freeCells[:l-1]
+	} else {
+		ci = len(me.cells)
+		me.cells = append(me.cells, sceneOctreeCell{})
+	}
+	cell := &me.cells[ci]
+	cell.center, cell.half, cell.parent, cell.items = *center, half, parent, cell.items[:0]
+	for i := 0; i < len(cell.children); i++ {
+		cell.children[i] = -1
+	}
+	return
+}
+
+func (me *sceneOctree) remove(nodeID int) {
+	if nodeID < len(me.nodeCells) && me.nodeCells[nodeID] > -1 {
+		ci := me.nodeCells[nodeID]
+		me.nodeCells[nodeID] = -1
+		items := me.cells[ci].items
+		for i := 0; i < len(items); i++ {
+			if items[i].nodeID == nodeID {
+				items[i] = items[len(items)-1]
+				me.cells[ci].items = items[:len(items)-1]
+				break
+			}
+		}
+		//	release all now-empty cells up the branch, but never the root
+		for parent := me.cells[ci].parent; parent > -1 && me.cellEmpty(ci); ci, parent = parent, me.cells[parent].parent {
+			for i := 0; i < len(me.cells[parent].children); i++ {
+				if me.cells[parent].children[i] == ci {
+					me.cells[parent].children[i] = -1
+				}
+			}
+			me.freeCells = append(me.freeCells, ci)
+		}
+		if me.cellEmpty(me.root) {
+			me.cells, me.freeCells, me.root = me.cells[:0], me.freeCells[:0], -1
+		}
+	}
+}
+
+//	Returns true if nodeID has an entry in me.
+func (me *sceneOctree) indexed(nodeID int) bool {
+	return nodeID < len(me.nodeCells) && me.nodeCells[nodeID] > -1
+}
+
+//	Returns the box of the item for nodeID, which must be in me.
+func (me *sceneOctree) itemBox(nodeID int) *sceneOctreeBox {
+	items := me.cells[me.nodeCells[nodeID]].items
+	for i := 0; i < len(items); i++ {
+		if items[i].nodeID == nodeID {
+			return &items[i].sceneOctreeBox
+		}
+	}
+	return nil
+}
+
+func (me *sceneOctree) cellEmpty(ci int) bool {
+	if len(me.cells[ci].items) > 0 {
+		return false
+	}
+	for _, child := range me.cells[ci].children {
+		if child > -1 {
+			return false
+		}
+	}
+	return true
+}
+
+//	Removes nodeID from me and, if ok, re-inserts it with the specified world-space bounding box.
+//	Boxes with NaN or infinite coordinates are not inserted.
+func (me *sceneOctree) update(nodeID int, ok bool, aabb *u3d.AaBb) {
+	for len(me.nodeCells) <= nodeID {
+		me.nodeCells = append(me.nodeCells, -1)
+	}
+	me.remove(nodeID)
+	if ok {
+		var box sceneOctreeBox
+		if box.setFromMinMax(&aabb.Min, &aabb.Max); box.finite() {
+			me.insert(nodeID, &box)
+		}
+	}
+}
+
+//	Calls on() for all items whose box passes test(), which reports whether a box is fully inside
+//	and/or intersects the queried volume. Cells fully inside have all their items passed without testing.
+func (me *sceneOctree) walk(test func(box *sceneOctreeBox) (inside, intersect bool), on func(nodeID int)) {
+	if me.root > -1 {
+		me.walkCell(me.root, false, test, on)
+	}
+}
+
+func (me *sceneOctree) walkCell(ci int, inside bool, test func(box *sceneOctreeBox) (inside, intersect bool), on func(nodeID int)) {
+	var in, x bool
+	if !inside {
+		var loose sceneOctreeBox
+		loose.setFromCenterHalf(&me.cells[ci].center, me.cells[ci].half*2)
+		if inside, x = test(&loose); !(inside || x) {
+			return
+		}
+	}
+	for i := 0; i < len(me.cells[ci].items); i++ {
+		if inside {
+			on(me.cells[ci].items[i].nodeID)
+		} else if in, x = test(&me.cells[ci].items[i].sceneOctreeBox); in || x {
+			on(me.cells[ci].items[i].nodeID)
+		}
+	}
+	for _, child := range me.cells[ci].children {
+		if child > -1 {
+			me.walkCell(child, inside, test, on)
+		}
+	}
+}
+
+func (me *sceneOctree) queryBox(min, max *unum.Vec3, nodeIDs []int) []int {
+	me.walk(func(box *sceneOctreeBox) (inside, intersect bool) {
+		intersect = box.max.X >= min.X && box.min.X <= max.X && box.max.Y >= min.Y && box.min.Y <= max.Y && box.max.Z >= min.Z && box.min.Z <= max.Z
+		inside = intersect && box.min.X >= min.X && box.max.X <= max.X && box.min.Y >= min.Y && box.max.Y <= max.Y && box.min.Z >= min.Z && box.max.Z <= max.Z
+		return
+	}, func(nodeID int) {
+		nodeIDs = append(nodeIDs, nodeID)
+	})
+	return nodeIDs
+}
+
+func (me *sceneOctree) queryRange(pos *unum.Vec3, minDist, maxDist float64, nodeIDs []int) []int {
+	me.walk(func(box *sceneOctreeBox) (inside, intersect bool) {
+		near, far := box.dists(pos)
+		intersect = near <= maxDist && far >= minDist
+		inside = near >= minDist && far <= maxDist
+		return
+	}, func(nodeID int) {
+		nodeIDs = append(nodeIDs, nodeID)
+	})
+	return nodeIDs
+}
+
+//	Appends to nodeIDs the IDs of all nodes with geometry whose world-space bounding box intersects
+//	the axis-aligned box from min to max, and returns the result. Node bounds are as of the last
+//	ApplyNodeTransforms() call affecting them. The order of the results is unspecified.
+func (me *Scene) QueryBox(min, max *unum.Vec3, nodeIDs []int) []int {
+	return me.thrApp.spatial.queryBox(min, max, nodeIDs)
+}
+
+//	Appends to nodeIDs the IDs of all nodes with geometry whose world-space bounding box has any point
+//	at a distance from pos between minDist and maxDist, and returns the result. Node bounds are as of
+//	the last ApplyNodeTransforms() call affecting them. The order of the results is unspecified.
+func (me *Scene) QueryRange(pos *unum.Vec3, minDist, maxDist float64, nodeIDs []int) []int {
+	return me.thrApp.spatial.queryRange(pos, minDist, maxDist, nodeIDs)
+}
+
+//	Appends to nodeIDs the IDs of all nodes with geometry whose world-space bounding box intersects the
+//	specified sphere, and returns the result. Node bounds are as of the last ApplyNodeTransforms() call
+//	affecting them. The order of the results is unspecified.
+func (me *Scene) QuerySphere(center *unum.Vec3, radius float64, nodeIDs []int) []int {
+	return me.thrApp.spatial.queryRange(center, 0, radius, nodeIDs)
+}
+
+//	Returns true if nodeID belongs in the spatial index: if it has bounds of its own, see SceneNode.boundsSrc().
+func (me *Scene) spatialIndexed(nodeID int) bool {
+	return nodeID > 0 && me.allNodes.IsOk(nodeID) && me.allNodes[nodeID].boundsSrc() != nil
+}
+
+//	Updates the spatial index entry of nodeID from its current bounds, and queues this update for the prep thread.
+//	nodeID may also be the slot of a just-removed node, whose index entries are then removed.
+func (me *Scene) spatialUpdate(nodeID int) {
+	if nodeID < 0 || nodeID >= len(me.allNodes) {
+		return
+	}
+	node := &me.allNodes[nodeID]
+	me.thrApp.spatial.update(nodeID, me.spatialIndexed(nodeID), &node.thrApp.bounding.self.AaBox)
+	if !node.thrApp.spatialDirty {
+		node.thrApp.spatialDirty = true
+		me.thrApp.spatialDirty = append(me.thrApp.spatialDirty, nodeID)
+	}
+}
+
+func (me *Scene) copySpatialAppToPrep() {
+	for _, nodeID := range me.thrApp.spatialDirty {
+		me.allNodes[nodeID].thrApp.spatialDirty = false
+		me.thrPrep.spatial.update(nodeID, me.spatialIndexed(nodeID), &me.allNodes[nodeID].thrApp.bounding.self.AaBox)
+	}
+	me.thrApp.spatialDirty = me.thrApp.spatialDirty[:0]
+}
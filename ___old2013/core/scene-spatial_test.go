@@ -0,0 +1,97 @@
+package core
+
+import (
+	"testing"
+
+	"github.com/metaleap/go-util-num"
+)
+
+//	Adds a new Mesh to Core.Libs.Meshes, loaded with a flat grid of the specified size around the origin.
+func sceneTestMesh(t *testing.T, name string, size float64) (meshID int) {
+	md, err := meshGenGrid(size, size, 2, 2)()
+	if err == nil {
+		meshID = Core.Libs.Meshes.AddNew()
+		Core.Libs.Meshes[meshID].Name = name
+		err = Core.Libs.Meshes[meshID].load(md)
+	}
+	if err != nil {
+		t.Fatalf("%v: %v", name, err)
+	}
+	return
+}
+
+func sceneTestNew() (scene *Scene) {
+	scene = &Scene{}
+	scene.init()
+	return
+}
+
+//	Hands the app-thread state of scene to its prep-thread state, as Loop.Run() does once per frame.
+func sceneTestSync(scene *Scene) {
+	scene.thrPrep.copyDone = false
+	scene.copyAppToPrep()
+}
+
+//	Returns true if nodeID is found by walking the prep-thread spatial index of scene, as Camera.onPrepSpatial() does.
+func sceneTestPrepVisits(scene *Scene, nodeID int) (found bool) {
+	scene.thrPrep.spatial.walk(func(box *sceneOctreeBox) (inside, intersect bool) {
+		return false, true
+	}, func(id int) {
+		found = found || id == nodeID
+	})
+	return
+}
+
+func TestSceneSpatialLodOnly(t *testing.T) {
+	small, big := sceneTestMesh(t, "lod small", 2), sceneTestMesh(t, "lod big", 20)
+	defer Core.Libs.Meshes.Remove(small, 1)
+	defer Core.Libs.Meshes.Remove(big, 1)
+	scene := sceneTestNew()
+	nodeID := scene.AddNewChildNode(0, -1)
+	node := scene.Node(nodeID)
+	if !node.Render.Cull.Frustum {
+		t.Fatalf("new nodes should be frustum-culled by default")
+	}
+	sceneTestSync(scene)
+	if scene.thrApp.spatial.indexed(nodeID) || sceneTestPrepVisits(scene, nodeID) {
+		t.Errorf("node without mesh or LodGroup is in the spatial index")
+	}
+
+	//	only a LodGroup: indexed as of the next frame, by the bounds of its first level
+	node.Render.Lod.Levels = []LodLevel{{Mesh: Core.Libs.Meshes.Handle(small), MaxDist: 10}, {Mesh: Core.Libs.Meshes.Handle(big)}}
+	sceneTestSync(scene)
+	if !scene.thrApp.spatial.indexed(nodeID) {
+		t.Fatalf("LOD-only node is not in the spatial index")
+	}
+	if !sceneTestPrepVisits(scene, nodeID) {
+		t.Errorf("LOD-only node is not in the prep thread's spatial index, so frustum culling never renders it")
+	}
+	for _, id := range scene.thrPrep.unculled {
+		if id == nodeID {
+			t.Errorf("LOD-only node is among the unculled nodes")
+		}
+	}
+	if box := scene.thrApp.spatial.itemBox(nodeID); box.max.X != 1 || box.min.X != -1 {
+		t.Errorf("LOD-only node is indexed with box %v..%v, want the bounds of its first level", box.min, box.max)
+	}
+	if ids := scene.QuerySphere(&unum.Vec3{}, 1, nil); len(ids) != 1 || ids[0] != nodeID {
+		t.Errorf("QuerySphere() found %v, want [%v]", ids, nodeID)
+	}
+
+	//	changing the first level updates the index entry
+	node.Render.Lod.Levels = node.Render.Lod.Levels[1:]
+	sceneTestSync(scene)
+	if box := scene.thrApp.spatial.itemBox(nodeID); box.max.X != 10 || box.min.X != -10 {
+		t.Errorf("node is indexed with box %v..%v after changing its LodGroup, want the bounds of its new first level", box.min, box.max)
+	}
+	if box := &scene.allNodes[0].thrApp.bounding.full.AaBox; box.Max.X != 10 {
+		t.Errorf("root node's full bounds reach X = %v, want 10", box.Max.X)
+	}
+
+	//	and removing the LodGroup removes it
+	node.Render.Lod.Levels = nil
+	sceneTestSync(scene)
+	if scene.thrApp.spatial.indexed(nodeID) || sceneTestPrepVisits(scene, nodeID) {
+		t.Errorf("node is still in the spatial index after its LodGroup was removed")
+	}
+}
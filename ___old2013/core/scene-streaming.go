@@ -0,0 +1,409 @@
+package core
+
+import (
+	"math"
+
+	"github.com/metaleap/go-util-num"
+	u3d "github.com/metaleap/go-util-3d"
+)
+
+//	Identifies a cell of a WorldStreamer. Cell (X, Z) covers all world positions
+//	from (X*CellSize, Z*CellSize) inclusive to ((X+1)*CellSize, (Z+1)*CellSize) exclusive on the X/Z plane.
+type WorldCellCoords struct {
+	X, Z int
+}
+
+//	The contents of a single world cell, as returned by WorldStreamer.LoadCell.
+type WorldCellContent struct {
+	//	The geometry of the cell: each is loaded into a new Mesh in Core.Libs.Meshes.
+	//	Their Providers are called in the background, right after WorldStreamer.LoadCell.
+	Meshes []WorldCellMesh
+
+	//	The textures of the cell: each is loaded into a new FxImage2D in Core.Libs.Images.Tex2D,
+	//	in the background like by Core.GpuSyncImageLibs().
+	Images []FxImageInitFrom
+
+	//	Creates the nodes (and any materials and effects) of the cell as descendants of rootNodeID.
+	//	meshIDs and imageIDs hold the IDs of the new Meshes and FxImage2Ds, in the order of Meshes and Images.
+	//	Called on the main thread between two frames, never in parallel with the app or prep threads.
+	Build func(scene *Scene, rootNodeID int, meshIDs, imageIDs []int)
+}
+
+//	A Mesh of a WorldCellContent.
+type WorldCellMesh struct {
+	Name     string
+	Provider u3d.MeshProvider
+}
+
+type worldCellState int
+
+const (
+	worldCellLoading worldCellState = iota
+	worldCellUploading
+	worldCellReady
+	worldCellFailed
+)
+
+type worldCell struct {
+	coords  WorldCellCoords
+	state   worldCellState
+	content *WorldCellContent
+	err     error
+
+	//	Provided in the background, not yet loaded into Core.Libs.Meshes.
+	meshDescs []*u3d.MeshDescriptor
+
+	//	Loaded in the background via fxImageLoad(), by index into imageIDs. Since Core.Libs.Images.Tex2D
+	//	may be compacted meanwhile, these are detached copies until uploaded.
+	images       []*FxImage2D
+	imagesLoaded chan fxImageLoaded
+	imageDone    []bool
+
+	root        SceneNodeHandle
+	meshIDs     []MeshHandle
+	imageIDs    []FxImage2DHandle
+	numUploaded int
+}
+
+//	Runs in its own goroutine: calls loadCell and the providers of all meshes it returns.
+//	Only gathers plain data: everything else, from Core.Libs entries to Diag output, is left to the main thread.
+func (me *worldCell) load(loadCell func(WorldCellCoords) (*WorldCellContent, error), done chan<- *worldCell) {
+	if me.content, me.err = loadCell(me.coords); me.err == nil && me.content != nil {
+		me.meshDescs = make([]*u3d.MeshDescriptor, len(me.content.Meshes))
+		for i := 0; i < len(me.meshDescs) && me.err == nil; i++ {
+			if me.meshDescs[i], me.err = me.content.Meshes[i].Provider(); me.err == nil && (me.meshDescs[i] == nil || len(me.meshDescs[i].Faces) == 0) {
+				me.err = errf("World cell %v: mesh '%v' has no geometry", me.coords, me.content.Meshes[i].Name)
+			}
+		}
+	}
+	done <- me
+}
+
+//	Records err as the reason why me fails, unless it already failed.
+func (me *worldCell) fail(err error) {
+	if me.err == nil {
+		me.err = err
+	}
+}
+
+//	Receives the images of me loaded by fxImageLoad() so far.
+func (me *worldCell) receiveImages() {
+	for {
+		select {
+		case loaded := <-me.imagesLoaded:
+			for i := 0; i < len(me.images); i++ {
+				if me.images[i] == loaded.img {
+					me.imageDone[i] = true
+				}
+			}
+			if loaded.err != nil {
+				me.fail(loaded.err)
+			}
+		default:
+			return
+		}
+	}
+}
+
+//	Streams the cells of an open world into a Scene, see Scene.AddNewWorldStreamer().
+//
+//	Cells within LoadDistance of the Camera are loaded in the background, each into a new child node of the
+//	Scene's root node. That node stays disabled until all meshes and images of the cell have been uploaded
+//	to the GPU, which happens on the main thread for at most GpuBudget seconds per frame.
+//	Cells farther than UnloadDistance are removed again, along with their nodes, meshes and images.
+type WorldStreamer struct {
+	//	The camera around whose Controller.Pos cells are streamed in.
+	Camera *Camera
+
+	//	The edge length of a cell. Defaults to 256. Must not be changed once cells are loaded.
+	CellSize float64
+
+	//	Cells whose center is within LoadDistance of the Camera are loaded. Defaults to 512.
+	LoadDistance float64
+
+	//	Cells whose center is farther than UnloadDistance from the Camera are unloaded.
+	//	Should exceed LoadDistance so that cells near the boundary don't thrash. Defaults to 640.
+	UnloadDistance float64
+
+	//	The maximum number of cells being loaded in the background at the same time. Defaults to 4.
+	MaxConcurrentLoads int
+
+	//	The time in seconds per frame that may be spent uploading meshes and images of loaded cells
+	//	to the GPU. At least one upload is performed per frame regardless. Defaults to 0.002.
+	GpuBudget float64
+
+	//	The buffer that the meshes of all cells are added to. Must be set.
+	MeshBuffer *MeshBuffer
+
+	//	Called in its own goroutine to provide the contents of a cell that came into range.
+	//	If it returns an error (or any mesh or image of the cell fails to load or upload), the cell
+	//	is removed again and not retried until it was out of range again, see CellErr().
+	LoadCell func(cell WorldCellCoords) (*WorldCellContent, error)
+
+	//	If set, called on the main thread once a cell is fully uploaded and its root node enabled.
+	OnCellLoaded func(cell WorldCellCoords, rootNodeID int)
+
+	//	If set, called on the main thread before the root node, meshes and images of a cell are removed.
+	//	Useful to also remove any materials and effects that its WorldCellContent.Build created.
+	OnCellUnloading func(cell WorldCellCoords, rootNodeID int)
+
+	cells      map[WorldCellCoords]*worldCell
+	loaded     chan *worldCell
+	numLoading int
+	removed    bool
+}
+
+func (me *WorldStreamer) init(cam *Camera, meshBuf *MeshBuffer, loadCell func(WorldCellCoords) (*WorldCellContent, error)) {
+	me.Camera, me.MeshBuffer, me.LoadCell = cam, meshBuf, loadCell
+	me.CellSize, me.LoadDistance, me.UnloadDistance, me.MaxConcurrentLoads, me.GpuBudget = 256, 512, 640, 4, 0.002
+	me.cells = map[WorldCellCoords]*worldCell{}
+}
+
+//	Returns the horizontal distance between the camera and the center of cell.
+func (me *WorldStreamer) cellDist(cell WorldCellCoords) float64 {
+	dx, dz := (float64(cell.X)+0.5)*me.CellSize-me.Camera.Controller.Pos.X, (float64(cell.Z)+0.5)*me.CellSize-me.Camera.Controller.Pos.Z
+	return math.Sqrt(dx*dx + dz*dz)
+}
+
+//	Returns true if cell is loaded, that is: its nodes exist and all its meshes and images are on the GPU.
+func (me *WorldStreamer) CellLoaded(cell WorldCellCoords) bool {
+	c := me.cells[cell]
+	return c != nil && c.state == worldCellReady
+}
+
+//	Returns the error that made cell fail to load, or nil if it didn't (or isn't in range).
+func (me *WorldStreamer) CellErr(cell WorldCellCoords) error {
+	if c := me.cells[cell]; c != nil && c.state == worldCellFailed {
+		return c.err
+	}
+	return nil
+}
+
+//	Returns the ID of the root node of cell, or -1 if cell isn't (yet) built.
+func (me *WorldStreamer) CellRootNodeID(cell WorldCellCoords) int {
+	if c := me.cells[cell]; c != nil && !c.root.IsNil() {
+		return c.root.ID()
+	}
+	return -1
+}
+
+//	Returns the cell containing the specified world position.
+func (me *WorldStreamer) CellAt(pos *unum.Vec3) WorldCellCoords {
+	return WorldCellCoords{int(math.Floor(pos.X / me.CellSize)), int(math.Floor(pos.Z / me.CellSize))}
+}
+
+//	Loads the meshes of cell into Core.Libs.Meshes, starts loading its images into Core.Libs.Images.Tex2D
+//	and calls its Build func.
+func (me *WorldStreamer) buildCell(scene *Scene, cell *worldCell) {
+	rootID := scene.AddNewChildNode(0, -1)
+	root := &scene.allNodes[rootID]
+	root.Name, root.Render.Enabled = strf("WorldCell_%d_%d", cell.coords.X, cell.coords.Z), false
+	cell.root = scene.allNodes.Handle(rootID)
+	meshIDs, imageIDs := make([]int, len(cell.meshDescs)), make([]int, len(cell.content.Images))
+	for i := 0; i < len(cell.meshDescs); i++ {
+		meshIDs[i] = Core.Libs.Meshes.AddNew()
+		mesh, md := &Core.Libs.Meshes[meshIDs[i]], cell.meshDescs[i]
+		mesh.Name = cell.content.Meshes[i].Name
+		err := mesh.Load(func() (*u3d.MeshDescriptor, error) { return md, nil })
+		if err == nil {
+			err = me.MeshBuffer.Add(meshIDs[i])
+		}
+		if err != nil {
+			cell.fail(err)
+		}
+		cell.meshIDs = append(cell.meshIDs, Core.Libs.Meshes.Handle(meshIDs[i]))
+	}
+	cell.imagesLoaded, cell.imageDone = make(chan fxImageLoaded, len(imageIDs)), make([]bool, len(imageIDs))
+	for i := 0; i < len(imageIDs); i++ {
+		imageIDs[i] = Core.Libs.Images.Tex2D.AddNew()
+		img := &Core.Libs.Images.Tex2D[imageIDs[i]]
+		img.InitFrom = cell.content.Images[i]
+		tmp := &FxImage2D{FxImageBase: img.FxImageBase, InitFrom: img.InitFrom}
+		cell.imageIDs, cell.images = append(cell.imageIDs, Core.Libs.Images.Tex2D.Handle(imageIDs[i])), append(cell.images, tmp)
+		go fxImageLoad(cell.imagesLoaded, tmp)
+	}
+	cell.meshDescs, cell.state = nil, worldCellUploading
+	if cell.content.Build != nil {
+		cell.content.Build(scene, rootID, meshIDs, imageIDs)
+	}
+	cell.content = nil
+}
+
+//	Removes the root node, meshes and images of cell, and cell itself.
+func (me *WorldStreamer) unloadCell(scene *Scene, cell *worldCell) {
+	me.releaseCell(scene, cell)
+	delete(me.cells, cell.coords)
+}
+
+//	Removes the root node, meshes and images of cell.
+func (me *WorldStreamer) releaseCell(scene *Scene, cell *worldCell) {
+	if root := scene.allNodes.Deref(&cell.root); root != nil {
+		if me.OnCellUnloading != nil {
+			me.OnCellUnloading(cell.coords, root.ID)
+		}
+		scene.RemoveNode(root.ID)
+	}
+	for i := 0; i < len(cell.meshIDs); i++ {
+		if mesh := Core.Libs.Meshes.Deref(&cell.meshIDs[i]); mesh != nil {
+			if mesh.meshBuffer != nil {
+				mesh.meshBuffer.Remove(mesh.ID)
+			}
+			Core.Libs.Meshes.Remove(mesh.ID, 1)
+		}
+	}
+	for i := 0; i < len(cell.imageIDs); i++ {
+		if img := Core.Libs.Images.Tex2D.Deref(&cell.imageIDs[i]); img != nil {
+			img.GpuDelete()
+			Core.Libs.Images.Tex2D.Remove(img.ID, 1)
+		}
+	}
+	cell.meshIDs, cell.imageIDs, cell.images, cell.imageDone = nil, nil, nil, nil
+}
+
+//	Uploads the meshes and images of built cells until me.GpuBudget is used up, enabling the root node of
+//	each cell whose uploads are all done. Cells with any failed load or upload are released and marked as failed.
+func (me *WorldStreamer) uploadCells(scene *Scene) {
+	var (
+		err      error
+		mesh     *Mesh
+		img      *FxImage2D
+		uploaded bool
+	)
+	start := Loop.Time()
+cells:
+	for _, cell := range me.cells {
+		if cell.state != worldCellUploading {
+			continue
+		}
+		cell.receiveImages()
+		for ; cell.err == nil && cell.numUploaded < len(cell.meshIDs)+len(cell.imageIDs); cell.numUploaded++ {
+			if uploaded && Loop.Time()-start >= me.GpuBudget {
+				return
+			}
+			if i := cell.numUploaded - len(cell.meshIDs); i < 0 {
+				if mesh = Core.Libs.Meshes.Deref(&cell.meshIDs[cell.numUploaded]); mesh == nil {
+					err = errf("mesh %v was removed", cell.numUploaded)
+				} else if mesh.meshBuffer == nil {
+					err = errf("mesh '%v' is not in a MeshBuffer", mesh.Name)
+				} else {
+					err = mesh.GpuUpload()
+				}
+			} else if !cell.imageDone[i] {
+				//	still loading in the background, check again next frame
+				continue cells
+			} else if img = Core.Libs.Images.Tex2D.Deref(&cell.imageIDs[i]); img == nil {
+				err = errf("image %v was removed", i)
+			} else if !cell.images[i].Loaded() {
+				err = errf("image %v has no data", i)
+			} else {
+				img.img, cell.images[i] = cell.images[i].img, nil
+				err = img.GpuSync()
+			}
+			if uploaded = true; err != nil {
+				cell.fail(err)
+				err = nil
+			}
+		}
+		if cell.err != nil {
+			Diag.LogErr(errf("World cell %v failed to load: %v", cell.coords, cell.err))
+			cell.state = worldCellFailed
+			me.releaseCell(scene, cell)
+			continue
+		}
+		cell.state = worldCellReady
+		if root := scene.allNodes.Deref(&cell.root); root != nil {
+			root.Render.Enabled = true
+			if me.OnCellLoaded != nil {
+				me.OnCellLoaded(cell.coords, root.ID)
+			}
+		}
+	}
+}
+
+//	Starts loading the cells nearest to the camera that are within me.LoadDistance, up to me.MaxConcurrentLoads at a time.
+func (me *WorldStreamer) loadCells() {
+	var (
+		dist, nearestDist float64
+		coords, nearest   WorldCellCoords
+		found             bool
+	)
+	min, max := me.CellAt(me.Camera.Controller.Pos.Added(&unum.Vec3{-me.LoadDistance, 0, -me.LoadDistance})), me.CellAt(me.Camera.Controller.Pos.Added(&unum.Vec3{me.LoadDistance, 0, me.LoadDistance}))
+	for me.numLoading < me.MaxConcurrentLoads {
+		for found, coords.X = false, min.X; coords.X <= max.X; coords.X++ {
+			for coords.Z = min.Z; coords.Z <= max.Z; coords.Z++ {
+				if me.cells[coords] == nil {
+					if dist = me.cellDist(coords); dist <= me.LoadDistance && (dist < nearestDist || !found) {
+						found, nearest, nearestDist = true, coords, dist
+					}
+				}
+			}
+		}
+		if !found {
+			break
+		}
+		cell := &worldCell{coords: nearest, state: worldCellLoading}
+		me.cells[nearest] = cell
+		me.numLoading++
+		go cell.load(me.LoadCell, me.loaded)
+	}
+}
+
+//	Called on the main thread between two frames, when neither the app thread nor the prep thread are running.
+func (me *WorldStreamer) onStream(scene *Scene) {
+	if me.loaded == nil {
+		me.loaded = make(chan *worldCell, me.MaxConcurrentLoads)
+	}
+	if !me.removed {
+		me.uploadCells(scene)
+	}
+	for done := false; !done; {
+		select {
+		case cell := <-me.loaded:
+			me.numLoading--
+			if me.removed || me.cells[cell.coords] != cell || me.cellDist(cell.coords) > me.UnloadDistance {
+				delete(me.cells, cell.coords)
+			} else if cell.err != nil || cell.content == nil {
+				if cell.state, cell.content, cell.meshDescs = worldCellFailed, nil, nil; cell.err != nil {
+					Diag.LogErr(cell.err)
+				}
+			} else {
+				me.buildCell(scene, cell)
+			}
+		default:
+			done = true
+		}
+	}
+	for _, cell := range me.cells {
+		if cell.state != worldCellLoading && (me.removed || me.cellDist(cell.coords) > me.UnloadDistance) {
+			me.unloadCell(scene, cell)
+		}
+	}
+	if !(me.removed || me.LoadCell == nil || me.Camera == nil || me.MeshBuffer == nil) {
+		me.loadCells()
+	}
+}
+
+//	Creates a new WorldStreamer that streams world cells provided by loadCell into me around cam,
+//	adding their meshes to meshBuf. Streaming starts with the next frame.
+func (me *Scene) AddNewWorldStreamer(cam *Camera, meshBuf *MeshBuffer, loadCell func(cell WorldCellCoords) (*WorldCellContent, error)) (streamer *WorldStreamer) {
+	streamer = &WorldStreamer{}
+	streamer.init(cam, meshBuf, loadCell)
+	me.streamers = append(me.streamers, streamer)
+	return
+}
+
+//	Stops the specified streamer, which must have been created by me.AddNewWorldStreamer().
+//	All its cells are unloaded before the next frame.
+func (me *Scene) RemoveWorldStreamer(streamer *WorldStreamer) {
+	streamer.removed = true
+}
+
+//	Streams world cells in and out for all WorldStreamers of me, see WorldStreamer.onStream().
+func (me *Scene) onStream() {
+	for i := 0; i < len(me.streamers); i++ {
+		if me.streamers[i].onStream(me); me.streamers[i].removed && me.streamers[i].numLoading == 0 && len(me.streamers[i].cells) == 0 {
+			me.streamers = append(me.streamers[:i], me.streamers[i+1:]...)
+			i--
+		}
+	}
+}
@@ -1,6 +1,7 @@
 package core
 
 import (
+	"github.com/metaleap/go-util-num"
 	"github.com/metaleap/go-util-slice"
 )
 
@@ -10,11 +11,25 @@ const sceneNodeChildCap = 16
 type Scene struct {
 	ID int
 
-	allNodes  SceneNodeLib
-	nodeCount int
+	//	Observer hooks for structural changes to this Scene.
+	On SceneEvents
+
+	allNodes    SceneNodeLib
+	animPlayers []*AnimPlayer
+	libGen      uint64
+	nodeCount   int
+	streamers   []*WorldStreamer
+
+	thrApp struct {
+		animDirty    []int
+		spatial      sceneOctree
+		spatialDirty []int
+	}
 
 	thrPrep struct {
 		copyDone, done bool
+		spatial        sceneOctree
+		unculled       []int
 	}
 
 	thrRend struct {
@@ -24,10 +39,14 @@ type Scene struct {
 
 func (me *Scene) dispose() {
 	me.allNodes.dispose()
-	me.nodeCount = 0
+	me.nodeCount, me.animPlayers, me.streamers = 0, nil, nil
 }
 
 func (me *Scene) init() {
+	me.On = SceneEvents{}
+	me.thrApp.spatial.init()
+	me.thrPrep.spatial.init()
+	me.thrApp.spatialDirty, me.thrApp.animDirty, me.animPlayers, me.streamers = me.thrApp.spatialDirty[:0], me.thrApp.animDirty[:0], nil, nil
 	me.allNodes.init()
 	root := &me.allNodes[me.allNodes.AddNew()]
 	me.nodeCount = 1
@@ -35,39 +54,183 @@ func (me *Scene) init() {
 }
 
 func (me *Scene) AddNewChildNode(parentNodeID, meshID int) (childNodeID int) {
+	if childNodeID = me.addNewChildNode(parentNodeID, meshID); childNodeID > -1 {
+		me.nodesAdded([]int{childNodeID})
+	}
+	return
+}
+
+//	Adds a new child-node to parentNodeID like AddNewChildNode(), but without applying its
+//	transforms or notifying anyone: the caller does this via nodesAdded() once it is set up.
+func (me *Scene) addNewChildNode(parentNodeID, meshID int) (childNodeID int) {
 	childNodeID = -1
 	if me.allNodes.IsOk(parentNodeID) {
 		me.nodeCount++
 		childNodeID = me.allNodes.AddNew()
-		me.allNodes[childNodeID].parentID, me.allNodes[childNodeID].Render.meshID = parentNodeID, meshID
+		me.allNodes[childNodeID].parentID, me.allNodes[childNodeID].Render.mesh = parentNodeID, Core.Libs.Meshes.Handle(meshID)
+		me.addChildNodeID(parentNodeID, childNodeID)
+	}
+	return
+}
 
-		if len(me.allNodes[parentNodeID].childNodeIDs) == cap(me.allNodes[parentNodeID].childNodeIDs) {
-			if len(me.allNodes[parentNodeID].childNodeIDs) > 0 {
-				uslice.IntSetCap(&me.allNodes[parentNodeID].childNodeIDs, 2*len(me.allNodes[parentNodeID].childNodeIDs))
-			} else {
-				uslice.IntSetCap(&me.allNodes[parentNodeID].childNodeIDs, sceneNodeChildCap)
+func (me *Scene) addChildNodeID(parentNodeID, childNodeID int) {
+	if len(me.allNodes[parentNodeID].childNodeIDs) == cap(me.allNodes[parentNodeID].childNodeIDs) {
+		if len(me.allNodes[parentNodeID].childNodeIDs) > 0 {
+			uslice.IntSetCap(&me.allNodes[parentNodeID].childNodeIDs, 2*len(me.allNodes[parentNodeID].childNodeIDs))
+		} else {
+			uslice.IntSetCap(&me.allNodes[parentNodeID].childNodeIDs, sceneNodeChildCap)
+		}
+	}
+	uslice.IntAppendUnique(&me.allNodes[parentNodeID].childNodeIDs, childNodeID)
+}
+
+func (me *Scene) removeChildNodeID(parentNodeID, childNodeID int) {
+	if me.allNodes.IsOk(parentNodeID) {
+		for i, cid := range me.allNodes[parentNodeID].childNodeIDs {
+			if cid == childNodeID {
+				before, after := me.allNodes[parentNodeID].childNodeIDs[:i], me.allNodes[parentNodeID].childNodeIDs[i+1:]
+				me.allNodes[parentNodeID].childNodeIDs = append(before, after...)
+				break
 			}
 		}
-		uslice.IntAppendUnique(&me.allNodes[parentNodeID].childNodeIDs, childNodeID)
-		me.ApplyNodeTransforms(childNodeID)
+	}
+}
 
-		var view int
-		var rts *RenderTechniqueScene
-		for canv := 0; canv < len(Core.Render.Canvases); canv++ {
-			for view = 0; view < len(Core.Render.Canvases[canv].Views); view++ {
-				if rts = Core.Render.Canvases[canv].Views[view].Technique_Scene(); rts != nil && rts.Camera.sceneID == me.ID {
-					rts.Camera.initNodeCamData(me.allNodes, childNodeID)
+//	Creates a deep copy of the specified node and all its child-nodes, and adds it as a new child-node to
+//	parentNodeID, which may be any node in this Scene except one inside the copied sub-tree itself.
+//	Returns the ID of the copy of nodeID, or -1 if either ID is invalid or parentNodeID is in the sub-tree of nodeID.
+func (me *Scene) CloneSubtree(nodeID, parentNodeID int) (cloneNodeID int) {
+	cloneNodeID = -1
+	if !me.IsNodeInSubtree(parentNodeID, nodeID) {
+		if cloneIDs := me.cloneSubtreeFrom(me, nodeID, parentNodeID); len(cloneIDs) > 0 {
+			cloneNodeID = cloneIDs[0]
+			me.nodesAdded(cloneIDs)
+		}
+	}
+	return
+}
+
+//	Adds copies of the sub-tree of srcNodeID in src to parentNodeID, via addNewChildNode().
+//	Returns the IDs of all copies, parents before their child-nodes, starting with that of srcNodeID.
+func (me *Scene) cloneSubtreeFrom(src *Scene, srcNodeID, parentNodeID int) (cloneIDs []int) {
+	if src.allNodes.IsOk(srcNodeID) && me.allNodes.IsOk(parentNodeID) {
+		//	collect the whole sub-tree first: with src == me, newly added clones must not be cloned again
+		srcIDs := []int{srcNodeID}
+		for i := 0; i < len(srcIDs); i++ {
+			for _, cid := range src.allNodes[srcIDs[i]].childNodeIDs {
+				if src.allNodes.IsOk(cid) {
+					srcIDs = append(srcIDs, cid)
 				}
 			}
 		}
+		var cloneID, cloneParentID int
+		srcNewIDs := make(map[int]int, len(srcIDs))
+		cloneIDs = make([]int, 0, len(srcIDs))
+		for _, srcID := range srcIDs {
+			if cloneParentID = parentNodeID; srcID != srcNodeID {
+				cloneParentID = srcNewIDs[src.allNodes[srcID].parentID]
+			}
+			cloneID = me.addNewChildNode(cloneParentID, src.allNodes[srcID].meshID())
+			srcNewIDs[srcID], cloneIDs = cloneID, append(cloneIDs, cloneID)
+			me.allNodes[cloneID].Name, me.allNodes[cloneID].Render = src.allNodes[srcID].Name, src.allNodes[srcID].Render
+			me.allNodes[cloneID].Render.skyMode = false
+			me.allNodes[cloneID].Render.Lod.Levels = append([]LodLevel(nil), src.allNodes[srcID].Render.Lod.Levels...)
+			me.allNodes[cloneID].Render.MorphWeights = append([]float64(nil), src.allNodes[srcID].Render.MorphWeights...)
+			me.allNodes[cloneID].Transform.Pos = src.allNodes[srcID].Transform.Pos
+			me.allNodes[cloneID].Transform.Rot = src.allNodes[srcID].Transform.Rot
+			me.allNodes[cloneID].Transform.Scale = src.allNodes[srcID].Transform.Scale
+		}
+		for srcID, cloneID := range srcNewIDs {
+			//	skeletons outside the cloned sub-tree are kept only within the same Scene
+			if skelID, ok := srcNewIDs[src.skeletonID(srcID)]; ok {
+				me.allNodes[cloneID].Render.Skeleton = me.allNodes.Handle(skelID)
+			} else if src != me {
+				me.allNodes[cloneID].Render.Skeleton = SceneNodeHandle{id: -1}
+			}
+		}
 	}
 	return
 }
 
+func (me *Scene) initCamsNodeData(nodeID int) {
+	var view int
+	var rts *RenderTechniqueScene
+	for canv := 0; canv < len(Core.Render.Canvases); canv++ {
+		for view = 0; view < len(Core.Render.Canvases[canv].Views); view++ {
+			if rts = Core.Render.Canvases[canv].Views[view].Technique_Scene(); rts != nil {
+				rts.Camera.initNodeCamData(me, nodeID)
+			}
+		}
+	}
+}
+
+//	Completes the addition of the new nodeIDs, parents before their child-nodes, all of them in the
+//	sub-tree of nodeIDs[0]: applies their transforms and bounds, and only then initializes their
+//	per-camera data and calls On.NodeAdded and On.NodeTransformed for each.
+func (me *Scene) nodesAdded(nodeIDs []int) {
+	me.applyNodeTransforms(nodeIDs[0], false)
+	me.applyAncestorBounds(nodeIDs[0])
+	for _, id := range nodeIDs {
+		me.initCamsNodeData(id)
+	}
+	for _, id := range nodeIDs {
+		me.On.NodeAdded.callAll(me, id)
+		me.On.NodeTransformed.callAll(me, id)
+	}
+}
+
+//	Creates a new copy of the prefab Scene in Core.Libs.Scenes with the specified prefabSceneID,
+//	adding it to parentNodeID. The copy is a new node without geometry of its own, containing copies
+//	of all the child-nodes of the prefab's root node. Returns the ID of that new node, or -1 if either ID is invalid.
+//	To create a prefab from an existing node, see SceneLib.AddNewPrefab().
+func (me *Scene) InstantiatePrefab(prefabSceneID, parentNodeID int) (instNodeID int) {
+	instNodeID = -1
+	if prefab := Core.Libs.Scenes.get(prefabSceneID); prefab != nil && prefab != me {
+		if instNodeID = me.addNewChildNode(parentNodeID, -1); instNodeID > -1 {
+			instIDs := []int{instNodeID}
+			rootChildIDs := append([]int(nil), prefab.allNodes[0].childNodeIDs...)
+			for _, cid := range rootChildIDs {
+				instIDs = append(instIDs, me.cloneSubtreeFrom(prefab, cid, instNodeID)...)
+			}
+			me.nodesAdded(instIDs)
+		}
+	}
+	return
+}
+
+//	Returns true if nodeID is ancestorNodeID or any of its (direct or indirect) child-nodes.
+func (me *Scene) IsNodeInSubtree(nodeID, ancestorNodeID int) bool {
+	for id := nodeID; me.allNodes.IsOk(id); id = me.allNodes[id].parentID {
+		if id == ancestorNodeID {
+			return true
+		}
+	}
+	return false
+}
+
 func (me *Scene) Node(id int) *SceneNode {
 	return me.allNodes.get(id)
 }
 
+//	Returns the ID of the first node named name in the sub-tree of rootNodeID (including
+//	rootNodeID itself), searching breadth-first. Returns -1 if there is no such node.
+func (me *Scene) NodeByName(rootNodeID int, name string) int {
+	if me.allNodes.IsOk(rootNodeID) {
+		ids := []int{rootNodeID}
+		for i := 0; i < len(ids); i++ {
+			if me.allNodes[ids[i]].Name == name {
+				return ids[i]
+			}
+			for _, cid := range me.allNodes[ids[i]].childNodeIDs {
+				if me.allNodes.IsOk(cid) {
+					ids = append(ids, cid)
+				}
+			}
+		}
+	}
+	return -1
+}
+
 func (me *Scene) NumNodes() int {
 	return me.nodeCount
 }
@@ -86,7 +249,11 @@ func (me *Scene) RemoveNode(fromID int) {
 				me.RemoveNode(i)
 			}
 		}
+		if me.allNodes.IsOk(fromID) {
+			me.On.NodeRemoved.callAll(me, fromID)
+		}
 		me.allNodes.Remove(fromID, 1)
+		me.spatialUpdate(fromID)
 		me.nodeCount--
 	}
 }
@@ -97,13 +264,93 @@ func (me *Scene) Root() *SceneNode {
 
 func (me *Scene) SetNodeMeshID(nodeID, meshID int) {
 	if me.allNodes.IsOk(nodeID) {
-		me.allNodes[nodeID].Render.meshID = meshID
+		me.allNodes[nodeID].Render.mesh = Core.Libs.Meshes.Handle(meshID)
 		me.ApplyNodeTransforms(nodeID)
+		me.On.NodeMeshChanged.callAll(me, nodeID)
+	}
+}
+
+//	Sets the Render.Skeleton of the specified node to skeletonNodeID, or to nil if that is -1 or invalid.
+func (me *Scene) SetNodeSkeletonID(nodeID, skeletonNodeID int) {
+	if me.allNodes.IsOk(nodeID) {
+		me.allNodes[nodeID].Render.Skeleton = me.allNodes.Handle(skeletonNodeID)
+		me.allNodes[nodeID].RebindSkin()
 	}
 }
 
+//	Returns the ID of the Render.Skeleton of the specified node, or -1 if it has none or it was removed.
+func (me *Scene) skeletonID(nodeID int) int {
+	if skel := me.allNodes.deref(me.allNodes[nodeID].Render.Skeleton); skel != nil {
+		return skel.ID
+	}
+	return -1
+}
+
+//	Moves the specified node (and with it all its child-nodes) to become a child-node of newParentNodeID.
+//	The node's Transform is recalculated such that its world transformation remains unchanged, as far as
+//	this is representable by Pos, Rot and Scale (non-uniformly scaled new parents may cause shearing that isn't).
+//	Returns false if either ID is invalid, if nodeID is the root node, or if newParentNodeID is inside the sub-tree of nodeID.
+func (me *Scene) SetParent(nodeID, newParentNodeID int) (ok bool) {
+	if ok = nodeID > 0 && me.allNodes.IsOk(nodeID) && me.allNodes.IsOk(newParentNodeID) && !me.IsNodeInSubtree(newParentNodeID, nodeID); ok {
+		if oldParentID := me.allNodes[nodeID].parentID; oldParentID != newParentNodeID {
+			var matWorld, matParent, matParentInv, matLocal unum.Mat4
+			me.nodeWorldMatrix(nodeID, &matWorld)
+			me.nodeWorldMatrix(newParentNodeID, &matParent)
+			mat4InvertAffine(&matParentInv, &matParent)
+			matLocal.SetFromMult4(&matParentInv, &matWorld)
+			me.allNodes[nodeID].Transform.setFromMatrix(&matLocal)
+
+			me.removeChildNodeID(oldParentID, nodeID)
+			me.allNodes[nodeID].parentID = newParentNodeID
+			me.addChildNodeID(newParentNodeID, nodeID)
+			me.ApplyNodeTransforms(nodeID)
+			me.applyAncestorBounds(oldParentID)
+			me.applyAncestorBounds(nodeID)
+			me.On.NodeReparented.callAll(me, nodeID, oldParentID)
+		}
+	}
+	return
+}
+
+//	Creates a new Scene in Core.Libs.Scenes containing a copy of the sub-tree of the specified
+//	node from the Scene with srcSceneID, for use with Scene.InstantiatePrefab(). The prefab is
+//	independent from its source: later changes to either one are not reflected in the other.
+//	The copy of srcNodeID keeps its Rot and Scale, but is positioned at the prefab's origin.
+//	Returns the new prefab's Scene ID, or -1 if either ID is invalid.
+func (me *SceneLib) AddNewPrefab(srcSceneID, srcNodeID int) (prefabSceneID int) {
+	prefabSceneID = -1
+	if me.IsOk(srcSceneID) && (*me)[srcSceneID].allNodes.IsOk(srcNodeID) {
+		prefabSceneID = me.AddNew()
+		//	AddNew() may have re-allocated *me, so only take pointers now
+		prefab, src := &(*me)[prefabSceneID], &(*me)[srcSceneID]
+		if cloneIDs := prefab.cloneSubtreeFrom(src, srcNodeID, 0); len(cloneIDs) > 0 {
+			prefab.allNodes[cloneIDs[0]].Transform.Pos.Set(0, 0, 0)
+			prefab.nodesAdded(cloneIDs)
+		}
+	}
+	return
+}
+
 //#begin-gt -gen-lib.gt T:Scene L:Core.Libs.Scenes
 
+//	A generational handle to a Scene in Core.Libs.Scenes.
+//	Unlike a plain ID, it is never silently resolved to another Scene that
+//	later re-uses the same ID after the original was removed or moved by Compact().
+type SceneHandle struct {
+	id  int
+	gen uint64
+}
+
+//	Returns the ID that me referred to when last resolved. It may since have become invalid.
+func (me SceneHandle) ID() int {
+	return me.id
+}
+
+//	Returns true if me is the zero SceneHandle (or was detected to be stale), which never resolves to a Scene.
+func (me SceneHandle) IsNil() bool {
+	return me.gen == 0
+}
+
 //	Only used for Core.Libs.Scenes
 type SceneLib []Scene
 
@@ -124,7 +371,7 @@ func (me *SceneLib) AddNew() (id int) {
 		*me = append(*me, Scene{})
 	}
 	ref := &(*me)[id]
-	ref.ID = id
+	ref.ID, ref.libGen = id, libGenNext()
 	ref.init()
 	return
 }
@@ -140,6 +387,8 @@ func (me *SceneLib) Compact() {
 		if (*me)[i].ID == -1 {
 			compact, before, after = true, (*me)[:i], (*me)[i+1:]
 			*me = append(before, after...)
+			//	re-check i, which now holds the next entry
+			i--
 		}
 	}
 	if compact {
@@ -148,6 +397,7 @@ func (me *SceneLib) Compact() {
 			if ref = &(*me)[i]; ref.ID != i {
 				oldID, ref.ID = ref.ID, i
 				changed[oldID] = i
+				libGenMove(ref.libGen, i)
 			}
 		}
 		if len(changed) > 0 {
@@ -172,6 +422,39 @@ func (me SceneLib) get(id int) (ref *Scene) {
 	return
 }
 
+//	Returns the Scene that h refers to, or nil if it has since been removed.
+//	If it was moved by Compact(), h is updated to its new ID. If it was removed, h becomes nil.
+func (me SceneLib) Deref(h *SceneHandle) (ref *Scene) {
+	if ref = me.deref(*h); ref != nil {
+		h.id = ref.ID
+	} else if h.gen != 0 {
+		*h = SceneHandle{id: -1}
+	}
+	return
+}
+
+//	Like Deref(), but leaves h as is: safe to call on handles owned by another thread.
+func (me SceneLib) deref(h SceneHandle) (ref *Scene) {
+	if h.gen != 0 {
+		id := h.id
+		if id < 0 || id >= len(me) || me[id].libGen != h.gen {
+			id = libGenMovedTo(h.gen)
+		}
+		if id > -1 && id < len(me) && me[id].ID == id && me[id].libGen == h.gen {
+			ref = &me[id]
+		}
+	}
+	return
+}
+
+//	Returns a SceneHandle to the Scene with the specified ID, or a nil SceneHandle if id is invalid.
+func (me SceneLib) Handle(id int) (h SceneHandle) {
+	if h.id = -1; me.IsOk(id) {
+		h.id, h.gen = id, me[id].libGen
+	}
+	return
+}
+
 func (me SceneLib) IsOk(id int) (ok bool) {
 	if id > -1 && id < len(me) {
 		ok = me[id].ID == id
@@ -191,7 +474,8 @@ func (me SceneLib) Remove(fromID, num int) {
 		changed := make(map[int]int, num)
 		for id := fromID; id < fromID+num; id++ {
 			me[id].dispose()
-			changed[id], me[id].ID = -1, -1
+			libGenForget(me[id].libGen)
+			changed[id], me[id].ID, me[id].libGen = -1, -1, 0
 		}
 		me.onSceneIDsChanged(changed)
 	}
@@ -16,6 +16,7 @@ var (
 	}
 	thrRend struct {
 		curCam                *Camera
+		curLayer              *CameraLayer
 		curView               *RenderView
 		curEffect, nextEffect *FxEffect
 		curTech, nextTech     RenderTechnique
@@ -70,12 +71,14 @@ func (me *RenderTechniqueScene) copyAppToPrep() {
 func (me *RenderTechniqueScene) copyPrepToRend() {
 	me.Camera.copyPrepToRend()
 	if me.Batch.Enabled {
-		n := me.thrPrep.batch.n
-		me.thrRend.batch.n = n
-		if len(me.thrRend.batch.all) < n {
-			me.thrRend.batch.all = make([]renderBatchEntry, n)
+		for _, layer := range me.Camera.thrRend.layers {
+			n := layer.thrPrep.batch.n
+			layer.thrRend.batch.n = n
+			if len(layer.thrRend.batch.all) < n {
+				layer.thrRend.batch.all = make([]renderBatchEntry, n)
+			}
+			copy(layer.thrRend.batch.all, layer.thrPrep.batch.all)
 		}
-		copy(me.thrRend.batch.all, me.thrPrep.batch.all)
 	}
 }
 
@@ -88,10 +91,13 @@ func (me *Camera) copyAppToPrep() {
 	}
 
 	me.thrPrep.matPos.Translation(&me.Controller.Pos)
-	if scene := me.Scene(); scene != nil {
-		scene.copyAppToPrep()
+	me.thrPrep.layers = append(me.thrPrep.layers[:0], me.layers...)
+	for _, layer := range me.thrPrep.layers {
+		layer.thrPrep.enabled, layer.thrPrep.clearDepth = layer.Enabled, layer.ClearDepth
+		if scene := layer.Scene(); scene != nil && layer.Enabled {
+			scene.copyAppToPrep()
+		}
 	}
-
 }
 
 func (me *Controller) copyAppToPrep() {
@@ -100,11 +106,20 @@ func (me *Controller) copyAppToPrep() {
 }
 
 func (me *Camera) copyPrepToRend() {
+	me.thrRend.layers = append(me.thrRend.layers[:0], me.thrPrep.layers...)
+	for _, layer := range me.thrRend.layers {
+		layer.copyPrepToRend()
+	}
+}
+
+func (me *CameraLayer) copyPrepToRend() {
+	me.thrRend.enabled, me.thrRend.clearDepth = me.thrPrep.enabled, me.thrPrep.clearDepth
 	copy(me.thrRend.nodeRender, me.thrPrep.nodeRender)
+	copy(me.thrRend.nodeMeshIDs, me.thrPrep.nodeMeshIDs)
 	for i := 0; i < len(me.thrPrep.nodeProjMats); i++ {
 		me.thrRend.nodeProjMats[i].Load(&me.thrPrep.nodeProjMats[i])
 	}
-	if scene := me.Scene(); scene != nil {
+	if scene := me.Scene(); scene != nil && me.thrRend.enabled {
 		scene.copyPrepToRend()
 	}
 }
@@ -112,11 +127,17 @@ func (me *Camera) copyPrepToRend() {
 func (me *Scene) copyAppToPrep() {
 	if !me.thrPrep.copyDone {
 		me.thrPrep.copyDone = true
+		me.thrPrep.unculled = me.thrPrep.unculled[:0]
+		me.applyBoundsMeshChanges()
 		for i := 0; i < len(me.allNodes); i++ {
 			if me.allNodes.Ok(i) {
 				me.allNodes[i].copyAppToPrep()
+				if !me.allNodes[i].Render.Cull.Frustum {
+					me.thrPrep.unculled = append(me.thrPrep.unculled, i)
+				}
 			}
 		}
+		me.copySpatialAppToPrep()
 	}
 }
 
@@ -135,7 +156,24 @@ func (me *Scene) copyPrepToRend() {
 func (me *SceneNode) copyAppToPrep() {
 	me.Transform.thrPrep.matModelView = me.Transform.thrApp.matModelView
 	me.thrPrep.bounding = me.thrApp.bounding
+	me.thrPrep.skinMats = append(me.thrPrep.skinMats[:0], me.thrApp.skin.mats...)
+	me.copyLodAppToPrep()
+	me.thrPrep.morphCount = 0
+	for i := 0; i < len(me.Render.MorphWeights) && i < meshMorphMaxTargets; i++ {
+		if me.thrPrep.morphWeights[i] = gl.Float(me.Render.MorphWeights[i]); me.Render.MorphWeights[i] != 0 {
+			me.thrPrep.morphCount = i + 1
+		}
+	}
 }
 
 func (me *SceneNode) copyPrepToRend() {
+	me.thrRend.morphCount, me.thrRend.morphWeights = me.thrPrep.morphCount, me.thrPrep.morphWeights
+	if n := len(me.thrPrep.skinMats); cap(me.thrRend.skinMats) < n {
+		me.thrRend.skinMats = make([]ugl.GlMat4, n)
+	} else {
+		me.thrRend.skinMats = me.thrRend.skinMats[:n]
+	}
+	for i := 0; i < len(me.thrPrep.skinMats); i++ {
+		me.thrRend.skinMats[i].Load(&me.thrPrep.skinMats[i])
+	}
 }